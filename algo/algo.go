@@ -0,0 +1,249 @@
+// Package algo provides execution algorithms built on the strategy interface, so the same
+// algo runs under strategy.Runtime, in paper trading and in backtests. Algos are driven by
+// timers, the local book and private account and order updates; their control methods
+// may be called from any goroutine.
+package algo
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+)
+
+// State is the lifecycle state of an algo
+type State int
+
+const (
+	StatePending State = iota
+	StateRunning
+	StatePaused
+	StateDone
+	StateCanceled
+)
+
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateRunning:
+		return "running"
+	case StatePaused:
+		return "paused"
+	case StateDone:
+		return "done"
+	case StateCanceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// finished reports whether an algo in state s will place no more orders
+func (s State) finished() bool { return s == StateDone || s == StateCanceled }
+
+// finalStatus reports whether an order update closes the order
+func finalStatus(status string) bool {
+	switch lighterapi.OrderStatus(status) {
+	case lighterapi.OrderStatusOpen, lighterapi.OrderStatusPending, lighterapi.OrderStatusInProgress:
+		return false
+	}
+	return true
+}
+
+// accountTrades returns the trades of a market carried by an account update
+func accountTrades(a client.LighterAccountResponse, marketId uint8) []client.WSTrade {
+	if a.RawAccountUpdate == nil {
+		return nil
+	}
+	return a.RawAccountUpdate.Trades[strconv.Itoa(int(marketId))]
+}
+
+// minBase returns the smallest order size of a market in base units
+func minBase(detail *lighterapi.OrderBookDetail) int64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(detail.MinBaseAmount), 64)
+	if err != nil || v <= 0 {
+		return 1
+	}
+	return max(client.BaseFromFloat(detail, v), 1)
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v
+}
+
+func boolToUint8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// child is an order sent by an algo
+type child struct {
+	clientIndex int64
+	orderIndex  int64
+	txHash      string
+	sentAt      time.Time
+	size        int64
+	price       uint32
+	// filled is the largest size known filled, from trades or order updates
+	filled   int64
+	tradeQty int64
+	notional float64
+	settled  bool
+}
+
+// children tracks the orders of an algo and their fills. Trades are matched by the
+// transaction hash of the child for taker fills and by order index for maker fills.
+type children struct {
+	market   *lighterapi.OrderBookDetail
+	list     []*child
+	byHash   map[string]*child
+	byClient map[int64]*child
+	byOrder  map[int64]*child
+	seen     map[int64]bool
+}
+
+func newChildren(market *lighterapi.OrderBookDetail) *children {
+	return &children{
+		market:   market,
+		byHash:   make(map[string]*child),
+		byClient: make(map[int64]*child),
+		byOrder:  make(map[int64]*child),
+		seen:     make(map[int64]bool),
+	}
+}
+
+func (cs *children) add(ch *child) {
+	cs.list = append(cs.list, ch)
+	cs.byHash[ch.txHash] = ch
+	cs.byClient[ch.clientIndex] = ch
+}
+
+// ownTrade reports whether a public trade was caused by one of the children
+func (cs *children) ownTrade(t client.LighterTradesResponse) bool {
+	return t.RawTrade != nil && (cs.byHash[t.RawTrade.TxHash] != nil || cs.byOrder[t.RawTrade.AskId] != nil || cs.byOrder[t.RawTrade.BidId] != nil)
+}
+
+// handleAccount records the fills carried by an account update. A child whose whole size
+// traded is settled here, so algos do not depend on its final order update.
+func (cs *children) handleAccount(a client.LighterAccountResponse) {
+	for _, t := range accountTrades(a, cs.market.MarketId) {
+		ch := cs.byHash[t.TxHash]
+		if ch != nil && ch.orderIndex == 0 {
+			// The child took liquidity when placed, so it is the taker side of the trade
+			index := t.BidId
+			if !t.IsMakerAsk {
+				index = t.AskId
+			}
+			ch.orderIndex = index
+			cs.byOrder[index] = ch
+		}
+		if ch == nil {
+			ch = cs.byOrder[t.AskId]
+		}
+		if ch == nil {
+			ch = cs.byOrder[t.BidId]
+		}
+		if ch == nil || cs.seen[t.TradeId] {
+			continue
+		}
+		cs.seen[t.TradeId] = true
+		qty := client.BaseFromFloat(cs.market, parseFloat(t.Size))
+		ch.tradeQty += qty
+		ch.notional += client.BaseToFloat(cs.market, qty) * parseFloat(t.Price)
+		ch.filled = max(ch.filled, ch.tradeQty)
+		if ch.size > 0 && ch.filled >= ch.size {
+			ch.settled = true
+		}
+	}
+}
+
+// handleOrder applies an order update to its child, returning nil for other orders
+func (cs *children) handleOrder(o client.LighterOrdersResponse) *child {
+	if o.MarketId != cs.market.MarketId {
+		return nil
+	}
+	ch := cs.byClient[o.ClientOrderIndex]
+	if ch == nil {
+		return nil
+	}
+	if index, err := strconv.ParseInt(o.OrderId, 10, 64); err == nil && ch.orderIndex == 0 {
+		ch.orderIndex = index
+		cs.byOrder[index] = ch
+	}
+	ch.filled = max(ch.filled, client.BaseFromFloat(cs.market, parseFloat(o.FilledQuantity)))
+	if finalStatus(o.Status) {
+		ch.settled = true
+	}
+	return ch
+}
+
+// totals sums the filled size of every child, plus the size and notional seen in trades
+func (cs *children) totals() (filled int64, tradeQty int64, notional float64) {
+	for _, ch := range cs.list {
+		filled += ch.filled
+		tradeQty += ch.tradeQty
+		notional += ch.notional
+	}
+	return filled, tradeQty, notional
+}
+
+// committed sums the filled size of settled children and the full size of the others,
+// which may still fill
+func (cs *children) committed() int64 {
+	var committed int64
+	for _, ch := range cs.list {
+		if ch.settled {
+			committed += ch.filled
+		} else {
+			committed += max(ch.size, ch.filled)
+		}
+	}
+	return committed
+}
+
+// progress fills the size, fill and benchmark fields of a Progress
+func (cs *children) progress(state State, total int64, arrival float64, isAsk bool) Progress {
+	filled, tradeQty, notional := cs.totals()
+	p := Progress{
+		State:        state,
+		Size:         client.BaseToFloat(cs.market, total),
+		Filled:       client.BaseToFloat(cs.market, filled),
+		Remaining:    client.BaseToFloat(cs.market, max(total-filled, 0)),
+		ArrivalPrice: arrival,
+		Children:     len(cs.list),
+	}
+	if tradeQty > 0 {
+		p.AvgPrice = notional / client.BaseToFloat(cs.market, tradeQty)
+	}
+	if p.AvgPrice > 0 && arrival > 0 {
+		p.SlippageBps = (p.AvgPrice - arrival) / arrival * 1e4
+		if isAsk {
+			p.SlippageBps = -p.SlippageBps
+		}
+	}
+	return p
+}
+
+// Progress is a snapshot of an algo
+type Progress struct {
+	State     State
+	Size      float64
+	Filled    float64
+	Remaining float64
+	// AvgPrice is the volume weighted price of the fills seen in account trades
+	AvgPrice float64
+	// ArrivalPrice is the mid when the algo started
+	ArrivalPrice float64
+	// SlippageBps is the shortfall of AvgPrice against ArrivalPrice in basis points;
+	// positive is worse than arrival
+	SlippageBps float64
+	// Children is the number of orders sent
+	Children int
+	Start    time.Time
+	End      time.Time
+}
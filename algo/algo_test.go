@@ -0,0 +1,217 @@
+package algo
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/strategy"
+)
+
+const testAccount = 1
+
+var testMarket = &lighterapi.OrderBookDetail{
+	MarketId:      0,
+	Symbol:        "ETH",
+	PriceDecimals: 2,
+	SizeDecimals:  4,
+	MinBaseAmount: "0.01",
+	MakerFee:      "0.002",
+	TakerFee:      "0.02",
+}
+
+// newPaperContext returns a strategy context trading through a paper client whose book
+// holds bid/ask. Order and account updates of the paper client are not delivered to the
+// algo, so tests choose which ones it sees.
+func newPaperContext(t *testing.T, bid, ask string) (*strategy.Context, *client.PaperTxClient) {
+	t.Helper()
+	book := client.NewOrderBookCache()
+	paper, err := client.NewPaperTxClient(nil, book, testAccount, client.WithPaperMarket(testMarket), client.WithPaperCollateral(1e6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := paper.HandleOrderBook(client.LighterOrderBookResponse{
+		MarketId:   testMarket.MarketId,
+		Bids:       []client.PriceLevel{{Price: bid, Quantity: "10"}},
+		Asks:       []client.PriceLevel{{Price: ask, Quantity: "10"}},
+		IsSnapshot: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return strategy.NewContext(context.Background(), paper, book, nil), paper
+}
+
+// relay queues the order and account events of a paper client in the order they were
+// published, for tests that let the algo see all of them. Events are queued rather than
+// delivered from the callbacks, which run while the algo still holds its lock.
+type relay struct {
+	events []func(strategy.Strategy, *strategy.Context) error
+}
+
+func newRelay(t *testing.T, paper *client.PaperTxClient) *relay {
+	t.Helper()
+	r := &relay{}
+	if _, err := paper.SubscribeOrders(client.LighterOrdersParamKey{AccountId: testAccount}, func(o client.LighterOrdersResponse) error {
+		if !o.IsSnapshot {
+			r.events = append(r.events, func(s strategy.Strategy, c *strategy.Context) error { return s.OnOrderUpdate(c, o) })
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := paper.SubscribeAccount(client.LighterAccountParamKey{AccountId: testAccount}, func(a client.LighterAccountResponse) error {
+		if !a.IsSnapshot {
+			r.events = append(r.events, func(s strategy.Strategy, c *strategy.Context) error { return s.OnAccount(c, a) })
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// deliver passes the queued events to s, including those raised while delivering
+func (r *relay) deliver(t *testing.T, c *strategy.Context, s strategy.Strategy) {
+	t.Helper()
+	for len(r.events) > 0 {
+		ev := r.events[0]
+		r.events = r.events[1:]
+		if err := ev(s, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// openOrder finds the paper order with a client order index
+func openOrder(t *testing.T, paper *client.PaperTxClient, clientIndex int64) client.LighterOrdersResponse {
+	t.Helper()
+	for _, o := range paper.OpenOrders(testMarket.MarketId) {
+		if o.ClientOrderIndex == clientIndex {
+			return o
+		}
+	}
+	t.Fatalf("no open order with client order index %d", clientIndex)
+	return client.LighterOrdersResponse{}
+}
+
+// makerFill is an account update carrying a fill of a resting order of the test account
+func makerFill(tradeId int64, o client.LighterOrdersResponse, size string) client.LighterAccountResponse {
+	index, _ := strconv.ParseInt(o.OrderId, 10, 64)
+	trade := client.WSTrade{TradeId: tradeId, TxHash: "taker" + strconv.FormatInt(tradeId, 10), MarketId: int(testMarket.MarketId), Size: size, Price: o.Price, IsMakerAsk: o.IsAsk == 1}
+	if o.IsAsk == 1 {
+		trade.AskId, trade.AskAccountId = index, testAccount
+		trade.BidId, trade.BidAccountId = 1, 99
+	} else {
+		trade.BidId, trade.BidAccountId = index, testAccount
+		trade.AskId, trade.AskAccountId = 1, 99
+	}
+	return client.LighterAccountResponse{
+		AccountId:        testAccount,
+		RawAccountUpdate: &client.WSAccountUpdate{Trades: map[string][]client.WSTrade{"0": {trade}}},
+	}
+}
+
+func TestChildrenSettleFromTrades(t *testing.T) {
+	cs := newChildren(testMarket)
+	taker := &child{clientIndex: 1, txHash: "a", size: 200}
+	maker := &child{clientIndex: 2, txHash: "b", size: 100, orderIndex: 77}
+	cs.add(taker)
+	cs.add(maker)
+	cs.byOrder[77] = maker
+
+	cs.handleAccount(client.LighterAccountResponse{RawAccountUpdate: &client.WSAccountUpdate{Trades: map[string][]client.WSTrade{"0": {
+		{TradeId: 1, TxHash: "a", Size: "0.0100", Price: "3000.00", AskId: 40, BidId: 41, IsMakerAsk: true},
+		{TradeId: 2, TxHash: "x", Size: "0.0100", Price: "2999.00", AskId: 77, BidId: 42},
+	}}}})
+	if taker.orderIndex != 41 || cs.byOrder[41] != taker {
+		t.Fatalf("taker order index %d, want 41 learned from the bid side", taker.orderIndex)
+	}
+	if taker.settled || !maker.settled {
+		t.Fatalf("settled taker=%v maker=%v, want only the fully traded maker", taker.settled, maker.settled)
+	}
+
+	// A repeated trade is not counted twice; a fill through the learned index settles the taker
+	cs.handleAccount(client.LighterAccountResponse{RawAccountUpdate: &client.WSAccountUpdate{Trades: map[string][]client.WSTrade{"0": {
+		{TradeId: 1, TxHash: "a", Size: "0.0100", Price: "3000.00", AskId: 40, BidId: 41, IsMakerAsk: true},
+		{TradeId: 3, TxHash: "y", Size: "0.0100", Price: "3000.00", AskId: 43, BidId: 41, IsMakerAsk: true},
+	}}}})
+	if taker.filled != 200 || !taker.settled {
+		t.Fatalf("taker filled %d settled %v, want 200 and settled", taker.filled, taker.settled)
+	}
+}
+
+func TestIcebergReplenishesFromTrades(t *testing.T) {
+	c, paper := newPaperContext(t, "2999.00", "3001.00")
+	ice, err := NewIceberg(IcebergConfig{Market: testMarket, Size: 0.03, DisplaySize: 0.01, Price: 2990, MinInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ice.OnTimer(c, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// Only the open update is seen; the slice closes through its trades
+	for i := int64(1); i <= 3; i++ {
+		slice := openOrder(t, paper, i)
+		if slice.BaseQuantity != "0.0100" {
+			t.Fatalf("slice %d size %s, want 0.0100", i, slice.BaseQuantity)
+		}
+		if err := ice.OnOrderUpdate(c, slice); err != nil {
+			t.Fatal(err)
+		}
+		if err := ice.OnAccount(c, makerFill(i, slice, "0.0100")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-ice.Done():
+	default:
+		t.Fatalf("iceberg not done: %+v", ice.Progress())
+	}
+	if p := ice.Progress(); p.State != StateDone || p.Filled != 0.03 || p.Children != 3 {
+		t.Fatalf("progress %+v, want done with 0.03 filled by 3 slices", p)
+	}
+}
+
+func TestPegReplacesCanceledOrder(t *testing.T) {
+	c, paper := newPaperContext(t, "2999.00", "3001.00")
+	peg, err := NewPeg(PegConfig{Market: testMarket, Size: 0.02, Reference: PegBid, PostOnly: true, MinInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := peg.OnTimer(c, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	first := openOrder(t, paper, 1)
+	if first.Price != "2999.00" {
+		t.Fatalf("peg rests at %s, want the bid 2999.00", first.Price)
+	}
+	if err := peg.OnOrderUpdate(c, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := peg.OnAccount(c, makerFill(1, first, "0.0050")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The exchange cancels the rest; the remainder is placed again under a new index
+	canceled := first
+	canceled.Status = string(lighterapi.OrderStatusCanceledPostOnly)
+	if err := peg.OnOrderUpdate(c, canceled); err != nil {
+		t.Fatal(err)
+	}
+	second := openOrder(t, paper, 2)
+	if second.BaseQuantity != "0.0150" {
+		t.Fatalf("replacement size %s, want the 0.0150 remainder", second.BaseQuantity)
+	}
+	if err := peg.OnOrderUpdate(c, second); err != nil {
+		t.Fatal(err)
+	}
+	if err := peg.OnAccount(c, makerFill(2, second, "0.0150")); err != nil {
+		t.Fatal(err)
+	}
+	if p := peg.Progress(); p.State != StateDone || p.Filled != 0.02 {
+		t.Fatalf("progress %+v, want done with 0.02 filled", p)
+	}
+}
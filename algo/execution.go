@@ -0,0 +1,360 @@
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/strategy"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const (
+	defaultMaxSlippage  = 0.001
+	defaultChildTimeout = 5 * time.Second
+)
+
+// ExecutionConfig describes a parent order worked by a TWAP or VWAP execution
+type ExecutionConfig struct {
+	Market *lighterapi.OrderBookDetail
+	IsAsk  bool
+	// Size is the parent size in base units
+	Size float64
+	// Start delays the first child; zero starts on the first timer with a two-sided book
+	Start   time.Time
+	Horizon time.Duration
+	// Slices is the number of scheduled child releases; defaults to one per minute of Horizon
+	Slices int
+	// LimitPrice is the worst price a child may trade at; 0 disables the guard
+	LimitPrice float64
+	// MaxParticipation caps the filled size at this fraction of the market volume traded
+	// since the start; 0 disables the cap
+	MaxParticipation float64
+	// MaxSlippage is how far past the touch a child may trade, as a fraction; defaults to 0.1%
+	MaxSlippage float64
+	// ChildTimeout bounds the wait for the final update of a child before the next one is
+	// sent; defaults to 5s. A child still unsettled then counts at its full size until its
+	// trades or final update arrive, so late fills cannot push the total past Size.
+	ChildTimeout time.Duration
+	// ClientOrderIndex is the client order index of the first child; children count up from
+	// it, so concurrent executions need distinct ranges. Defaults to 1.
+	ClientOrderIndex int64
+}
+
+// Execution slices a parent order into immediate-or-cancel limit orders along a schedule.
+// It implements strategy.Strategy and is driven by OnTimer, so the runtime or backtest must
+// have a timer interval shorter than a slice. Fills are read from the trades of account
+// updates, matched to children by transaction hash. Size still unfilled when the horizon
+// ends is left unfilled.
+type Execution struct {
+	strategy.Base
+
+	cfg ExecutionConfig
+	// cumulative holds the fraction of Size due by the start of each slice
+	cumulative []float64
+	total      int64
+	minBase    int64
+
+	mu           sync.Mutex
+	state        State
+	start        time.Time
+	pausedAt     time.Time
+	arrival      float64
+	marketVolume float64
+	nextIndex    int64
+	children     *children
+	done         chan struct{}
+}
+
+// NewTWAP creates an execution releasing equal slices over the horizon
+func NewTWAP(cfg ExecutionConfig) (*Execution, error) {
+	return newExecution(cfg, nil)
+}
+
+// NewVWAP creates an execution releasing slices in proportion to profile, typically built by
+// VolumeProfile or LoadVolumeProfile. The profile length sets the number of slices.
+func NewVWAP(cfg ExecutionConfig, profile []float64) (*Execution, error) {
+	if len(profile) == 0 {
+		return nil, errors.New("algo: empty volume profile")
+	}
+	cfg.Slices = len(profile)
+	return newExecution(cfg, profile)
+}
+
+func newExecution(cfg ExecutionConfig, weights []float64) (*Execution, error) {
+	if cfg.Market == nil {
+		return nil, errors.New("algo: market is required")
+	}
+	if cfg.Size <= 0 || cfg.Horizon <= 0 {
+		return nil, errors.New("algo: size and horizon must be positive")
+	}
+	if cfg.MaxParticipation < 0 || cfg.MaxParticipation > 1 {
+		return nil, fmt.Errorf("algo: participation %v outside [0, 1]", cfg.MaxParticipation)
+	}
+	if cfg.Slices <= 0 {
+		cfg.Slices = max(int(cfg.Horizon/time.Minute), 1)
+	}
+	if cfg.MaxSlippage <= 0 {
+		cfg.MaxSlippage = defaultMaxSlippage
+	}
+	if cfg.ChildTimeout <= 0 {
+		cfg.ChildTimeout = defaultChildTimeout
+	}
+	if cfg.ClientOrderIndex <= 0 {
+		cfg.ClientOrderIndex = 1
+	}
+	total := client.BaseFromFloat(cfg.Market, cfg.Size)
+	if total <= 0 {
+		return nil, fmt.Errorf("algo: size %v is below one base unit", cfg.Size)
+	}
+
+	if weights == nil {
+		weights = make([]float64, cfg.Slices)
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	var sum float64
+	for _, w := range weights {
+		if w < 0 {
+			return nil, errors.New("algo: negative volume profile weight")
+		}
+		sum += w
+	}
+	if sum == 0 {
+		return nil, errors.New("algo: volume profile has no weight")
+	}
+	cumulative := make([]float64, len(weights))
+	var acc float64
+	for i, w := range weights {
+		acc += w / sum
+		cumulative[i] = acc
+	}
+	cumulative[len(cumulative)-1] = 1
+
+	return &Execution{
+		cfg:        cfg,
+		cumulative: cumulative,
+		total:      total,
+		minBase:    minBase(cfg.Market),
+		nextIndex:  cfg.ClientOrderIndex,
+		children:   newChildren(cfg.Market),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// Pause stops sending children. On Resume the schedule is shifted by the time spent paused.
+func (e *Execution) Pause() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state == StatePending || e.state == StateRunning {
+		e.state = StatePaused
+	}
+}
+
+// Resume continues a paused execution
+func (e *Execution) Resume() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state != StatePaused {
+		return
+	}
+	if e.start.IsZero() {
+		e.state = StatePending
+	} else {
+		e.state = StateRunning
+	}
+}
+
+// Cancel stops the execution for good. A child already sent may still fill.
+func (e *Execution) Cancel() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.finish(StateCanceled)
+}
+
+// Done is closed when the execution completes or is canceled
+func (e *Execution) Done() <-chan struct{} { return e.done }
+
+// Progress returns the current state of the execution
+func (e *Execution) Progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p := e.children.progress(e.state, e.total, e.arrival, e.cfg.IsAsk)
+	p.Start = e.start
+	if !e.start.IsZero() {
+		p.End = e.start.Add(e.cfg.Horizon)
+	}
+	return p
+}
+
+// OnTrade tracks market volume for the participation cap, leaving out the execution's own fills
+func (e *Execution) OnTrade(_ *strategy.Context, t client.LighterTradesResponse) error {
+	if t.MarketId != e.cfg.Market.MarketId {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state != StateRunning && e.state != StatePaused {
+		return nil
+	}
+	if e.children.ownTrade(t) {
+		return nil
+	}
+	e.marketVolume += parseFloat(t.Quantity)
+	return nil
+}
+
+// OnAccount records fills of children
+func (e *Execution) OnAccount(_ *strategy.Context, a client.LighterAccountResponse) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.children.handleAccount(a)
+	e.checkComplete()
+	return nil
+}
+
+// OnOrderUpdate settles children as soon as their final status arrives
+func (e *Execution) OnOrderUpdate(_ *strategy.Context, o client.LighterOrdersResponse) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.children.handleOrder(o) != nil {
+		e.checkComplete()
+	}
+	return nil
+}
+
+// OnTimer sends the next child when the schedule, the participation cap and the price guard
+// allow it
+func (e *Execution) OnTimer(c *strategy.Context, now time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch e.state {
+	case StatePaused:
+		// pausedAt is stamped with the strategy clock, simulated in backtests
+		if e.pausedAt.IsZero() {
+			e.pausedAt = now
+		}
+		return nil
+	case StatePending:
+		if now.Before(e.cfg.Start) {
+			return nil
+		}
+		mid, ok := c.Book().Mid(e.cfg.Market.MarketId)
+		if !ok {
+			return nil
+		}
+		// A pause before the start needs no schedule shift
+		e.state, e.start, e.arrival = StateRunning, now, mid
+		e.pausedAt = time.Time{}
+	case StateRunning:
+		if !e.pausedAt.IsZero() {
+			e.start = e.start.Add(now.Sub(e.pausedAt))
+			e.pausedAt = time.Time{}
+		}
+	default:
+		return nil
+	}
+
+	for _, ch := range e.children.list {
+		if !ch.settled && now.Sub(ch.sentAt) < e.cfg.ChildTimeout {
+			return nil
+		}
+	}
+	if e.checkComplete() {
+		return nil
+	}
+	if !now.Before(e.start.Add(e.cfg.Horizon)) {
+		e.finish(StateDone)
+		return nil
+	}
+
+	slice := int(now.Sub(e.start) * time.Duration(len(e.cumulative)) / e.cfg.Horizon)
+	slice = min(slice, len(e.cumulative)-1)
+	committed := e.children.committed()
+	qty := int64(math.Round(e.cumulative[slice]*float64(e.total))) - committed
+	if e.cfg.MaxParticipation > 0 {
+		allowed := client.BaseFromFloat(e.cfg.Market, e.cfg.MaxParticipation*e.marketVolume) - committed
+		qty = min(qty, allowed)
+	}
+	qty = min(qty, e.total-committed)
+	if qty < e.minBase {
+		return nil
+	}
+
+	price, ok := e.childPrice(c)
+	if !ok {
+		return nil
+	}
+	ch := &child{clientIndex: e.nextIndex, sentAt: now, size: qty, price: price}
+	e.nextIndex++
+	txHash, err := c.Place(types.CreateOrderTxReq{
+		MarketIndex:      e.cfg.Market.MarketId,
+		ClientOrderIndex: ch.clientIndex,
+		BaseAmount:       qty,
+		Price:            price,
+		IsAsk:            boolToUint8(e.cfg.IsAsk),
+		Type:             txtypes.LimitOrder,
+		TimeInForce:      txtypes.ImmediateOrCancel,
+		OrderExpiry:      txtypes.NilOrderExpiry,
+	})
+	if err != nil {
+		return fmt.Errorf("algo: send child %d: %w", ch.clientIndex, err)
+	}
+	ch.txHash = txHash
+	e.children.add(ch)
+	return nil
+}
+
+// childPrice returns the touch moved by MaxSlippage, or false when the touch is beyond
+// the limit price
+func (e *Execution) childPrice(c *strategy.Context) (uint32, bool) {
+	marketId := e.cfg.Market.MarketId
+	if e.cfg.IsAsk {
+		bid, ok := c.Book().BestBid(marketId)
+		if !ok || (e.cfg.LimitPrice > 0 && bid.Price < e.cfg.LimitPrice) {
+			return 0, false
+		}
+		price := bid.Price * (1 - e.cfg.MaxSlippage)
+		if e.cfg.LimitPrice > 0 {
+			price = math.Max(price, e.cfg.LimitPrice)
+		}
+		return client.PriceFromFloat(e.cfg.Market, price), true
+	}
+	ask, ok := c.Book().BestAsk(marketId)
+	if !ok || (e.cfg.LimitPrice > 0 && ask.Price > e.cfg.LimitPrice) {
+		return 0, false
+	}
+	price := ask.Price * (1 + e.cfg.MaxSlippage)
+	if e.cfg.LimitPrice > 0 {
+		price = math.Min(price, e.cfg.LimitPrice)
+	}
+	return client.PriceFromFloat(e.cfg.Market, price), true
+}
+
+// checkComplete finishes the execution once the remainder is below the minimum order size
+func (e *Execution) checkComplete() bool {
+	if e.state != StateRunning {
+		return e.state.finished()
+	}
+	filled, _, _ := e.children.totals()
+	if e.total-filled < e.minBase {
+		e.finish(StateDone)
+		return true
+	}
+	return false
+}
+
+func (e *Execution) finish(state State) {
+	if e.state.finished() {
+		return
+	}
+	e.state = state
+	close(e.done)
+}
@@ -0,0 +1,147 @@
+package algo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/defi-maker/golighter/client"
+)
+
+// newTWAP buys 0.04 over four one-minute slices through a paper client on a 2999/3001 book
+func newTWAP(t *testing.T, cfg ExecutionConfig) (*Execution, *relay, func(time.Time)) {
+	t.Helper()
+	c, paper := newPaperContext(t, "2999.00", "3001.00")
+	r := newRelay(t, paper)
+	cfg.Market, cfg.Size, cfg.Horizon, cfg.Slices = testMarket, 0.04, 4*time.Minute, 4
+	exec, err := NewTWAP(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tick := func(now time.Time) {
+		t.Helper()
+		if err := exec.OnTimer(c, now); err != nil {
+			t.Fatal(err)
+		}
+		r.deliver(t, c, exec)
+	}
+	return exec, r, tick
+}
+
+func expectProgress(t *testing.T, exec *Execution, filled float64, children int) {
+	t.Helper()
+	if p := exec.Progress(); p.Filled != filled || p.Children != children {
+		t.Fatalf("progress %+v, want %v filled by %d children", p, filled, children)
+	}
+}
+
+func TestTWAPReleasesSlicesOnSchedule(t *testing.T) {
+	exec, _, tick := newTWAP(t, ExecutionConfig{})
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tick(start)
+	expectProgress(t, exec, 0.01, 1)
+	tick(start.Add(30 * time.Second))
+	expectProgress(t, exec, 0.01, 1)
+	tick(start.Add(time.Minute))
+	expectProgress(t, exec, 0.02, 2)
+	// A late timer catches up in one child
+	tick(start.Add(3 * time.Minute))
+	expectProgress(t, exec, 0.04, 3)
+
+	select {
+	case <-exec.Done():
+	default:
+		t.Fatal("execution not done after filling its size")
+	}
+	p := exec.Progress()
+	if p.State != StateDone || p.ArrivalPrice != 3000 || p.AvgPrice != 3001 {
+		t.Fatalf("progress %+v, want done at 3001 against a 3000 arrival", p)
+	}
+	if !p.Start.Equal(start) || !p.End.Equal(start.Add(4*time.Minute)) {
+		t.Fatalf("schedule %v to %v, want %v to %v", p.Start, p.End, start, start.Add(4*time.Minute))
+	}
+}
+
+func TestTWAPPauseShiftsSchedule(t *testing.T) {
+	exec, _, tick := newTWAP(t, ExecutionConfig{})
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tick(start)
+	exec.Pause()
+	tick(start.Add(time.Minute))
+	exec.Resume()
+	// Two minutes were spent paused, so three minutes in is one minute into the schedule
+	tick(start.Add(3 * time.Minute))
+	expectProgress(t, exec, 0.02, 2)
+	if p := exec.Progress(); !p.Start.Equal(start.Add(2 * time.Minute)) {
+		t.Fatalf("start %v, want it shifted to %v", p.Start, start.Add(2*time.Minute))
+	}
+}
+
+func TestTWAPPauseBeforeStart(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	exec, _, tick := newTWAP(t, ExecutionConfig{Start: start.Add(time.Minute)})
+
+	exec.Pause()
+	tick(start)
+	exec.Resume()
+	tick(start.Add(2 * time.Minute))
+	tick(start.Add(2*time.Minute + time.Second))
+	if p := exec.Progress(); !p.Start.Equal(start.Add(2 * time.Minute)) {
+		t.Fatalf("start %v, want %v with no shift for the pause before it", p.Start, start.Add(2*time.Minute))
+	}
+	expectProgress(t, exec, 0.01, 1)
+}
+
+func TestTWAPLimitPriceAndParticipation(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	exec, _, tick := newTWAP(t, ExecutionConfig{LimitPrice: 3000})
+	tick(start)
+	if p := exec.Progress(); p.State != StateRunning || p.Children != 0 {
+		t.Fatalf("progress %+v, want running without children while the ask is above the limit", p)
+	}
+
+	exec, _, tick = newTWAP(t, ExecutionConfig{MaxParticipation: 0.1})
+	tick(start)
+	expectProgress(t, exec, 0, 0)
+	if err := exec.OnTrade(nil, client.LighterTradesResponse{MarketId: testMarket.MarketId, Price: "3000.00", Quantity: "0.15", Side: "buy"}); err != nil {
+		t.Fatal(err)
+	}
+	// 10% of 0.15 traded by others allows 0.015, more than the 0.01 slice
+	tick(start.Add(time.Second))
+	expectProgress(t, exec, 0.01, 1)
+	// The next 0.005 allowed is below the minimum order
+	tick(start.Add(time.Minute))
+	expectProgress(t, exec, 0.01, 1)
+	if err := exec.OnTrade(nil, client.LighterTradesResponse{MarketId: testMarket.MarketId, Price: "3000.00", Quantity: "0.05", Side: "sell"}); err != nil {
+		t.Fatal(err)
+	}
+	tick(start.Add(time.Minute + time.Second))
+	expectProgress(t, exec, 0.02, 2)
+}
+
+func TestTWAPCountsTimedOutChildUntilItsFillsArrive(t *testing.T) {
+	c, paper := newPaperContext(t, "2999.00", "3001.00")
+	r := newRelay(t, paper)
+	exec, err := NewTWAP(ExecutionConfig{Market: testMarket, Size: 0.04, Horizon: 4 * time.Minute, Slices: 4, ChildTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// The first child fills but its updates are held back past the timeout
+	if err := exec.OnTimer(c, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.OnTimer(c, start.Add(3*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	expectProgress(t, exec, 0, 2)
+	r.deliver(t, c, exec)
+
+	// The catch-up child left room for the late 0.01
+	expectProgress(t, exec, 0.04, 2)
+	if p := exec.Progress(); p.State != StateDone {
+		t.Fatalf("progress %+v, want done", p)
+	}
+}
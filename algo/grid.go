@@ -0,0 +1,582 @@
+package algo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/strategy"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const (
+	defaultGridInterval = 250 * time.Millisecond
+	// gridBatchSize bounds the orders sent in one SendBatch call
+	gridBatchSize = 50
+	// gridRecoverLookback is how many closed orders Recover searches for missing grid orders
+	gridRecoverLookback = 500
+	// gridReconcileDelay is how long after a batch with an unknown outcome the grid waits
+	// before asking the exchange which of its orders exist
+	gridReconcileDelay = 10 * time.Second
+)
+
+// GridConfig describes a ladder of limit orders between two prices
+type GridConfig struct {
+	Market *lighterapi.OrderBookDetail
+	Lower  float64
+	Upper  float64
+	// Levels is the number of evenly spaced prices from Lower to Upper, both included
+	Levels int
+	// Size is the order size of every level in base units
+	Size     float64
+	PostOnly bool
+	// StatePath persists the grid after every change and is reloaded by NewGrid; empty
+	// keeps the state in memory only
+	StatePath string
+	// MinInterval spaces order batches; defaults to 250ms
+	MinInterval time.Duration
+	// ClientOrderIndex is the client order index of the first order of a new grid; orders
+	// count up from it. Defaults to 1.
+	ClientOrderIndex int64
+	// API, when set, lets the grid call Recover by itself after a batch whose outcome is
+	// unknown, such as a timeout. It needs an auth token provider for the account. Without
+	// it, posting pauses after such a batch until Recover is called.
+	API *client.Client
+}
+
+// GridReport summarizes a grid
+type GridReport struct {
+	State State
+	// RealizedProfit is the level spacing earned by completed round trips, before fees
+	RealizedProfit float64
+	Fees           float64
+	NetProfit      float64
+	RoundTrips     int
+	// Inventory is the signed base size bought minus sold by the grid
+	Inventory float64
+	OpenBuys  int
+	OpenSells int
+}
+
+// Grid keeps a buy below and a sell above the market at every level of a price ladder,
+// leaving the level nearest the mid empty. When an order fills, the opposite side is posted
+// one level away, so each buy and sell pair earns the level spacing. Orders that close
+// without filling are posted again; a post-only order refused for crossing the book waits
+// before it is. Grid implements strategy.Strategy and learns about fills from order updates.
+type Grid struct {
+	strategy.Base
+
+	cfg    GridConfig
+	prices []uint32
+	size   int64
+
+	mu         sync.Mutex
+	state      State
+	gs         *GridState
+	known      map[int64]bool
+	cancelSent bool
+	throttle   throttle
+	done       chan struct{}
+	// reconcileAt is set while a batch outcome is unknown; no orders are posted until
+	// Recover has run
+	reconcileAt time.Time
+	// retries holds the levels whose post-only order was refused for crossing the book
+	retries map[int]*levelRetry
+}
+
+// levelRetry delays posting at a level; refusals in a row double the wait
+type levelRetry struct {
+	at   time.Time
+	wait time.Duration
+}
+
+// NewGrid validates cfg and creates a grid, resuming from StatePath when it exists
+func NewGrid(cfg GridConfig) (*Grid, error) {
+	if cfg.Market == nil {
+		return nil, errors.New("algo: market is required")
+	}
+	if cfg.Levels < 2 || cfg.Lower <= 0 || cfg.Upper <= cfg.Lower {
+		return nil, errors.New("algo: a grid needs two or more levels and 0 < lower < upper")
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = defaultGridInterval
+	}
+	if cfg.ClientOrderIndex <= 0 {
+		cfg.ClientOrderIndex = 1
+	}
+	size := client.BaseFromFloat(cfg.Market, cfg.Size)
+	if size < minBase(cfg.Market) {
+		return nil, fmt.Errorf("algo: size %v is below the market minimum", cfg.Size)
+	}
+	prices := make([]uint32, cfg.Levels)
+	for i := range prices {
+		prices[i] = client.PriceFromFloat(cfg.Market, cfg.Lower+(cfg.Upper-cfg.Lower)*float64(i)/float64(cfg.Levels-1))
+		if i > 0 && prices[i] <= prices[i-1] {
+			return nil, fmt.Errorf("algo: %d levels are closer than one price tick", cfg.Levels)
+		}
+	}
+
+	g := &Grid{
+		cfg:      cfg,
+		prices:   prices,
+		size:     size,
+		known:    make(map[int64]bool),
+		retries:  make(map[int]*levelRetry),
+		throttle: throttle{interval: cfg.MinInterval},
+		done:     make(chan struct{}),
+	}
+	if cfg.StatePath != "" {
+		gs, err := LoadGridState(cfg.StatePath)
+		if err != nil {
+			return nil, fmt.Errorf("algo: load grid state: %w", err)
+		}
+		if gs != nil && (gs.MarketId != cfg.Market.MarketId || gs.Lower != cfg.Lower || gs.Upper != cfg.Upper || gs.Levels != cfg.Levels || gs.Size != cfg.Size) {
+			return nil, fmt.Errorf("algo: grid state in %s was written for a different grid", cfg.StatePath)
+		}
+		g.gs = gs
+	}
+	if g.gs == nil {
+		g.gs = &GridState{
+			MarketId:        cfg.Market.MarketId,
+			Lower:           cfg.Lower,
+			Upper:           cfg.Upper,
+			Levels:          cfg.Levels,
+			Size:            cfg.Size,
+			NextClientIndex: cfg.ClientOrderIndex,
+		}
+	}
+	for _, o := range g.gs.Orders {
+		if o.OrderIndex != 0 {
+			g.known[o.OrderIndex] = o.IsAsk
+		}
+	}
+	return g, nil
+}
+
+// Recover reconciles the grid with the exchange: open orders are read with ActiveOrders, and
+// grid orders no longer open are looked up among the recently closed ones to apply their
+// fills. Orders never confirmed by the exchange and found in neither list are posted again.
+// Call it after NewGrid on restart, before the grid is added to a runtime; with
+// GridConfig.API set the grid also calls it after a batch with an unknown outcome. api
+// needs an auth token provider for accountIndex.
+func (g *Grid) Recover(ctx context.Context, api *client.Client, accountIndex int64) error {
+	marketId := g.cfg.Market.MarketId
+	active, err := api.ActiveOrders(ctx, accountIndex, marketId)
+	if err != nil {
+		return fmt.Errorf("algo: recover grid: %w", err)
+	}
+	open := make(map[int64]lighterapi.Order, len(active))
+	for _, o := range active {
+		open[o.ClientOrderIndex] = o
+	}
+
+	g.mu.Lock()
+	missing := 0
+	for i := range g.gs.Orders {
+		o := &g.gs.Orders[i]
+		if o.ClientOrderIndex == 0 {
+			continue
+		}
+		if a, ok := open[o.ClientOrderIndex]; ok {
+			g.trackIndex(o, a.OrderIndex)
+			g.applyFill(o, client.BaseFromFloat(g.cfg.Market, parseFloat(a.FilledBaseAmount)))
+		} else {
+			missing++
+		}
+	}
+	g.mu.Unlock()
+
+	var closed []lighterapi.Order
+	if missing > 0 {
+		if closed, err = api.RecentInactiveOrders(ctx, accountIndex, &marketId, gridRecoverLookback); err != nil {
+			return fmt.Errorf("algo: recover grid: %w", err)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// Newest first; a matched order leaves the grid, so older orders reusing its client index
+	// are skipped
+	for _, c := range closed {
+		if _, ok := open[c.ClientOrderIndex]; ok {
+			continue
+		}
+		if idx := g.find(c.ClientOrderIndex); idx >= 0 {
+			o := &g.gs.Orders[idx]
+			g.trackIndex(o, c.OrderIndex)
+			g.applyFill(o, client.BaseFromFloat(g.cfg.Market, parseFloat(c.FilledBaseAmount)))
+			g.close(idx)
+		}
+	}
+	// Orders sent but neither open nor closed never reached the book
+	for i := range g.gs.Orders {
+		o := &g.gs.Orders[i]
+		if _, ok := open[o.ClientOrderIndex]; o.ClientOrderIndex != 0 && o.OrderIndex == 0 && !ok {
+			o.ClientOrderIndex = 0
+		}
+	}
+	g.reconcileAt = time.Time{}
+	return g.save()
+}
+
+// Cancel stops the grid; its open orders are canceled on the next event. The state file is
+// kept, so a new grid with the same config resumes the profit accounting.
+func (g *Grid) Cancel() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.state != StateCanceled {
+		g.state = StateCanceled
+		close(g.done)
+	}
+}
+
+// Done is closed when the grid is canceled
+func (g *Grid) Done() <-chan struct{} { return g.done }
+
+// Report returns the realized profit and open orders of the grid
+func (g *Grid) Report() GridReport {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r := GridReport{
+		State:          g.state,
+		RealizedProfit: g.gs.RealizedProfit,
+		Fees:           g.gs.Fees,
+		NetProfit:      g.gs.RealizedProfit - g.gs.Fees,
+		RoundTrips:     g.gs.RoundTrips,
+		Inventory:      g.gs.Inventory,
+	}
+	for _, o := range g.gs.Orders {
+		switch {
+		case o.ClientOrderIndex == 0:
+		case o.IsAsk:
+			r.OpenSells++
+		default:
+			r.OpenBuys++
+		}
+	}
+	return r
+}
+
+// State returns a copy of the grid state as persisted
+func (g *Grid) State() GridState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := *g.gs
+	s.Orders = append([]GridOrder(nil), g.gs.Orders...)
+	return s
+}
+
+func (g *Grid) OnBook(c *strategy.Context, b client.LighterOrderBookResponse) error {
+	if b.MarketId != g.cfg.Market.MarketId {
+		return nil
+	}
+	return g.step(c, c.Now())
+}
+
+func (g *Grid) OnTimer(c *strategy.Context, now time.Time) error {
+	if err := g.reconcile(c, now); err != nil {
+		return err
+	}
+	return g.step(c, now)
+}
+
+// reconcile runs Recover once a batch with an unknown outcome has had time to land
+func (g *Grid) reconcile(c *strategy.Context, now time.Time) error {
+	g.mu.Lock()
+	due := g.cfg.API != nil && !g.reconcileAt.IsZero() && !now.Before(g.reconcileAt)
+	g.mu.Unlock()
+	if !due {
+		return nil
+	}
+	err := g.Recover(c.Context(), g.cfg.API, c.AccountIndex())
+	if err != nil {
+		g.mu.Lock()
+		g.reconcileAt = now.Add(gridReconcileDelay)
+		g.mu.Unlock()
+	}
+	return err
+}
+
+// OnOrderUpdate applies fills and posts the opposite side of filled orders
+func (g *Grid) OnOrderUpdate(c *strategy.Context, o client.LighterOrdersResponse) error {
+	if o.MarketId != g.cfg.Market.MarketId {
+		return nil
+	}
+	g.mu.Lock()
+	idx := g.find(o.ClientOrderIndex)
+	if idx < 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	order := &g.gs.Orders[idx]
+	if index, err := strconv.ParseInt(o.OrderId, 10, 64); err == nil {
+		g.trackIndex(order, index)
+	}
+	g.applyFill(order, client.BaseFromFloat(g.cfg.Market, parseFloat(o.FilledQuantity)))
+	switch {
+	case lighterapi.OrderStatus(o.Status) == lighterapi.OrderStatusCanceledPostOnly:
+		g.backOff(order.Level, c.Now())
+		g.close(idx)
+	case finalStatus(o.Status):
+		g.close(idx)
+	case lighterapi.OrderStatus(o.Status) == lighterapi.OrderStatusOpen:
+		delete(g.retries, order.Level)
+	}
+	err := g.save()
+	g.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return g.step(c, c.Now())
+}
+
+// OnAccount charges the fees of the grid's trades
+func (g *Grid) OnAccount(_ *strategy.Context, a client.LighterAccountResponse) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	last := g.gs.LastTradeId
+	for _, t := range accountTrades(a, g.cfg.Market.MarketId) {
+		if t.TradeId <= g.gs.LastTradeId {
+			continue
+		}
+		last = max(last, t.TradeId)
+		isAsk, ok := g.known[t.AskId]
+		if !ok {
+			isAsk, ok = g.known[t.BidId]
+		}
+		if !ok {
+			continue
+		}
+		rate := t.TakerFee
+		if t.IsMakerAsk == isAsk {
+			rate = t.MakerFee
+		}
+		g.gs.Fees += parseFloat(t.Price) * parseFloat(t.Size) * float64(rate) / float64(txtypes.FeeTick)
+	}
+	if last == g.gs.LastTradeId {
+		return nil
+	}
+	g.gs.LastTradeId = last
+	return g.save()
+}
+
+// step lays out the ladder, posts waiting orders or cancels the grid's orders
+func (g *Grid) step(c *strategy.Context, now time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch g.state {
+	case StatePending:
+		if len(g.gs.Orders) == 0 {
+			mid, ok := c.Book().Mid(g.cfg.Market.MarketId)
+			if !ok {
+				return nil
+			}
+			g.layout(mid)
+		}
+		g.state = StateRunning
+	case StateCanceled:
+		return g.cancelOrders(c)
+	}
+	return g.postWaiting(c, now)
+}
+
+// layout queues a buy at every level below the one nearest mid and a sell at every level above
+func (g *Grid) layout(mid float64) {
+	nearest := 0
+	for i, p := range g.prices {
+		if math.Abs(client.PriceToFloat(g.cfg.Market, p)-mid) < math.Abs(client.PriceToFloat(g.cfg.Market, g.prices[nearest])-mid) {
+			nearest = i
+		}
+	}
+	for i := range g.prices {
+		if i != nearest {
+			g.gs.Orders = append(g.gs.Orders, GridOrder{Level: i, IsAsk: i > nearest, Size: g.size})
+		}
+	}
+}
+
+// postWaiting sends the waiting orders whose level is free. Client order indexes are saved
+// before sending so a restart can recover orders whose send outcome is unknown.
+func (g *Grid) postWaiting(c *strategy.Context, now time.Time) error {
+	occupied := make(map[int]bool)
+	for _, o := range g.gs.Orders {
+		if o.ClientOrderIndex != 0 {
+			occupied[o.Level] = true
+		}
+	}
+	var batch []int
+	for i, o := range g.gs.Orders {
+		if r := g.retries[o.Level]; r != nil && now.Before(r.at) {
+			continue
+		}
+		if o.ClientOrderIndex == 0 && !occupied[o.Level] && len(batch) < gridBatchSize {
+			occupied[o.Level] = true
+			batch = append(batch, i)
+		}
+	}
+	if len(batch) == 0 || !g.reconcileAt.IsZero() || !g.throttle.ready(now) {
+		return nil
+	}
+
+	tif := uint8(txtypes.GoodTillTime)
+	if g.cfg.PostOnly {
+		tif = txtypes.PostOnly
+	}
+	reqs := make([]types.CreateOrderTxReq, 0, len(batch))
+	for _, i := range batch {
+		o := &g.gs.Orders[i]
+		o.ClientOrderIndex = g.gs.NextClientIndex
+		g.gs.NextClientIndex++
+		reqs = append(reqs, types.CreateOrderTxReq{
+			MarketIndex:      g.cfg.Market.MarketId,
+			ClientOrderIndex: o.ClientOrderIndex,
+			BaseAmount:       o.Size,
+			Price:            g.prices[o.Level],
+			IsAsk:            boolToUint8(o.IsAsk),
+			Type:             txtypes.LimitOrder,
+			TimeInForce:      tif,
+		})
+	}
+	if err := g.save(); err != nil {
+		return err
+	}
+	_, err := c.PlaceBatch(reqs)
+	g.throttle.record(now, err)
+	if err == nil {
+		return nil
+	}
+	if !rejected(err) {
+		// Some orders may have landed; keep their indexes so order updates or Recover
+		// find them instead of posting duplicates
+		g.reconcileAt = now.Add(gridReconcileDelay)
+		return fmt.Errorf("algo: post grid orders, outcome unknown: %w", err)
+	}
+	for _, i := range batch {
+		g.gs.Orders[i].ClientOrderIndex = 0
+	}
+	return errors.Join(fmt.Errorf("algo: post grid orders: %w", err), g.save())
+}
+
+// rejected reports whether a send error means no transaction was accepted: a risk check
+// refused it or the API answered with a client error
+func rejected(err error) bool {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Status >= 400 && apiErr.Status < 500
+	}
+	return client.IsRiskRejection(err)
+}
+
+func (g *Grid) cancelOrders(c *strategy.Context) error {
+	if g.cancelSent {
+		return nil
+	}
+	g.cancelSent = true
+	var errs []error
+	for _, o := range g.gs.Orders {
+		if o.ClientOrderIndex == 0 {
+			continue
+		}
+		if _, err := c.Cancel(g.cfg.Market.MarketId, o.ClientOrderIndex); err != nil {
+			errs = append(errs, fmt.Errorf("algo: cancel grid order %d: %w", o.ClientOrderIndex, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyFill records the filled size of an order, crediting the spacing to counter orders
+func (g *Grid) applyFill(o *GridOrder, filled int64) {
+	delta := min(filled, o.Size) - o.Filled
+	if delta <= 0 {
+		return
+	}
+	o.Filled += delta
+	qty := client.BaseToFloat(g.cfg.Market, delta)
+	if o.IsAsk {
+		g.gs.Inventory -= qty
+	} else {
+		g.gs.Inventory += qty
+	}
+	if o.Counter {
+		pair := o.Level + 1
+		if o.IsAsk {
+			pair = o.Level - 1
+		}
+		spacing := math.Abs(client.PriceToFloat(g.cfg.Market, g.prices[o.Level]) - client.PriceToFloat(g.cfg.Market, g.prices[pair]))
+		g.gs.RealizedProfit += qty * spacing
+	}
+}
+
+// close removes a closed order, queueing the opposite side one level away for the filled
+// size and the same order again for the rest. A part below the minimum order size cannot be
+// posted; an unposted fill stays in Inventory.
+func (g *Grid) close(idx int) {
+	o := g.gs.Orders[idx]
+	g.gs.Orders = append(g.gs.Orders[:idx], g.gs.Orders[idx+1:]...)
+	if g.state == StateCanceled {
+		return
+	}
+	if o.Filled >= o.Size && o.Counter {
+		g.gs.RoundTrips++
+	}
+	minSize := minBase(g.cfg.Market)
+	if o.Filled >= minSize {
+		next := o.Level + 1
+		if o.IsAsk {
+			next = o.Level - 1
+		}
+		g.gs.Orders = append(g.gs.Orders, GridOrder{Level: next, IsAsk: !o.IsAsk, Size: o.Filled, Counter: true})
+	}
+	if rest := o.Size - o.Filled; rest >= minSize {
+		g.gs.Orders = append(g.gs.Orders, GridOrder{Level: o.Level, IsAsk: o.IsAsk, Size: rest, Counter: o.Counter})
+	}
+}
+
+// backOff delays the next post at a level whose post-only order crossed the book, so it
+// is not refused again on every batch until the price moves
+func (g *Grid) backOff(level int, now time.Time) {
+	r := g.retries[level]
+	if r == nil {
+		r = &levelRetry{}
+		g.retries[level] = r
+	}
+	r.wait = min(max(2*r.wait, time.Second), maxThrottleBackoff)
+	r.at = now.Add(r.wait)
+}
+
+func (g *Grid) find(clientIndex int64) int {
+	if clientIndex == 0 {
+		return -1
+	}
+	for i, o := range g.gs.Orders {
+		if o.ClientOrderIndex == clientIndex {
+			return i
+		}
+	}
+	return -1
+}
+
+func (g *Grid) trackIndex(o *GridOrder, orderIndex int64) {
+	if orderIndex != 0 {
+		o.OrderIndex = orderIndex
+		g.known[orderIndex] = o.IsAsk
+	}
+}
+
+func (g *Grid) save() error {
+	if g.cfg.StatePath == "" {
+		return nil
+	}
+	if err := g.gs.save(g.cfg.StatePath); err != nil {
+		return fmt.Errorf("algo: save grid state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package algo
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// GridOrder is one order of a grid. Orders with a zero ClientOrderIndex are waiting for
+// their level to be free.
+type GridOrder struct {
+	Level            int   `json:"level"`
+	IsAsk            bool  `json:"is_ask"`
+	ClientOrderIndex int64 `json:"client_order_index"`
+	OrderIndex       int64 `json:"order_index"`
+	// Size and Filled are in base units
+	Size   int64 `json:"size"`
+	Filled int64 `json:"filled"`
+	// Counter marks an order posted against a fill on the adjacent level; its fills
+	// complete round trips
+	Counter bool `json:"counter"`
+}
+
+// GridState is the persisted state of a grid
+type GridState struct {
+	MarketId        uint8       `json:"market_id"`
+	Lower           float64     `json:"lower"`
+	Upper           float64     `json:"upper"`
+	Levels          int         `json:"levels"`
+	Size            float64     `json:"size"`
+	NextClientIndex int64       `json:"next_client_index"`
+	Orders          []GridOrder `json:"orders"`
+	RealizedProfit  float64     `json:"realized_profit"`
+	Fees            float64     `json:"fees"`
+	RoundTrips      int         `json:"round_trips"`
+	// Inventory is the signed base size bought minus sold by the grid
+	Inventory   float64   `json:"inventory"`
+	LastTradeId int64     `json:"last_trade_id"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// LoadGridState reads a grid state file, returning nil if none exists
+func LoadGridState(path string) (*GridState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s GridState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// save writes the state through a temporary file so a crash never leaves it truncated
+func (s *GridState) save(path string) error {
+	s.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,353 @@
+package algo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+	"github.com/defi-maker/golighter/strategy"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const sendTxBatchPath = "/api/v1/sendTxBatch"
+
+// lostReply sends batches to the exchange but reports the first one as failed, as a
+// timeout after the request went out would
+type lostReply struct {
+	*client.TxClient
+	lost bool
+}
+
+func (l *lostReply) SendBatch(ctx context.Context, infos []txtypes.TxInfo) (*lighterapi.RespSendTxBatch, error) {
+	resp, err := l.TxClient.SendBatch(ctx, infos)
+	if err == nil && !l.lost {
+		l.lost = true
+		return nil, context.DeadlineExceeded
+	}
+	return resp, err
+}
+
+// newExchangeGrid returns a grid of buys at 2990 and 2995 and sells at 3005 and 3010 around
+// a 3000 mid, trading on a fake exchange
+func newExchangeGrid(t *testing.T) (*Grid, *lightertest.Server, *client.TxClient, *client.OrderBookCache) {
+	t.Helper()
+	srv, api, tx := lightertest.NewExchange(t)
+	book := client.NewOrderBookCache()
+	if err := book.Handle(client.LighterOrderBookResponse{
+		MarketId:   0,
+		Bids:       []client.PriceLevel{{Price: "2999.00", Quantity: "1"}},
+		Asks:       []client.PriceLevel{{Price: "3001.00", Quantity: "1"}},
+		IsSnapshot: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	grid, err := NewGrid(GridConfig{Market: testMarket, Lower: 2990, Upper: 3010, Levels: 5, Size: 0.01, API: api, MinInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return grid, srv, tx, book
+}
+
+func clientIndexes(orders []lighterapi.Order) []int64 {
+	var out []int64
+	for _, o := range orders {
+		out = append(out, o.ClientOrderIndex)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func equalIndexes(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGridKeepsIndexesWhenBatchOutcomeUnknown(t *testing.T) {
+	grid, srv, tx, book := newExchangeGrid(t)
+	c := strategy.NewContext(context.Background(), &lostReply{TxClient: tx}, book, nil)
+	start := time.Now()
+
+	err := grid.OnTimer(c, start)
+	if err == nil || !strings.Contains(err.Error(), "outcome unknown") {
+		t.Fatalf("first batch returned %v, want an unknown outcome", err)
+	}
+	want := []int64{1, 2, 3, 4}
+	if got := clientIndexes(srv.Orders(testAccount)); !equalIndexes(got, want) {
+		t.Fatalf("exchange holds %v, want %v", got, want)
+	}
+
+	// Nothing is posted until the grid has reconciled
+	if err := grid.OnTimer(c, start.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := grid.OnTimer(c, start.Add(gridReconcileDelay+time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests(sendTxBatchPath); n != 1 {
+		t.Fatalf("%d batches sent, want 1", n)
+	}
+	if got := clientIndexes(srv.Orders(testAccount)); !equalIndexes(got, want) {
+		t.Fatalf("exchange holds %v after reconciling, want %v", got, want)
+	}
+	if r := grid.Report(); r.OpenBuys != 2 || r.OpenSells != 2 {
+		t.Fatalf("report %+v, want 2 open buys and 2 open sells", r)
+	}
+}
+
+func TestGridRepostsOrdersThatNeverLanded(t *testing.T) {
+	grid, srv, tx, book := newExchangeGrid(t)
+	c := strategy.NewContext(context.Background(), tx, book, nil)
+	start := time.Now()
+
+	srv.FailNext(sendTxBatchPath, 1, 503, "unavailable")
+	if err := grid.OnTimer(c, start); err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+	if err := grid.OnTimer(c, start.Add(gridReconcileDelay)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := clientIndexes(srv.Orders(testAccount)), []int64{5, 6, 7, 8}; !equalIndexes(got, want) {
+		t.Fatalf("exchange holds %v, want the ladder posted again as %v", got, want)
+	}
+}
+
+func TestGridResetsIndexesOnRejection(t *testing.T) {
+	grid, srv, tx, book := newExchangeGrid(t)
+	c := strategy.NewContext(context.Background(), tx, book, nil)
+	start := time.Now()
+
+	srv.FailNext(sendTxBatchPath, 1, 400, "invalid nonce")
+	err := grid.OnTimer(c, start)
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || strings.Contains(err.Error(), "outcome unknown") {
+		t.Fatalf("batch returned %v, want a definite rejection", err)
+	}
+	if err := grid.OnTimer(c, start.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := clientIndexes(srv.Orders(testAccount)), []int64{5, 6, 7, 8}; !equalIndexes(got, want) {
+		t.Fatalf("exchange holds %v, want %v", got, want)
+	}
+}
+
+// gridOrderAt finds the grid order resting at a level
+func gridOrderAt(t *testing.T, grid *Grid, level int) GridOrder {
+	t.Helper()
+	for _, o := range grid.State().Orders {
+		if o.Level == level {
+			return o
+		}
+	}
+	t.Fatalf("no grid order at level %d", level)
+	return GridOrder{}
+}
+
+func TestGridRoundTrip(t *testing.T) {
+	c, paper := newPaperContext(t, "2999.00", "3001.00")
+	r := newRelay(t, paper)
+	path := filepath.Join(t.TempDir(), "grid.json")
+	cfg := GridConfig{Market: testMarket, Lower: 2990, Upper: 3010, Levels: 5, Size: 0.01, StatePath: path, MinInterval: time.Nanosecond}
+	grid, err := NewGrid(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trade := func(side, price string) {
+		t.Helper()
+		if err := paper.HandleTrades(client.LighterTradesResponse{MarketId: testMarket.MarketId, Price: price, Quantity: "0.01", Side: side}); err != nil {
+			t.Fatal(err)
+		}
+		r.deliver(t, c, grid)
+	}
+
+	if err := grid.OnTimer(c, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	r.deliver(t, c, grid)
+	// The level at the 3000 mid stays empty
+	if rep := grid.Report(); rep.State != StateRunning || rep.OpenBuys != 2 || rep.OpenSells != 2 {
+		t.Fatalf("report %+v, want 2 buys and 2 sells", rep)
+	}
+	if o := gridOrderAt(t, grid, 1); o.IsAsk || o.OrderIndex == 0 {
+		t.Fatalf("level 1 holds %+v, want a buy with a known order index", o)
+	}
+
+	// The buy at 2995 fills and a counter sell takes the empty level at 3000
+	trade("sell", "2995.00")
+	if o := gridOrderAt(t, grid, 2); !o.IsAsk || !o.Counter || o.ClientOrderIndex == 0 {
+		t.Fatalf("level 2 holds %+v, want a posted counter sell", o)
+	}
+	if rep := grid.Report(); rep.Inventory != 0.01 || rep.OpenBuys != 1 || rep.OpenSells != 3 {
+		t.Fatalf("report %+v, want 0.01 inventory with 1 buy and 3 sells", rep)
+	}
+
+	// The counter sell completes the round trip and a counter buy goes back to 2995
+	trade("buy", "3000.00")
+	rep := grid.Report()
+	if rep.RoundTrips != 1 || math.Abs(rep.RealizedProfit-0.05) > 1e-9 || rep.Inventory != 0 || rep.OpenBuys != 2 || rep.OpenSells != 2 {
+		t.Fatalf("report %+v, want one round trip earning 0.05 and a flat inventory", rep)
+	}
+	wantFees := (2995 + 3000) * 0.01 * 0.00002
+	if math.Abs(rep.Fees-wantFees) > 1e-9 || math.Abs(rep.NetProfit-(0.05-wantFees)) > 1e-9 {
+		t.Fatalf("fees %v net %v, want %v of maker fees", rep.Fees, rep.NetProfit, wantFees)
+	}
+	if o := gridOrderAt(t, grid, 1); o.IsAsk || !o.Counter {
+		t.Fatalf("level 1 holds %+v, want a counter buy", o)
+	}
+
+	// A restart resumes from the state file; a different grid refuses it
+	resumed, err := NewGrid(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resumed.Report(); got.RoundTrips != 1 || got.RealizedProfit != rep.RealizedProfit || got.Fees != rep.Fees || got.OpenBuys != 2 || got.OpenSells != 2 {
+		t.Fatalf("resumed report %+v, want %+v", got, rep)
+	}
+	if got, want := resumed.State().NextClientIndex, grid.State().NextClientIndex; got != want {
+		t.Fatalf("resumed next client index %d, want %d", got, want)
+	}
+	other := cfg
+	other.Levels = 6
+	if _, err := NewGrid(other); err == nil {
+		t.Fatal("expected a grid with other levels to refuse the state file")
+	}
+}
+
+func TestGridRepostsUnfilledCancel(t *testing.T) {
+	c, paper := newPaperContext(t, "2999.00", "3001.00")
+	r := newRelay(t, paper)
+	grid, err := NewGrid(GridConfig{Market: testMarket, Lower: 2990, Upper: 3010, Levels: 5, Size: 0.01, MinInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := grid.OnTimer(c, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	r.deliver(t, c, grid)
+	before := gridOrderAt(t, grid, 4)
+
+	// The exchange closes the sell at 3010 without a fill; it is posted again as it was
+	if _, err := c.Cancel(testMarket.MarketId, before.ClientOrderIndex); err != nil {
+		t.Fatal(err)
+	}
+	r.deliver(t, c, grid)
+	after := gridOrderAt(t, grid, 4)
+	if !after.IsAsk || after.Counter || after.ClientOrderIndex == 0 || after.ClientOrderIndex == before.ClientOrderIndex {
+		t.Fatalf("level 4 holds %+v after %+v was canceled, want the same sell under a new index", after, before)
+	}
+	if rep := grid.Report(); rep.Inventory != 0 || rep.OpenSells != 2 {
+		t.Fatalf("report %+v, want no inventory and 2 sells", rep)
+	}
+}
+
+func TestGridCountersPartialFillOfCanceledOrder(t *testing.T) {
+	c, paper := newPaperContext(t, "2999.00", "3001.00")
+	r := newRelay(t, paper)
+	grid, err := NewGrid(GridConfig{Market: testMarket, Lower: 2990, Upper: 3010, Levels: 5, Size: 0.03, MinInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trade := func(side, price, qty string) {
+		t.Helper()
+		if err := paper.HandleTrades(client.LighterTradesResponse{MarketId: testMarket.MarketId, Price: price, Quantity: qty, Side: side}); err != nil {
+			t.Fatal(err)
+		}
+		r.deliver(t, c, grid)
+	}
+	if err := grid.OnTimer(c, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	r.deliver(t, c, grid)
+
+	// The buy at 2995 fills 0.01 before the exchange cancels it
+	trade("sell", "2995.00", "0.01")
+	before := gridOrderAt(t, grid, 1)
+	if _, err := c.Cancel(testMarket.MarketId, before.ClientOrderIndex); err != nil {
+		t.Fatal(err)
+	}
+	r.deliver(t, c, grid)
+	if o := gridOrderAt(t, grid, 2); !o.IsAsk || !o.Counter || o.Size != 100 || o.ClientOrderIndex == 0 {
+		t.Fatalf("level 2 holds %+v, want a posted counter sell of the 0.01 filled", o)
+	}
+	if o := gridOrderAt(t, grid, 1); o.IsAsk || o.Size != 200 || o.ClientOrderIndex == 0 || o.ClientOrderIndex == before.ClientOrderIndex {
+		t.Fatalf("level 1 holds %+v, want the 0.02 rest posted again", o)
+	}
+
+	// The counter sell closes the partial round trip
+	trade("buy", "3000.00", "0.01")
+	rep := grid.Report()
+	if rep.RoundTrips != 1 || math.Abs(rep.RealizedProfit-0.05) > 1e-9 || rep.Inventory != 0 {
+		t.Fatalf("report %+v, want one round trip earning 0.05 and a flat inventory", rep)
+	}
+}
+
+func TestGridBacksOffPostOnlyRejections(t *testing.T) {
+	c, paper := newPaperContext(t, "2999.00", "3001.00")
+	r := newRelay(t, paper)
+	grid, err := NewGrid(GridConfig{Market: testMarket, Lower: 2990, Upper: 3010, Levels: 5, Size: 0.01, PostOnly: true, MinInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := grid.OnTimer(c, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	r.deliver(t, c, grid)
+	setBook := func(bid, ask string) {
+		t.Helper()
+		if err := paper.HandleOrderBook(client.LighterOrderBookResponse{
+			MarketId:   testMarket.MarketId,
+			Bids:       []client.PriceLevel{{Price: bid, Quantity: "10"}},
+			Asks:       []client.PriceLevel{{Price: ask, Quantity: "10"}},
+			IsSnapshot: true,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The sell at 3005 is canceled while the bid rises past it; its repost crosses once and
+	// then waits instead of being refused on every batch
+	sell := gridOrderAt(t, grid, 3)
+	if _, err := c.Cancel(testMarket.MarketId, sell.ClientOrderIndex); err != nil {
+		t.Fatal(err)
+	}
+	setBook("3005.50", "3007.00")
+	r.deliver(t, c, grid)
+	sent := grid.State().NextClientIndex
+	if o := gridOrderAt(t, grid, 3); o.ClientOrderIndex != 0 {
+		t.Fatalf("level 3 holds %+v, want the sell waiting", o)
+	}
+	if err := grid.OnTimer(c, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	r.deliver(t, c, grid)
+	if got := grid.State().NextClientIndex; got != sent {
+		t.Fatalf("%d orders sent during the back-off", got-sent)
+	}
+
+	// Once the price moves back and the wait is over, the sell rests again
+	setBook("2999.00", "3001.00")
+	if err := grid.OnTimer(c, time.Now().Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	r.deliver(t, c, grid)
+	if o := gridOrderAt(t, grid, 3); o.ClientOrderIndex != sent {
+		t.Fatalf("level 3 holds %+v, want the sell posted as %d", o, sent)
+	}
+	if rep := grid.Report(); rep.OpenSells != 2 || rep.OpenBuys != 2 {
+		t.Fatalf("report %+v, want 2 buys and 2 sells", rep)
+	}
+}
@@ -0,0 +1,212 @@
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/strategy"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const defaultIcebergInterval = 250 * time.Millisecond
+
+// IcebergConfig describes an order shown one slice at a time
+type IcebergConfig struct {
+	Market *lighterapi.OrderBookDetail
+	IsAsk  bool
+	// Size is the total size in base units
+	Size float64
+	// DisplaySize is the size of each resting slice
+	DisplaySize float64
+	Price       float64
+	PostOnly    bool
+	// MinInterval spaces slice placements and cancels; defaults to 250ms
+	MinInterval time.Duration
+	// ClientOrderIndex is the client order index of the first slice; slices count up from it.
+	// Defaults to 1.
+	ClientOrderIndex int64
+}
+
+// Iceberg rests one slice of DisplaySize at a fixed price and places the next slice when the
+// previous one is filled or canceled. It implements strategy.Strategy. A slice closes when
+// account trades cover its size or an order update reports its final status.
+type Iceberg struct {
+	strategy.Base
+
+	cfg     IcebergConfig
+	total   int64
+	display int64
+	price   uint32
+	minBase int64
+
+	mu         sync.Mutex
+	state      State
+	start      time.Time
+	arrival    float64
+	nextIndex  int64
+	children   *children
+	live       *child
+	cancelSent bool
+	throttle   throttle
+	done       chan struct{}
+}
+
+// NewIceberg validates cfg and creates an iceberg order; add it to a runtime to start it
+func NewIceberg(cfg IcebergConfig) (*Iceberg, error) {
+	if cfg.Market == nil {
+		return nil, errors.New("algo: market is required")
+	}
+	if cfg.Size <= 0 || cfg.DisplaySize <= 0 || cfg.Price <= 0 {
+		return nil, errors.New("algo: size, display size and price must be positive")
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = defaultIcebergInterval
+	}
+	if cfg.ClientOrderIndex <= 0 {
+		cfg.ClientOrderIndex = 1
+	}
+	total := client.BaseFromFloat(cfg.Market, cfg.Size)
+	display := min(client.BaseFromFloat(cfg.Market, cfg.DisplaySize), total)
+	if display < minBase(cfg.Market) {
+		return nil, fmt.Errorf("algo: display size %v is below the market minimum", cfg.DisplaySize)
+	}
+	return &Iceberg{
+		cfg:       cfg,
+		total:     total,
+		display:   display,
+		price:     client.PriceFromFloat(cfg.Market, cfg.Price),
+		minBase:   minBase(cfg.Market),
+		nextIndex: cfg.ClientOrderIndex,
+		children:  newChildren(cfg.Market),
+		throttle:  throttle{interval: cfg.MinInterval},
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Cancel stops the iceberg; the resting slice is canceled on the next event
+func (i *Iceberg) Cancel() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.finish(StateCanceled)
+}
+
+// Done is closed when the iceberg is filled or canceled
+func (i *Iceberg) Done() <-chan struct{} { return i.done }
+
+// Progress returns the current state of the iceberg
+func (i *Iceberg) Progress() Progress {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	p := i.children.progress(i.state, i.total, i.arrival, i.cfg.IsAsk)
+	p.Start = i.start
+	return p
+}
+
+func (i *Iceberg) OnBook(c *strategy.Context, b client.LighterOrderBookResponse) error {
+	if b.MarketId != i.cfg.Market.MarketId {
+		return nil
+	}
+	return i.step(c, c.Now())
+}
+
+func (i *Iceberg) OnOrderUpdate(c *strategy.Context, o client.LighterOrdersResponse) error {
+	i.mu.Lock()
+	ch := i.children.handleOrder(o)
+	i.mu.Unlock()
+	if ch == nil {
+		return nil
+	}
+	return i.step(c, c.Now())
+}
+
+func (i *Iceberg) OnAccount(c *strategy.Context, a client.LighterAccountResponse) error {
+	i.mu.Lock()
+	i.children.handleAccount(a)
+	i.mu.Unlock()
+	return i.step(c, c.Now())
+}
+
+func (i *Iceberg) OnTimer(c *strategy.Context, now time.Time) error {
+	return i.step(c, now)
+}
+
+// step cancels or replenishes the resting slice
+func (i *Iceberg) step(c *strategy.Context, now time.Time) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.live != nil && i.live.settled {
+		i.live = nil
+	}
+	switch i.state {
+	case StatePending:
+		i.state, i.start = StateRunning, now
+	case StateCanceled:
+		if i.live == nil || i.cancelSent || !i.throttle.ready(now) {
+			return nil
+		}
+		_, err := c.Cancel(i.cfg.Market.MarketId, i.live.clientIndex)
+		i.throttle.record(now, err)
+		if err != nil {
+			return fmt.Errorf("algo: cancel iceberg slice %d: %w", i.live.clientIndex, err)
+		}
+		i.cancelSent = true
+		return nil
+	case StateDone:
+		return nil
+	}
+
+	if i.arrival == 0 {
+		i.arrival, _ = c.Book().Mid(i.cfg.Market.MarketId)
+	}
+	filled, _, _ := i.children.totals()
+	if i.total-filled < i.minBase {
+		i.finish(StateDone)
+		return nil
+	}
+	if i.live != nil || !i.throttle.ready(now) {
+		return nil
+	}
+
+	ch := &child{clientIndex: i.nextIndex, sentAt: now, size: min(i.display, i.total-filled), price: i.price}
+	i.nextIndex++
+	txHash, err := placeResting(c, i.cfg.Market, ch, i.cfg.IsAsk, i.cfg.PostOnly)
+	i.throttle.record(now, err)
+	if err != nil {
+		return fmt.Errorf("algo: place iceberg slice %d: %w", ch.clientIndex, err)
+	}
+	ch.txHash = txHash
+	i.children.add(ch)
+	i.live = ch
+	return nil
+}
+
+func (i *Iceberg) finish(state State) {
+	if i.state.finished() {
+		return
+	}
+	i.state = state
+	close(i.done)
+}
+
+// placeResting sends a child as a good-till-time limit order
+func placeResting(c *strategy.Context, market *lighterapi.OrderBookDetail, ch *child, isAsk, postOnly bool) (string, error) {
+	tif := uint8(txtypes.GoodTillTime)
+	if postOnly {
+		tif = txtypes.PostOnly
+	}
+	return c.Place(types.CreateOrderTxReq{
+		MarketIndex:      market.MarketId,
+		ClientOrderIndex: ch.clientIndex,
+		BaseAmount:       ch.size,
+		Price:            ch.price,
+		IsAsk:            boolToUint8(isAsk),
+		Type:             txtypes.LimitOrder,
+		TimeInForce:      tif,
+	})
+}
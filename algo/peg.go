@@ -0,0 +1,299 @@
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/strategy"
+	"github.com/elliottech/lighter-go/types"
+)
+
+const defaultPegInterval = time.Second
+
+// PegReference is the book price a peg order follows
+type PegReference int
+
+const (
+	PegBid PegReference = iota
+	PegAsk
+	PegMid
+)
+
+// PegConfig describes an order that follows the book
+type PegConfig struct {
+	Market *lighterapi.OrderBookDetail
+	IsAsk  bool
+	// Size is the total size in base units
+	Size      float64
+	Reference PegReference
+	// Offset is added to the reference price; use a negative offset to bid below it
+	Offset float64
+	// LimitPrice is the worst price the order may rest at; 0 disables the guard
+	LimitPrice float64
+	// PostOnly keeps the order passive, placing it a tick inside the opposite touch when
+	// the target would cross
+	PostOnly bool
+	// Tolerance is how far the target may drift from the resting price before the order is
+	// modified; 0 follows every tick
+	Tolerance float64
+	// MinInterval spaces placements and modifications to stay within rate limits;
+	// defaults to 1s
+	MinInterval time.Duration
+	// ClientOrderIndex is the client order index of the first order; replacements count up
+	// from it. Defaults to 1.
+	ClientOrderIndex int64
+}
+
+// Peg rests a passive order at a reference price of the book plus an offset and moves it with
+// GetModifyOrderTransaction as the book changes. It implements strategy.Strategy and reprices
+// on book updates and timers. The order closes when account trades cover its size or an
+// order update reports its final status; a canceled order, for example a rejected post-only
+// order, is placed again with the remaining size.
+type Peg struct {
+	strategy.Base
+
+	cfg     PegConfig
+	total   int64
+	minBase int64
+
+	mu         sync.Mutex
+	state      State
+	start      time.Time
+	arrival    float64
+	nextIndex  int64
+	children   *children
+	live       *child
+	cancelSent bool
+	throttle   throttle
+	done       chan struct{}
+}
+
+// NewPeg validates cfg and creates a peg order; add it to a runtime to start it
+func NewPeg(cfg PegConfig) (*Peg, error) {
+	if cfg.Market == nil {
+		return nil, errors.New("algo: market is required")
+	}
+	if cfg.Size <= 0 {
+		return nil, errors.New("algo: size must be positive")
+	}
+	if cfg.Reference < PegBid || cfg.Reference > PegMid {
+		return nil, fmt.Errorf("algo: unknown peg reference %d", cfg.Reference)
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = defaultPegInterval
+	}
+	if cfg.ClientOrderIndex <= 0 {
+		cfg.ClientOrderIndex = 1
+	}
+	total := client.BaseFromFloat(cfg.Market, cfg.Size)
+	if total < minBase(cfg.Market) {
+		return nil, fmt.Errorf("algo: size %v is below the market minimum", cfg.Size)
+	}
+	return &Peg{
+		cfg:       cfg,
+		total:     total,
+		minBase:   minBase(cfg.Market),
+		nextIndex: cfg.ClientOrderIndex,
+		children:  newChildren(cfg.Market),
+		throttle:  throttle{interval: cfg.MinInterval},
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Cancel stops the peg; the resting order is canceled on the next event
+func (p *Peg) Cancel() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finish(StateCanceled)
+}
+
+// Done is closed when the peg is filled or canceled
+func (p *Peg) Done() <-chan struct{} { return p.done }
+
+// Progress returns the current state of the peg
+func (p *Peg) Progress() Progress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pr := p.children.progress(p.state, p.total, p.arrival, p.cfg.IsAsk)
+	pr.Start = p.start
+	return pr
+}
+
+func (p *Peg) OnBook(c *strategy.Context, b client.LighterOrderBookResponse) error {
+	if b.MarketId != p.cfg.Market.MarketId {
+		return nil
+	}
+	return p.step(c, c.Now())
+}
+
+func (p *Peg) OnOrderUpdate(c *strategy.Context, o client.LighterOrdersResponse) error {
+	p.mu.Lock()
+	ch := p.children.handleOrder(o)
+	p.mu.Unlock()
+	if ch == nil {
+		return nil
+	}
+	return p.step(c, c.Now())
+}
+
+func (p *Peg) OnAccount(c *strategy.Context, a client.LighterAccountResponse) error {
+	p.mu.Lock()
+	p.children.handleAccount(a)
+	p.mu.Unlock()
+	return p.step(c, c.Now())
+}
+
+func (p *Peg) OnTimer(c *strategy.Context, now time.Time) error {
+	return p.step(c, now)
+}
+
+// step places, reprices or cancels the resting order
+func (p *Peg) step(c *strategy.Context, now time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.live != nil && p.live.settled {
+		p.live = nil
+	}
+	marketId := p.cfg.Market.MarketId
+	switch p.state {
+	case StatePending:
+		mid, ok := c.Book().Mid(marketId)
+		if !ok {
+			return nil
+		}
+		p.state, p.start, p.arrival = StateRunning, now, mid
+	case StateCanceled:
+		if p.live == nil || p.cancelSent || !p.throttle.ready(now) {
+			return nil
+		}
+		_, err := c.Cancel(marketId, p.live.clientIndex)
+		p.throttle.record(now, err)
+		if err != nil {
+			return fmt.Errorf("algo: cancel peg order %d: %w", p.live.clientIndex, err)
+		}
+		p.cancelSent = true
+		return nil
+	case StateDone:
+		return nil
+	}
+
+	filled, _, _ := p.children.totals()
+	if p.total-filled < p.minBase {
+		p.finish(StateDone)
+		return nil
+	}
+	target, ok := p.target(c)
+	if !ok || !p.throttle.ready(now) {
+		return nil
+	}
+
+	if p.live == nil {
+		ch := &child{clientIndex: p.nextIndex, sentAt: now, size: p.total - filled, price: target}
+		p.nextIndex++
+		txHash, err := placeResting(c, p.cfg.Market, ch, p.cfg.IsAsk, p.cfg.PostOnly)
+		p.throttle.record(now, err)
+		if err != nil {
+			return fmt.Errorf("algo: place peg order %d: %w", ch.clientIndex, err)
+		}
+		ch.txHash = txHash
+		p.children.add(ch)
+		p.live = ch
+		return nil
+	}
+
+	drift := math.Abs(client.PriceToFloat(p.cfg.Market, target) - client.PriceToFloat(p.cfg.Market, p.live.price))
+	if target == p.live.price || drift < p.cfg.Tolerance {
+		return nil
+	}
+	_, err := c.Modify(types.ModifyOrderTxReq{
+		MarketIndex: marketId,
+		Index:       p.live.clientIndex,
+		BaseAmount:  p.live.size,
+		Price:       target,
+	})
+	p.throttle.record(now, err)
+	if err != nil {
+		return fmt.Errorf("algo: modify peg order %d: %w", p.live.clientIndex, err)
+	}
+	p.live.price = target
+	return nil
+}
+
+// target returns the price the order should rest at
+func (p *Peg) target(c *strategy.Context) (uint32, bool) {
+	marketId := p.cfg.Market.MarketId
+	bid, okBid := p.touch(c.Book().Bids(marketId), false)
+	ask, okAsk := p.touch(c.Book().Asks(marketId), true)
+
+	var ref float64
+	switch p.cfg.Reference {
+	case PegBid:
+		ref = bid
+		if !okBid {
+			return 0, false
+		}
+	case PegAsk:
+		ref = ask
+		if !okAsk {
+			return 0, false
+		}
+	case PegMid:
+		if !okBid || !okAsk {
+			return 0, false
+		}
+		ref = (bid + ask) / 2
+	}
+
+	price := ref + p.cfg.Offset
+	tick := math.Pow10(-int(p.cfg.Market.PriceDecimals))
+	if p.cfg.IsAsk {
+		if p.cfg.LimitPrice > 0 {
+			price = math.Max(price, p.cfg.LimitPrice)
+		}
+		if p.cfg.PostOnly && okBid {
+			price = math.Max(price, bid+tick)
+		}
+		price = math.Ceil(price/tick-1e-9) * tick
+	} else {
+		if p.cfg.LimitPrice > 0 {
+			price = math.Min(price, p.cfg.LimitPrice)
+		}
+		if p.cfg.PostOnly && okAsk {
+			price = math.Min(price, ask-tick)
+		}
+		price = math.Floor(price/tick+1e-9) * tick
+	}
+	if price <= 0 {
+		return 0, false
+	}
+	return client.PriceFromFloat(p.cfg.Market, price), true
+}
+
+// touch returns the best price of one side, skipping a level made up only of the peg's own
+// order so the peg does not follow itself
+func (p *Peg) touch(levels []client.BookLevel, isAsk bool) (float64, bool) {
+	for _, l := range levels {
+		if p.live != nil && isAsk == p.cfg.IsAsk && client.PriceFromFloat(p.cfg.Market, l.Price) == p.live.price {
+			own := client.BaseToFloat(p.cfg.Market, p.live.size-p.live.filled)
+			if l.Size <= own+client.BaseToFloat(p.cfg.Market, 1)/2 {
+				continue
+			}
+		}
+		return l.Price, true
+	}
+	return 0, false
+}
+
+func (p *Peg) finish(state State) {
+	if p.state.finished() {
+		return
+	}
+	p.state = state
+	close(p.done)
+}
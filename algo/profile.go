@@ -0,0 +1,68 @@
+package algo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/defi-maker/golighter/client"
+)
+
+const day = 24 * time.Hour
+
+// VolumeProfile splits a schedule of slices equal parts of horizon starting at start into
+// weights proportional to the historical volume traded at the same time of day. Candles
+// outside those windows are ignored; without volume the profile is flat. Weights sum to 1.
+func VolumeProfile(candles []client.Candle, start time.Time, horizon time.Duration, slices int) []float64 {
+	if slices <= 0 {
+		return nil
+	}
+	weights := make([]float64, slices)
+	width := horizon / time.Duration(slices)
+	if width > 0 {
+		origin := start.UTC().Sub(start.UTC().Truncate(day))
+		for _, c := range candles {
+			at := time.UnixMilli(c.Start).UTC()
+			offset := (at.Sub(at.Truncate(day)) - origin + day) % day
+			if offset >= horizon {
+				continue
+			}
+			weights[int(offset/width)%slices] += c.Volume
+		}
+	}
+
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	for i := range weights {
+		if total > 0 {
+			weights[i] /= total
+		} else {
+			weights[i] = 1 / float64(slices)
+		}
+	}
+	return weights
+}
+
+// LoadVolumeProfile builds a VolumeProfile from the candlesticks of the last days days,
+// bucketed at the slice width rounded down to whole minutes
+func LoadVolumeProfile(ctx context.Context, api *client.Client, marketId uint8, start time.Time, horizon time.Duration, slices, days int) ([]float64, error) {
+	if slices <= 0 || horizon <= 0 || days <= 0 {
+		return nil, errors.New("algo: slices, horizon and days must be positive")
+	}
+	resolution := (horizon / time.Duration(slices)).Truncate(time.Minute)
+	if resolution < time.Minute {
+		resolution = time.Minute
+	}
+	builder, err := client.NewCandleBuilder(api, marketId, resolution)
+	if err != nil {
+		return nil, err
+	}
+	builder.SetMaxHistory(days * int(day/resolution))
+	now := time.Now()
+	if err := builder.Backfill(ctx, now.Add(-time.Duration(days)*day), now); err != nil {
+		return nil, err
+	}
+	return VolumeProfile(builder.Candles(), start, horizon, slices), nil
+}
@@ -0,0 +1,32 @@
+package algo
+
+import (
+	"time"
+
+	"github.com/defi-maker/golighter/client"
+)
+
+const maxThrottleBackoff = 30 * time.Second
+
+// throttle spaces order actions by a minimum interval on the strategy clock. Hooks must not
+// block, so an action that is not ready is skipped and retried on a later event. After a
+// rate limited response the interval doubles until an action succeeds.
+type throttle struct {
+	interval time.Duration
+	backoff  time.Duration
+	last     time.Time
+}
+
+func (t *throttle) ready(now time.Time) bool {
+	return t.last.IsZero() || now.Sub(t.last) >= t.interval+t.backoff
+}
+
+func (t *throttle) record(now time.Time, err error) {
+	t.last = now
+	switch {
+	case client.IsRateLimited(err):
+		t.backoff = min(max(2*t.backoff, t.interval, time.Second), maxThrottleBackoff)
+	case err == nil:
+		t.backoff = 0
+	}
+}
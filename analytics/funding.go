@@ -0,0 +1,410 @@
+// Package analytics derives funding, PnL and accounting reports from Lighter REST data.
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+)
+
+const hoursPerYear = 24 * 365
+
+// DefaultFundingIntervals holds the funding period of each exchange reported by FundingRates
+var DefaultFundingIntervals = map[lighterapi.FundingRateExchange]time.Duration{
+	lighterapi.Lighter:     time.Hour,
+	lighterapi.Hyperliquid: time.Hour,
+	lighterapi.Binance:     8 * time.Hour,
+	lighterapi.Bybit:       8 * time.Hour,
+}
+
+// AnnualizeRate converts a per-period funding rate into a simple annual rate
+func AnnualizeRate(rate float64, interval time.Duration) float64 {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return rate * hoursPerYear * float64(time.Hour) / float64(interval)
+}
+
+// FundingQuote is one exchange's current funding rate for a symbol
+type FundingQuote struct {
+	Exchange   lighterapi.FundingRateExchange
+	Rate       float64
+	Interval   time.Duration
+	Annualized float64
+}
+
+// FundingSpread compares Lighter against another exchange. Spread is the annualized
+// Lighter rate minus the other exchange's rate, so a positive value means longs pay
+// more on Lighter.
+type FundingSpread struct {
+	Exchange lighterapi.FundingRateExchange
+	Spread   float64
+}
+
+// FundingComparison groups the funding quotes of a symbol across exchanges
+type FundingComparison struct {
+	Symbol   string
+	MarketId uint8
+	Lighter  *FundingQuote
+	Others   []FundingQuote
+	Spreads  []FundingSpread
+}
+
+// MaxSpread returns the spread with the largest magnitude, if any
+func (c FundingComparison) MaxSpread() (FundingSpread, bool) {
+	var best FundingSpread
+	found := false
+	for _, s := range c.Spreads {
+		if !found || math.Abs(s.Spread) > math.Abs(best.Spread) {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// CompareFundingRates groups rates by symbol and computes the annualized spread of Lighter
+// versus every other exchange. intervals overrides DefaultFundingIntervals per exchange.
+// Symbols without a Lighter quote are returned without spreads.
+func CompareFundingRates(rates []lighterapi.FundingRate, intervals map[lighterapi.FundingRateExchange]time.Duration) []FundingComparison {
+	bySymbol := make(map[string]*FundingComparison)
+	for _, r := range rates {
+		symbol := normalizeSymbol(r.Symbol)
+		cmp, ok := bySymbol[symbol]
+		if !ok {
+			cmp = &FundingComparison{Symbol: symbol}
+			bySymbol[symbol] = cmp
+		}
+
+		interval := fundingInterval(r.Exchange, intervals)
+		quote := FundingQuote{
+			Exchange:   r.Exchange,
+			Rate:       r.Rate,
+			Interval:   interval,
+			Annualized: AnnualizeRate(r.Rate, interval),
+		}
+		if r.Exchange == lighterapi.Lighter {
+			cmp.MarketId = r.MarketId
+			cmp.Lighter = &quote
+			continue
+		}
+		cmp.Others = append(cmp.Others, quote)
+	}
+
+	out := make([]FundingComparison, 0, len(bySymbol))
+	for _, cmp := range bySymbol {
+		sort.Slice(cmp.Others, func(i, j int) bool { return cmp.Others[i].Exchange < cmp.Others[j].Exchange })
+		if cmp.Lighter != nil {
+			for _, other := range cmp.Others {
+				cmp.Spreads = append(cmp.Spreads, FundingSpread{
+					Exchange: other.Exchange,
+					Spread:   cmp.Lighter.Annualized - other.Annualized,
+				})
+			}
+		}
+		out = append(out, *cmp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// FundingStats summarizes a market's funding history from Fundings
+type FundingStats struct {
+	Count      int
+	Mean       float64
+	Min        float64
+	Max        float64
+	Annualized float64
+}
+
+// SummarizeFundings computes statistics over Fundings entries paid every interval
+func SummarizeFundings(fundings []lighterapi.Funding, interval time.Duration) FundingStats {
+	var stats FundingStats
+	sum := 0.0
+	for _, f := range fundings {
+		rate, err := strconv.ParseFloat(f.Rate, 64)
+		if err != nil {
+			continue
+		}
+		if stats.Count == 0 || rate < stats.Min {
+			stats.Min = rate
+		}
+		if stats.Count == 0 || rate > stats.Max {
+			stats.Max = rate
+		}
+		sum += rate
+		stats.Count++
+	}
+	if stats.Count > 0 {
+		stats.Mean = sum / float64(stats.Count)
+		stats.Annualized = AnnualizeRate(stats.Mean, interval)
+	}
+	return stats
+}
+
+// RealizedFunding is the funding an account paid and received on one market and side.
+// Paid and Received are both positive USDC amounts; Net is Received minus Paid.
+type RealizedFunding struct {
+	MarketId uint8
+	Side     lighterapi.PositionFundingPositionSide
+	Paid     float64
+	Received float64
+	Net      float64
+	Payments int
+	First    int64
+	Last     int64
+}
+
+// AggregatePositionFunding sums PositionFunding changes per market and position side.
+// A positive change is funding received, a negative change funding paid.
+func AggregatePositionFunding(entries []lighterapi.PositionFunding) []RealizedFunding {
+	type key struct {
+		market uint8
+		side   lighterapi.PositionFundingPositionSide
+	}
+	byKey := make(map[key]*RealizedFunding)
+	for _, e := range entries {
+		change, err := strconv.ParseFloat(e.Change, 64)
+		if err != nil {
+			continue
+		}
+		k := key{e.MarketId, e.PositionSide}
+		r, ok := byKey[k]
+		if !ok {
+			r = &RealizedFunding{MarketId: e.MarketId, Side: e.PositionSide, First: e.Timestamp, Last: e.Timestamp}
+			byKey[k] = r
+		}
+		if change >= 0 {
+			r.Received += change
+		} else {
+			r.Paid -= change
+		}
+		r.Net += change
+		r.Payments++
+		r.First = min(r.First, e.Timestamp)
+		r.Last = max(r.Last, e.Timestamp)
+	}
+
+	out := make([]RealizedFunding, 0, len(byKey))
+	for _, r := range byKey {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].MarketId != out[j].MarketId {
+			return out[i].MarketId < out[j].MarketId
+		}
+		return out[i].Side < out[j].Side
+	})
+	return out
+}
+
+// FundingAnalyzer fetches funding data through a REST client
+type FundingAnalyzer struct {
+	api       *client.Client
+	intervals map[lighterapi.FundingRateExchange]time.Duration
+}
+
+// NewFundingAnalyzer creates an analyzer using DefaultFundingIntervals
+func NewFundingAnalyzer(api *client.Client) (*FundingAnalyzer, error) {
+	if api == nil {
+		return nil, errors.New("analytics: nil client")
+	}
+	return &FundingAnalyzer{api: api}, nil
+}
+
+// SetInterval overrides the funding period used to annualize an exchange's rates
+func (a *FundingAnalyzer) SetInterval(exchange lighterapi.FundingRateExchange, interval time.Duration) {
+	if a.intervals == nil {
+		a.intervals = make(map[lighterapi.FundingRateExchange]time.Duration)
+	}
+	a.intervals[exchange] = interval
+}
+
+// Compare fetches current funding rates and compares them per symbol
+func (a *FundingAnalyzer) Compare(ctx context.Context) ([]FundingComparison, error) {
+	resp, err := a.api.FundingRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return CompareFundingRates(resp.FundingRates, a.intervals), nil
+}
+
+// History summarizes a market's funding between from and to
+func (a *FundingAnalyzer) History(ctx context.Context, marketId uint8, from, to time.Time) (FundingStats, error) {
+	resp, err := a.api.Fundings(ctx, &lighterapi.FundingsParams{
+		MarketId:       marketId,
+		Resolution:     lighterapi.FundingsParamsResolutionN1h,
+		StartTimestamp: from.UnixMilli(),
+		EndTimestamp:   to.UnixMilli(),
+		CountBack:      int64(to.Sub(from) / time.Hour),
+	})
+	if err != nil {
+		return FundingStats{}, err
+	}
+	return SummarizeFundings(resp.Fundings, fundingInterval(lighterapi.Lighter, a.intervals)), nil
+}
+
+// Realized returns the funding an account paid and received since the given time.
+// A nil marketId covers all markets.
+func (a *FundingAnalyzer) Realized(ctx context.Context, accountIndex int64, marketId *uint8, since time.Time) ([]RealizedFunding, error) {
+	entries, err := a.api.PositionFundingHistory(ctx, accountIndex, marketId, since.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return AggregatePositionFunding(entries), nil
+}
+
+// SpreadAlert is emitted when the annualized spread of a symbol crosses its threshold
+type SpreadAlert struct {
+	Symbol    string
+	MarketId  uint8
+	Exchange  lighterapi.FundingRateExchange
+	Spread    float64
+	Threshold float64
+	Time      time.Time
+}
+
+func (a SpreadAlert) String() string {
+	return fmt.Sprintf("%s lighter vs %s annualized spread %.2f%% exceeds %.2f%%",
+		a.Symbol, a.Exchange, a.Spread*100, a.Threshold*100)
+}
+
+// BasisMonitor polls funding rates and reports spreads whose magnitude exceeds a threshold.
+// An alert fires once when a spread crosses its threshold and re-arms after it falls back.
+type BasisMonitor struct {
+	analyzer *FundingAnalyzer
+
+	mu        sync.Mutex
+	threshold float64
+	symbols   map[string]float64
+	onAlert   func(SpreadAlert)
+	active    map[string]bool
+	last      []FundingComparison
+}
+
+// NewBasisMonitor creates a monitor alerting on annualized spreads above threshold (0.1 = 10%)
+func NewBasisMonitor(analyzer *FundingAnalyzer, threshold float64) *BasisMonitor {
+	return &BasisMonitor{
+		analyzer:  analyzer,
+		threshold: threshold,
+		symbols:   make(map[string]float64),
+		active:    make(map[string]bool),
+	}
+}
+
+// SetThreshold overrides the threshold for a single symbol
+func (m *BasisMonitor) SetThreshold(symbol string, threshold float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.symbols[normalizeSymbol(symbol)] = threshold
+}
+
+// OnAlert registers the alert callback
+func (m *BasisMonitor) OnAlert(fn func(SpreadAlert)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onAlert = fn
+}
+
+// Latest returns the comparisons from the most recent check
+func (m *BasisMonitor) Latest() []FundingComparison {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// Check fetches rates once and returns the alerts that fired
+func (m *BasisMonitor) Check(ctx context.Context) ([]SpreadAlert, error) {
+	comparisons, err := m.analyzer.Compare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.Evaluate(comparisons, time.Now()), nil
+}
+
+// Evaluate applies thresholds to comparisons, invoking the callback for new alerts
+func (m *BasisMonitor) Evaluate(comparisons []FundingComparison, now time.Time) []SpreadAlert {
+	m.mu.Lock()
+	var alerts []SpreadAlert
+	for _, cmp := range comparisons {
+		threshold, ok := m.symbols[cmp.Symbol]
+		if !ok {
+			threshold = m.threshold
+		}
+		for _, s := range cmp.Spreads {
+			key := cmp.Symbol + "/" + string(s.Exchange)
+			if math.Abs(s.Spread) <= threshold {
+				delete(m.active, key)
+				continue
+			}
+			if m.active[key] {
+				continue
+			}
+			m.active[key] = true
+			alerts = append(alerts, SpreadAlert{
+				Symbol:    cmp.Symbol,
+				MarketId:  cmp.MarketId,
+				Exchange:  s.Exchange,
+				Spread:    s.Spread,
+				Threshold: threshold,
+				Time:      now,
+			})
+		}
+	}
+	m.last = comparisons
+	onAlert := m.onAlert
+	m.mu.Unlock()
+
+	if onAlert != nil {
+		for _, alert := range alerts {
+			onAlert(alert)
+		}
+	}
+	return alerts
+}
+
+// Run checks every interval until ctx is done. Fetch errors are passed to onError when set.
+func (m *BasisMonitor) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Check(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func fundingInterval(exchange lighterapi.FundingRateExchange, overrides map[lighterapi.FundingRateExchange]time.Duration) time.Duration {
+	if d, ok := overrides[exchange]; ok && d > 0 {
+		return d
+	}
+	if d, ok := DefaultFundingIntervals[exchange]; ok {
+		return d
+	}
+	return time.Hour
+}
+
+// normalizeSymbol strips quote suffixes so "BTC", "BTCUSDT" and "BTC-USD" compare equal
+func normalizeSymbol(symbol string) string {
+	s := strings.ToUpper(strings.TrimSpace(symbol))
+	for _, suffix := range []string{"-PERP", "PERP", "USDT", "USDC", "USD"} {
+		if trimmed := strings.TrimSuffix(s, suffix); trimmed != s && trimmed != "" {
+			s = trimmed
+			break
+		}
+	}
+	return strings.TrimRight(s, "-_/")
+}
@@ -0,0 +1,379 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// LotMethod selects which open lots a closing fill is matched against
+type LotMethod string
+
+const (
+	FIFO        LotMethod = "fifo"
+	LIFO        LotMethod = "lifo"
+	AverageCost LotMethod = "average"
+)
+
+// Fill is one execution on the account's side of a trade. Size is signed: positive for buys.
+type Fill struct {
+	TradeId        int64
+	MarketId       uint8
+	Time           time.Time
+	Size           float64
+	Price          float64
+	Fee            float64
+	Maker          bool
+	PositionBefore *float64
+}
+
+// FillsFromTrade returns the account's fills in a REST trade; self-trades yield two fills
+func FillsFromTrade(t lighterapi.Trade, accountIndex int64) []Fill {
+	return fillsFrom(accountIndex, t.TradeId, t.MarketId, t.Timestamp, t.Size, t.Price, t.UsdAmount,
+		t.IsMakerAsk, t.AskAccountId, t.BidAccountId, int64(t.MakerFee), int64(t.TakerFee),
+		t.MakerPositionSizeBefore, t.TakerPositionSizeBefore)
+}
+
+// FillsFromWSTrade returns the account's fills in a stream trade
+func FillsFromWSTrade(t client.WSTrade, accountIndex int64) []Fill {
+	return fillsFrom(accountIndex, t.TradeId, uint8(t.MarketId), t.Timestamp, t.Size, t.Price, t.UsdAmount,
+		t.IsMakerAsk, t.AskAccountId, t.BidAccountId, int64(t.MakerFee), int64(t.TakerFee),
+		t.MakerPositionSizeBefore, t.TakerPositionSizeBefore)
+}
+
+func fillsFrom(accountIndex, tradeId int64, marketId uint8, ts int64, size, price, usdAmount string,
+	isMakerAsk bool, askAccount, bidAccount, makerFee, takerFee int64, makerBefore, takerBefore string) []Fill {
+	qty, px, notional := parseFloat(size), parseFloat(price), parseFloat(usdAmount)
+	at := time.UnixMilli(client.UnixMillis(ts)).UTC()
+
+	var fills []Fill
+	for _, side := range []struct {
+		account int64
+		sign    float64
+		maker   bool
+	}{
+		{askAccount, -1, isMakerAsk},
+		{bidAccount, 1, !isMakerAsk},
+	} {
+		if side.account != accountIndex {
+			continue
+		}
+		rate, before := takerFee, takerBefore
+		if side.maker {
+			rate, before = makerFee, makerBefore
+		}
+		fill := Fill{
+			TradeId:  tradeId,
+			MarketId: marketId,
+			Time:     at,
+			Size:     side.sign * qty,
+			Price:    px,
+			Fee:      notional * float64(rate) / float64(txtypes.FeeTick),
+			Maker:    side.maker,
+		}
+		if v, err := strconv.ParseFloat(before, 64); err == nil && before != "" {
+			fill.PositionBefore = &v
+		}
+		fills = append(fills, fill)
+	}
+	return fills
+}
+
+// Lot is an open position slice with its share of the opening fee
+type Lot struct {
+	TradeId int64
+	Opened  time.Time
+	Size    float64
+	Price   float64
+	Fee     float64
+}
+
+// Realization is one matched close of (part of) a lot
+type Realization struct {
+	MarketId    uint8
+	TradeId     int64
+	Time        time.Time
+	Size        float64 // signed size of the lot that was closed
+	OpenTradeId int64
+	Opened      time.Time
+	OpenPrice   float64
+	ClosePrice  float64
+	Gross       float64
+	OpenFee     float64
+	CloseFee    float64
+	Net         float64
+}
+
+// Unrealized is the open position of a market valued at a mark price
+type Unrealized struct {
+	MarketId uint8
+	Size     float64
+	AvgCost  float64
+	Mark     float64
+	Pnl      float64
+	OpenFees float64
+	Lots     []Lot
+}
+
+// PositionMismatch records a fill whose reported prior position differs from the engine's
+type PositionMismatch struct {
+	TradeId  int64
+	MarketId uint8
+	Expected float64
+	Reported float64
+}
+
+const positionTolerance = 1e-9
+
+type fillKey struct {
+	tradeId int64
+	buy     bool
+}
+
+// LotEngine matches fills into lots per market and keeps a realized PnL ledger
+type LotEngine struct {
+	mu         sync.Mutex
+	method     LotMethod
+	account    int64
+	lots       map[uint8][]Lot
+	seen       map[fillKey]bool
+	ledger     []Realization
+	mismatches []PositionMismatch
+	onRealized func(Realization)
+}
+
+// NewLotEngine creates an engine for one account
+func NewLotEngine(accountIndex int64, method LotMethod) (*LotEngine, error) {
+	switch method {
+	case FIFO, LIFO, AverageCost:
+	default:
+		return nil, fmt.Errorf("analytics: unknown lot method %q", method)
+	}
+	return &LotEngine{
+		method:  method,
+		account: accountIndex,
+		lots:    make(map[uint8][]Lot),
+		seen:    make(map[fillKey]bool),
+	}, nil
+}
+
+// OnRealized registers a callback invoked for each ledger entry
+func (e *LotEngine) OnRealized(fn func(Realization)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onRealized = fn
+}
+
+// SetOpening seeds a market with a position opened before the first processed fill
+func (e *LotEngine) SetOpening(marketId uint8, size, avgPrice float64, opened time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lots[marketId] = nil
+	if size != 0 {
+		e.lots[marketId] = []Lot{{Opened: opened, Size: size, Price: avgPrice}}
+	}
+}
+
+// ApplyTrades feeds REST trades in trade id order, skipping trades already processed
+func (e *LotEngine) ApplyTrades(trades []lighterapi.Trade) []Realization {
+	sorted := append([]lighterapi.Trade(nil), trades...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].TradeId < sorted[j].TradeId })
+	var out []Realization
+	for _, t := range sorted {
+		for _, fill := range FillsFromTrade(t, e.account) {
+			out = append(out, e.Apply(fill)...)
+		}
+	}
+	return out
+}
+
+// HandleAccount consumes the trades carried by account_all updates; pass it to SubscribeAccount
+func (e *LotEngine) HandleAccount(resp client.LighterAccountResponse) error {
+	if resp.RawAccountUpdate == nil {
+		return nil
+	}
+	var trades []client.WSTrade
+	for _, list := range resp.RawAccountUpdate.Trades {
+		trades = append(trades, list...)
+	}
+	sort.Slice(trades, func(i, j int) bool { return trades[i].TradeId < trades[j].TradeId })
+	for _, t := range trades {
+		for _, fill := range FillsFromWSTrade(t, e.account) {
+			e.Apply(fill)
+		}
+	}
+	return nil
+}
+
+// Apply matches a fill against the market's open lots and returns the realizations it produced.
+// Fills are deduplicated by trade id and side.
+func (e *LotEngine) Apply(fill Fill) []Realization {
+	if fill.Size == 0 {
+		return nil
+	}
+	e.mu.Lock()
+	key := fillKey{fill.TradeId, fill.Size > 0}
+	if fill.TradeId != 0 && e.seen[key] {
+		e.mu.Unlock()
+		return nil
+	}
+	e.seen[key] = true
+
+	lots := e.lots[fill.MarketId]
+	if fill.PositionBefore != nil {
+		if held := lotsSize(lots); math.Abs(held-*fill.PositionBefore) > positionTolerance*math.Max(1, math.Abs(held)) {
+			e.mismatches = append(e.mismatches, PositionMismatch{
+				TradeId:  fill.TradeId,
+				MarketId: fill.MarketId,
+				Expected: held,
+				Reported: *fill.PositionBefore,
+			})
+		}
+	}
+
+	var realized []Realization
+	remaining := fill.Size
+	for remaining != 0 && len(lots) > 0 && !sameSign(lots[0].Size, remaining) {
+		i := 0
+		if e.method == LIFO {
+			i = len(lots) - 1
+		}
+		lot := &lots[i]
+		closed := math.Min(math.Abs(lot.Size), math.Abs(remaining))
+		share := closed / math.Abs(lot.Size)
+		sign := math.Copysign(1, lot.Size)
+
+		r := Realization{
+			MarketId:    fill.MarketId,
+			TradeId:     fill.TradeId,
+			Time:        fill.Time,
+			Size:        sign * closed,
+			OpenTradeId: lot.TradeId,
+			Opened:      lot.Opened,
+			OpenPrice:   lot.Price,
+			ClosePrice:  fill.Price,
+			Gross:       sign * closed * (fill.Price - lot.Price),
+			OpenFee:     lot.Fee * share,
+			CloseFee:    fill.Fee * closed / math.Abs(fill.Size),
+		}
+		r.Net = r.Gross - r.OpenFee - r.CloseFee
+		realized = append(realized, r)
+
+		lot.Fee -= r.OpenFee
+		lot.Size -= sign * closed
+		remaining += sign * closed
+		if math.Abs(lot.Size) <= positionTolerance {
+			lots = append(lots[:i], lots[i+1:]...)
+		}
+	}
+
+	if math.Abs(remaining) > positionTolerance {
+		lot := Lot{
+			TradeId: fill.TradeId,
+			Opened:  fill.Time,
+			Size:    remaining,
+			Price:   fill.Price,
+			Fee:     fill.Fee * math.Abs(remaining) / math.Abs(fill.Size),
+		}
+		if e.method == AverageCost && len(lots) > 0 {
+			merged := lots[0]
+			total := merged.Size + lot.Size
+			merged.Price = (merged.Price*merged.Size + lot.Price*lot.Size) / total
+			merged.Size = total
+			merged.Fee += lot.Fee
+			lots[0] = merged
+		} else {
+			lots = append(lots, lot)
+		}
+	}
+	e.lots[fill.MarketId] = lots
+	e.ledger = append(e.ledger, realized...)
+	onRealized := e.onRealized
+	e.mu.Unlock()
+
+	if onRealized != nil {
+		for _, r := range realized {
+			onRealized(r)
+		}
+	}
+	return realized
+}
+
+// Ledger returns every realization in processing order
+func (e *LotEngine) Ledger() []Realization {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Realization(nil), e.ledger...)
+}
+
+// Mismatches returns fills whose reported prior position did not match the lots
+func (e *LotEngine) Mismatches() []PositionMismatch {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]PositionMismatch(nil), e.mismatches...)
+}
+
+// Lots returns the open lots of a market
+func (e *LotEngine) Lots(marketId uint8) []Lot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Lot(nil), e.lots[marketId]...)
+}
+
+// Unrealized values open positions at the given mark prices; markets without a mark are skipped
+func (e *LotEngine) Unrealized(marks map[uint8]float64) []Unrealized {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var out []Unrealized
+	for marketId, lots := range e.lots {
+		mark, ok := marks[marketId]
+		if !ok || len(lots) == 0 {
+			continue
+		}
+		u := Unrealized{MarketId: marketId, Mark: mark, Lots: append([]Lot(nil), lots...)}
+		cost := 0.0
+		for _, lot := range lots {
+			u.Size += lot.Size
+			cost += lot.Size * lot.Price
+			u.Pnl += lot.Size * (mark - lot.Price)
+			u.OpenFees += lot.Fee
+		}
+		if u.Size != 0 {
+			u.AvgCost = cost / u.Size
+		}
+		out = append(out, u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].MarketId < out[j].MarketId })
+	return out
+}
+
+// RealizedByMarket sums net realized PnL per market
+func (e *LotEngine) RealizedByMarket() map[uint8]float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	totals := make(map[uint8]float64)
+	for _, r := range e.ledger {
+		totals[r.MarketId] += r.Net
+	}
+	return totals
+}
+
+func lotsSize(lots []Lot) float64 {
+	total := 0.0
+	for _, lot := range lots {
+		total += lot.Size
+	}
+	return total
+}
+
+func sameSign(a, b float64) bool {
+	return (a > 0) == (b > 0)
+}
@@ -0,0 +1,127 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/defi-maker/golighter/analytics"
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/lightertest"
+)
+
+// roundTrip trades on the fake exchange: the trader takes 0.1 at 3000 and 0.1 at 3100, then
+// sells 0.15 at 3200 as maker. Taker fees are 0.15 and 0.155, the maker fee 0.096.
+func roundTrip(t *testing.T) []lighterapi.Trade {
+	t.Helper()
+	srv, _, _ := lightertest.NewExchange(t)
+	for _, o := range []struct {
+		account int64
+		isAsk   bool
+		price   uint32
+		base    int64
+	}{
+		{lightertest.MakerAccount, true, 300000, 1000},
+		{trader, false, 300000, 1000},
+		{lightertest.MakerAccount, true, 310000, 1000},
+		{trader, false, 310000, 1000},
+		{trader, true, 320000, 1500},
+		{lightertest.MakerAccount, false, 320000, 1500},
+	} {
+		if _, err := srv.PlaceOrder(o.account, 0, o.isAsk, o.price, o.base); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trades := srv.Trades(0)
+	if len(trades) != 3 {
+		t.Fatalf("%d trades, want 3", len(trades))
+	}
+	return trades
+}
+
+func TestLotMethods(t *testing.T) {
+	trades := roundTrip(t)
+	for _, tt := range []struct {
+		method     analytics.LotMethod
+		closes     []float64 // open price of each realization
+		gross, net float64
+		left       analytics.Lot
+	}{
+		{analytics.FIFO, []float64{3000, 3100}, 25, 25 - 0.15 - 0.0775 - 0.096, analytics.Lot{Size: 0.05, Price: 3100, Fee: 0.0775}},
+		{analytics.LIFO, []float64{3100, 3000}, 20, 20 - 0.155 - 0.075 - 0.096, analytics.Lot{Size: 0.05, Price: 3000, Fee: 0.075}},
+		{analytics.AverageCost, []float64{3050}, 22.5, 22.5 - 0.22875 - 0.096, analytics.Lot{Size: 0.05, Price: 3050, Fee: 0.07625}},
+	} {
+		t.Run(string(tt.method), func(t *testing.T) {
+			e, err := analytics.NewLotEngine(trader, tt.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var notified int
+			e.OnRealized(func(analytics.Realization) { notified++ })
+
+			realized := e.ApplyTrades(trades)
+			if len(realized) != len(tt.closes) || notified != len(tt.closes) {
+				t.Fatalf("%d realizations, %d notified, want %d", len(realized), notified, len(tt.closes))
+			}
+			gross, net := 0.0, 0.0
+			for i, r := range realized {
+				if r.OpenPrice != tt.closes[i] || r.ClosePrice != 3200 || r.Size <= 0 {
+					t.Fatalf("realization %d %+v, want a long lot from %v closed at 3200", i, r, tt.closes[i])
+				}
+				gross += r.Gross
+				net += r.Net
+			}
+			if !near(gross, tt.gross) || !near(net, tt.net) {
+				t.Fatalf("gross %v net %v, want %v and %v", gross, net, tt.gross, tt.net)
+			}
+			if pnl := e.RealizedByMarket()[0]; !near(pnl, tt.net) {
+				t.Fatalf("realized by market %v, want %v", pnl, tt.net)
+			}
+
+			lots := e.Lots(0)
+			if len(lots) != 1 || !near(lots[0].Size, tt.left.Size) || !near(lots[0].Price, tt.left.Price) || !near(lots[0].Fee, tt.left.Fee) {
+				t.Fatalf("open lots %+v, want %+v", lots, tt.left)
+			}
+			u := e.Unrealized(map[uint8]float64{0: 3300, 1: 100})
+			if len(u) != 1 || !near(u[0].Pnl, 0.05*(3300-tt.left.Price)) || !near(u[0].AvgCost, tt.left.Price) {
+				t.Fatalf("unrealized %+v, want the open lot marked at 3300", u)
+			}
+
+			// The fake reports the prior position on every trade and it matched the lots
+			if m := e.Mismatches(); len(m) != 0 {
+				t.Fatalf("mismatches %+v", m)
+			}
+			if again := e.ApplyTrades(trades); len(again) != 0 || len(e.Ledger()) != len(tt.closes) {
+				t.Fatal("replayed trades were applied twice")
+			}
+		})
+	}
+}
+
+func TestLotEngineFlipsAndFlagsMismatches(t *testing.T) {
+	e, err := analytics.NewLotEngine(trader, analytics.FIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.SetOpening(0, 0.2, 2900, opened)
+
+	// The exchange says the account was flat, not long 0.2
+	trades := roundTrip(t)
+	e.ApplyTrades(trades[:1])
+	if m := e.Mismatches(); len(m) != 1 || m[0].Expected != 0.2 || m[0].Reported != 0 {
+		t.Fatalf("mismatches %+v, want the seeded 0.2 against a reported 0", m)
+	}
+
+	// Selling through the position closes the lots and opens a short with the rest
+	realized := e.Apply(analytics.Fill{TradeId: 100, MarketId: 0, Time: opened, Size: -0.5, Price: 3000, Fee: 1})
+	if len(realized) != 2 || realized[0].OpenPrice != 2900 || !near(realized[0].CloseFee, 0.4) {
+		t.Fatalf("realizations %+v, want the seeded lot closed first with 0.4 of the fee", realized)
+	}
+	if lots := e.Lots(0); len(lots) != 1 || !near(lots[0].Size, -0.2) || !near(lots[0].Fee, 0.4) {
+		t.Fatalf("open lots %+v, want a 0.2 short carrying 0.4 of fees", lots)
+	}
+
+	if _, err := analytics.NewLotEngine(trader, "hifo"); err == nil {
+		t.Fatal("expected an unknown lot method to be refused")
+	}
+}
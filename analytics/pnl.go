@@ -0,0 +1,354 @@
+package analytics
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// ReportPeriod is the length of a PnL statement
+type ReportPeriod string
+
+const (
+	PeriodDaily   ReportPeriod = "daily"
+	PeriodWeekly  ReportPeriod = "weekly"
+	PeriodMonthly ReportPeriod = "monthly"
+)
+
+// periodStart returns the UTC start of the period containing t; weeks start on Monday
+func (p ReportPeriod) periodStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case PeriodWeekly:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case PeriodMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func (p ReportPeriod) next(start time.Time) time.Time {
+	switch p {
+	case PeriodWeekly:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func (p ReportPeriod) valid() bool {
+	return p == PeriodDaily || p == PeriodWeekly || p == PeriodMonthly
+}
+
+// Statement is the PnL of an account over one period.
+//
+// TradePnl, PoolPnl and the flows come from Pnl; Funding comes from PositionFunding and fees
+// from the account's side of each Trade. PricePnl is TradePnl with funding and fees removed,
+// i.e. the part explained by price moves.
+type Statement struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	TradePnl        float64   `json:"trade_pnl"`
+	PricePnl        float64   `json:"price_pnl"`
+	Funding         float64   `json:"funding"`
+	Fees            float64   `json:"fees"`
+	MakerFees       float64   `json:"maker_fees"`
+	TakerFees       float64   `json:"taker_fees"`
+	PoolPnl         float64   `json:"pool_pnl"`
+	TotalPnl        float64   `json:"total_pnl"`
+	Inflow          float64   `json:"inflow"`
+	Outflow         float64   `json:"outflow"`
+	PoolInflow      float64   `json:"pool_inflow"`
+	PoolOutflow     float64   `json:"pool_outflow"`
+	NetFlow         float64   `json:"net_flow"`
+	Volume          float64   `json:"volume"`
+	Trades          int       `json:"trades"`
+	FundingPayments int       `json:"funding_payments"`
+	PnlRows         int       `json:"pnl_rows"`
+	Warnings        []string  `json:"warnings,omitempty"`
+}
+
+func (s *Statement) add(o Statement) {
+	s.TradePnl += o.TradePnl
+	s.PricePnl += o.PricePnl
+	s.Funding += o.Funding
+	s.Fees += o.Fees
+	s.MakerFees += o.MakerFees
+	s.TakerFees += o.TakerFees
+	s.PoolPnl += o.PoolPnl
+	s.TotalPnl += o.TotalPnl
+	s.Inflow += o.Inflow
+	s.Outflow += o.Outflow
+	s.PoolInflow += o.PoolInflow
+	s.PoolOutflow += o.PoolOutflow
+	s.NetFlow += o.NetFlow
+	s.Volume += o.Volume
+	s.Trades += o.Trades
+	s.FundingPayments += o.FundingPayments
+	s.PnlRows += o.PnlRows
+}
+
+// PnLReport is a series of statements for one account
+type PnLReport struct {
+	AccountIndex int64        `json:"account_index"`
+	Period       ReportPeriod `json:"period"`
+	From         time.Time    `json:"from"`
+	To           time.Time    `json:"to"`
+	Statements   []Statement  `json:"statements"`
+	Total        Statement    `json:"total"`
+}
+
+// PnLInput holds the raw data a report is built from
+type PnLInput struct {
+	AccountIndex int64
+	Pnl          []lighterapi.PnLEntry
+	Trades       []lighterapi.Trade
+	Fundings     []lighterapi.PositionFunding
+	// Incremental marks Pnl rows as per-bucket amounts rather than running totals
+	Incremental bool
+}
+
+// BuildPnLReport splits [from, to) into periods and fills one statement per period.
+// Pnl rows are running totals by default, so a period's amount is the last value in the
+// period minus the last value before it; include at least one row before from.
+func BuildPnLReport(in PnLInput, period ReportPeriod, from, to time.Time) (*PnLReport, error) {
+	if !period.valid() {
+		return nil, fmt.Errorf("analytics: unknown report period %q", period)
+	}
+	if !to.After(from) {
+		return nil, errors.New("analytics: report range is empty")
+	}
+
+	report := &PnLReport{AccountIndex: in.AccountIndex, Period: period, From: from.UTC(), To: to.UTC()}
+	for start := period.periodStart(from); start.Before(to); start = period.next(start) {
+		report.Statements = append(report.Statements, Statement{Start: start, End: period.next(start)})
+	}
+	index := func(ts int64) int {
+		t := time.UnixMilli(client.UnixMillis(ts))
+		if t.Before(from) || !t.Before(to) {
+			return -1
+		}
+		return sort.Search(len(report.Statements), func(i int) bool {
+			return report.Statements[i].End.After(t)
+		})
+	}
+
+	applyPnl(report.Statements, in.Pnl, in.Incremental, from, index)
+
+	for _, trade := range in.Trades {
+		i := index(trade.Timestamp)
+		if i < 0 {
+			continue
+		}
+		s := &report.Statements[i]
+		maker, taker := accountFees(trade, in.AccountIndex)
+		s.MakerFees += maker
+		s.TakerFees += taker
+		s.Fees += maker + taker
+		s.Volume += parseFloat(trade.UsdAmount)
+		s.Trades++
+	}
+	for _, f := range in.Fundings {
+		i := index(f.Timestamp)
+		if i < 0 {
+			continue
+		}
+		report.Statements[i].Funding += parseFloat(f.Change)
+		report.Statements[i].FundingPayments++
+	}
+
+	for i := range report.Statements {
+		s := &report.Statements[i]
+		s.PricePnl = s.TradePnl - s.Funding + s.Fees
+		s.TotalPnl = s.TradePnl + s.PoolPnl
+		s.NetFlow = s.Inflow - s.Outflow
+		reconcile(s)
+		report.Total.add(*s)
+	}
+	if len(report.Statements) > 0 {
+		report.Total.Start = report.Statements[0].Start
+		report.Total.End = report.Statements[len(report.Statements)-1].End
+	}
+	return report, nil
+}
+
+// applyPnl distributes Pnl rows over the statements
+func applyPnl(statements []Statement, rows []lighterapi.PnLEntry, incremental bool, from time.Time, index func(int64) int) {
+	sorted := append([]lighterapi.PnLEntry(nil), rows...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+
+	var prev *lighterapi.PnLEntry
+	for k := range sorted {
+		row := sorted[k]
+		i := index(row.Timestamp)
+		if i < 0 {
+			if time.UnixMilli(client.UnixMillis(row.Timestamp)).Before(from) {
+				prev = &sorted[k]
+			}
+			continue
+		}
+		delta := row
+		if !incremental {
+			if prev == nil {
+				// No baseline before the range: the first row only anchors the running totals
+				prev = &sorted[k]
+				statements[i].PnlRows++
+				continue
+			}
+			delta = lighterapi.PnLEntry{
+				TradePnl:    row.TradePnl - prev.TradePnl,
+				PoolPnl:     row.PoolPnl - prev.PoolPnl,
+				Inflow:      row.Inflow - prev.Inflow,
+				Outflow:     row.Outflow - prev.Outflow,
+				PoolInflow:  row.PoolInflow - prev.PoolInflow,
+				PoolOutflow: row.PoolOutflow - prev.PoolOutflow,
+			}
+			prev = &sorted[k]
+		}
+		s := &statements[i]
+		s.TradePnl += delta.TradePnl
+		s.PoolPnl += delta.PoolPnl
+		s.Inflow += delta.Inflow
+		s.Outflow += delta.Outflow
+		s.PoolInflow += delta.PoolInflow
+		s.PoolOutflow += delta.PoolOutflow
+		s.PnlRows++
+	}
+}
+
+// reconcile flags statements whose Pnl rows disagree with the trade and funding history
+func reconcile(s *Statement) {
+	if s.PnlRows == 0 && (s.Trades > 0 || s.FundingPayments > 0) {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("no pnl rows for %d trades and %d funding payments", s.Trades, s.FundingPayments))
+	}
+	if s.PnlRows > 0 && s.Trades == 0 && s.FundingPayments == 0 && s.TradePnl != 0 {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("trade pnl %.6f without trades or funding", s.TradePnl))
+	}
+}
+
+// accountFees returns the maker and taker fees the account paid on a trade in USDC.
+// Trade fees are rates in units of 1/FeeTick of the USD amount.
+func accountFees(trade lighterapi.Trade, accountIndex int64) (maker, taker float64) {
+	notional := parseFloat(trade.UsdAmount)
+	makerAccount, takerAccount := trade.BidAccountId, trade.AskAccountId
+	if trade.IsMakerAsk {
+		makerAccount, takerAccount = trade.AskAccountId, trade.BidAccountId
+	}
+	if makerAccount == accountIndex {
+		maker = notional * float64(trade.MakerFee) / float64(txtypes.FeeTick)
+	}
+	if takerAccount == accountIndex {
+		taker = notional * float64(trade.TakerFee) / float64(txtypes.FeeTick)
+	}
+	return maker, taker
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *PnLReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+var statementHeader = []string{
+	"start", "end", "trade_pnl", "price_pnl", "funding", "fees", "maker_fees", "taker_fees",
+	"pool_pnl", "total_pnl", "inflow", "outflow", "pool_inflow", "pool_outflow", "net_flow",
+	"volume", "trades", "funding_payments", "pnl_rows", "warnings",
+}
+
+// WriteCSV writes one row per statement followed by a total row
+func (r *PnLReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(statementHeader); err != nil {
+		return err
+	}
+	rows := append(append([]Statement(nil), r.Statements...), r.Total)
+	for i, s := range rows {
+		start := s.Start.Format(time.DateOnly)
+		if i == len(rows)-1 {
+			start = "total"
+		}
+		warnings := ""
+		for j, w := range s.Warnings {
+			if j > 0 {
+				warnings += "; "
+			}
+			warnings += w
+		}
+		record := []string{
+			start, s.End.Format(time.DateOnly),
+			ftoa(s.TradePnl), ftoa(s.PricePnl), ftoa(s.Funding), ftoa(s.Fees), ftoa(s.MakerFees), ftoa(s.TakerFees),
+			ftoa(s.PoolPnl), ftoa(s.TotalPnl), ftoa(s.Inflow), ftoa(s.Outflow), ftoa(s.PoolInflow), ftoa(s.PoolOutflow),
+			ftoa(s.NetFlow), ftoa(s.Volume), strconv.Itoa(s.Trades), strconv.Itoa(s.FundingPayments),
+			strconv.Itoa(s.PnlRows), warnings,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// PnLReporter fetches Pnl, Trades and PositionFunding and builds reports
+type PnLReporter struct {
+	api *client.Client
+}
+
+// NewPnLReporter creates a reporter. Account endpoints are authenticated with the
+// client's AuthTokenFunc (see client.WithAuthTokenProvider).
+func NewPnLReporter(api *client.Client) (*PnLReporter, error) {
+	if api == nil {
+		return nil, errors.New("analytics: nil client")
+	}
+	return &PnLReporter{api: api}, nil
+}
+
+// Build fetches the account's history for [from, to) and builds a report
+func (r *PnLReporter) Build(ctx context.Context, accountIndex int64, period ReportPeriod, from, to time.Time) (*PnLReport, error) {
+	if !period.valid() {
+		return nil, fmt.Errorf("analytics: unknown report period %q", period)
+	}
+	// Start a day early so the running totals have a baseline
+	pnlFrom := period.periodStart(from).AddDate(0, 0, -1)
+	pnl, err := r.api.AccountPnl(ctx, accountIndex, lighterapi.N1d, pnlFrom, to)
+	if err != nil {
+		return nil, fmt.Errorf("analytics: pnl: %w", err)
+	}
+	trades, err := r.api.AccountTradeHistory(ctx, accountIndex, nil, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("analytics: trades: %w", err)
+	}
+	fundings, err := r.api.PositionFundingHistory(ctx, accountIndex, nil, from.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("analytics: position funding: %w", err)
+	}
+
+	return BuildPnLReport(PnLInput{
+		AccountIndex: accountIndex,
+		Pnl:          pnl,
+		Trades:       trades,
+		Fundings:     fundings,
+	}, period, from, to)
+}
+
+func parseFloat(value string) float64 {
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
+
+func ftoa(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
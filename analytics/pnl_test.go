@@ -0,0 +1,174 @@
+package analytics_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/defi-maker/golighter/analytics"
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const trader = lightertest.TraderAccount
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// day returns midnight UTC of a January 2026 day
+func day(d int) time.Time {
+	return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+// fakeClock lets a test move the time the fake exchange stamps trades with
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// newAuthExchange returns a fake exchange on clock and a client authenticating account endpoints
+func newAuthExchange(t *testing.T, clock *fakeClock) (*lightertest.Server, *client.Client, *client.TxClient) {
+	t.Helper()
+	srv, _, tx := lightertest.NewExchange(t, lightertest.WithClock(clock.Now))
+	api, err := srv.Client(client.WithAuthTokenProvider(func() (string, error) { return "token", nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, api, tx
+}
+
+// buy sends an IOC buy for the trader
+func buy(t *testing.T, tx *client.TxClient, clientIndex int64, price uint32, base int64) {
+	t.Helper()
+	info, err := tx.GetCreateOrderTransaction(&types.CreateOrderTxReq{
+		ClientOrderIndex: clientIndex,
+		BaseAmount:       base,
+		Price:            price,
+		Type:             txtypes.LimitOrder,
+		TimeInForce:      txtypes.ImmediateOrCancel,
+		OrderExpiry:      txtypes.NilOrderExpiry,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Send(context.Background(), info, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPnLReporterDecomposesTradePnl(t *testing.T) {
+	clock := &fakeClock{now: day(5).Add(12 * time.Hour)}
+	srv, api, tx := newAuthExchange(t, clock)
+
+	// Jan 5: the trader takes 0.1 at 3000 and pays 5 bps on 300
+	if _, err := srv.PlaceOrder(lightertest.MakerAccount, 0, true, 300000, 1000); err != nil {
+		t.Fatal(err)
+	}
+	buy(t, tx, 1, 300000, 1000)
+	// Jan 6: the trader's resting ask at 3010 is lifted and pays 2 bps on 301
+	clock.now = day(6).Add(12 * time.Hour)
+	if _, err := srv.PlaceOrder(trader, 0, true, 301000, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.PlaceOrder(lightertest.MakerAccount, 0, false, 301000, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pnl rows are running totals stamped in seconds, starting the day before the report
+	srv.HandleJSON("/api/v1/pnl", lighterapi.AccountPnL{Code: 200, Resolution: "1d", Pnl: []lighterapi.PnLEntry{
+		{Timestamp: day(4).Unix(), TradePnl: 10, Inflow: 100},
+		{Timestamp: day(5).Unix(), TradePnl: 9.5, Inflow: 100},
+		{Timestamp: day(6).Unix(), TradePnl: 10.8, Inflow: 150},
+	}})
+	srv.HandleJSON("/api/v1/positionFunding", lighterapi.PositionFundings{Code: 200, PositionFundings: []lighterapi.PositionFunding{
+		{FundingId: 3, Timestamp: day(6).Add(8 * time.Hour).Unix(), Change: "0.05"},
+		{FundingId: 2, Timestamp: day(5).Add(8 * time.Hour).Unix(), Change: "-0.20"},
+		{FundingId: 1, Timestamp: day(4).Add(8 * time.Hour).Unix(), Change: "-9"},
+	}})
+
+	reporter, err := analytics.NewPnLReporter(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := reporter.Build(context.Background(), trader, analytics.PeriodDaily, day(5), day(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Statements) != 2 {
+		t.Fatalf("%d statements, want 2", len(report.Statements))
+	}
+
+	first, second := report.Statements[0], report.Statements[1]
+	if !near(first.TradePnl, -0.5) || !near(first.Funding, -0.2) || !near(first.TakerFees, 0.15) || first.MakerFees != 0 || first.Trades != 1 || !near(first.Volume, 300) {
+		t.Fatalf("Jan 5 statement %+v", first)
+	}
+	// -0.5 of trade pnl is -0.2 of funding, -0.15 of fees and -0.15 of price moves
+	if !near(first.PricePnl, -0.15) {
+		t.Fatalf("Jan 5 price pnl %v, want -0.15", first.PricePnl)
+	}
+	if !near(second.TradePnl, 1.3) || !near(second.Funding, 0.05) || !near(second.MakerFees, 0.0602) || !near(second.PricePnl, 1.3102) || !near(second.NetFlow, 50) {
+		t.Fatalf("Jan 6 statement %+v", second)
+	}
+	if len(first.Warnings)+len(second.Warnings) != 0 {
+		t.Fatalf("unexpected warnings %v %v", first.Warnings, second.Warnings)
+	}
+	if total := report.Total; !near(total.TradePnl, 0.8) || !near(total.Fees, 0.2102) || total.FundingPayments != 2 || !total.End.Equal(day(7)) {
+		t.Fatalf("total %+v", total)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[1][0] != "2026-01-05" || rows[3][0] != "total" {
+		t.Fatalf("csv rows %v, want a header, 2 statements and a total", rows)
+	}
+	if v, err := strconv.ParseFloat(rows[3][2], 64); err != nil || !near(v, 0.8) {
+		t.Fatalf("csv rows %v, want a total trade pnl of 0.8", rows[3])
+	}
+}
+
+func TestBuildPnLReportPeriods(t *testing.T) {
+	in := analytics.PnLInput{
+		AccountIndex: trader,
+		Incremental:  true,
+		Pnl: []lighterapi.PnLEntry{
+			{Timestamp: day(2).UnixMilli(), TradePnl: 1},
+			{Timestamp: day(6).UnixMilli(), TradePnl: 2},
+		},
+		// A trade on a day without pnl rows is flagged
+		Trades: []lighterapi.Trade{{Timestamp: day(13).UnixMilli(), BidAccountId: trader, UsdAmount: "100"}},
+	}
+	// Jan 1 2026 is a Thursday; weeks start on Monday Dec 29 and Jan 5 and Jan 12
+	report, err := analytics.BuildPnLReport(in, analytics.PeriodWeekly, day(1), day(14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Statements) != 3 || !report.Statements[0].Start.Equal(day(1).AddDate(0, 0, -3)) {
+		t.Fatalf("statements %+v, want 3 weeks from Dec 29", report.Statements)
+	}
+	if report.Statements[0].TradePnl != 1 || report.Statements[1].TradePnl != 2 {
+		t.Fatalf("weekly trade pnl %v and %v, want 1 and 2", report.Statements[0].TradePnl, report.Statements[1].TradePnl)
+	}
+	if len(report.Statements[2].Warnings) != 1 {
+		t.Fatalf("warnings %v, want one for the trade without pnl rows", report.Statements[2].Warnings)
+	}
+
+	if _, err := analytics.BuildPnLReport(in, "yearly", day(1), day(14)); err == nil {
+		t.Fatal("expected an unknown period to be refused")
+	}
+	if _, err := analytics.BuildPnLReport(in, analytics.PeriodDaily, day(14), day(1)); err == nil {
+		t.Fatal("expected an empty range to be refused")
+	}
+}
@@ -0,0 +1,454 @@
+// Package backtest replays recorded market data through a strategy.Strategy. Orders are
+// matched by client.PaperTxClient on a simulated clock, with order entry latency, queue
+// position, maker and taker fees and funding payments, and the run reports fills, an equity
+// curve and performance statistics.
+package backtest
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/strategy"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const defaultEquityInterval = time.Minute
+
+// Config describes the simulated account and exchange
+type Config struct {
+	// Markets provides decimals and fees of every traded market
+	Markets      []*lighterapi.OrderBookDetail
+	AccountIndex int64
+	// Collateral is the starting USDC balance
+	Collateral float64
+	// Latency delays every transaction between the strategy sending it and the exchange
+	// executing it
+	Latency time.Duration
+	// TimerInterval is the period of OnTimer; 0 disables timers
+	TimerInterval time.Duration
+	// EquityInterval is the sampling period of the equity curve; defaults to one minute
+	EquityInterval time.Duration
+	// Data without book events is given a one-level synthetic book around each trade.
+	// HalfSpread is its distance from the trade price (default one price tick) and
+	// SyntheticDepth its base size per side (default the trade size).
+	HalfSpread     float64
+	SyntheticDepth float64
+	// OnError receives strategy hook errors and rejected transactions
+	OnError func(error)
+}
+
+// Fill is one execution of a strategy order
+type Fill struct {
+	At       time.Time
+	MarketId uint8
+	OrderId  int64
+	IsAsk    bool
+	Price    float64
+	Size     float64
+	Fee      float64
+	Maker    bool
+}
+
+// FundingPayment is a funding transfer on an open position; Amount is negative when paid
+type FundingPayment struct {
+	At       time.Time
+	MarketId uint8
+	Rate     float64
+	Position float64
+	Amount   float64
+}
+
+// EquityPoint is one sample of the equity curve
+type EquityPoint struct {
+	At     time.Time
+	Equity float64
+}
+
+// Reject is a transaction the simulated exchange refused
+type Reject struct {
+	At     time.Time
+	TxType uint8
+	Err    string
+}
+
+// Result is the outcome of a run
+type Result struct {
+	Fills   []Fill
+	Funding []FundingPayment
+	Rejects []Reject
+	Equity  []EquityPoint
+	Stats   Stats
+}
+
+// Backtester runs one strategy over recorded events
+type Backtester struct {
+	cfg      Config
+	strategy strategy.Strategy
+}
+
+// New validates the configuration
+func New(cfg Config, s strategy.Strategy) (*Backtester, error) {
+	if s == nil {
+		return nil, errors.New("backtest: strategy is required")
+	}
+	if len(cfg.Markets) == 0 {
+		return nil, errors.New("backtest: at least one market is required")
+	}
+	if cfg.EquityInterval <= 0 {
+		cfg.EquityInterval = defaultEquityInterval
+	}
+	return &Backtester{cfg: cfg, strategy: s}, nil
+}
+
+// pendingTx is a transaction waiting out the configured latency
+type pendingTx struct {
+	due  time.Time
+	info txtypes.TxInfo
+}
+
+// run holds the state of one Run call; everything happens on the calling goroutine
+type run struct {
+	cfg      Config
+	strategy strategy.Strategy
+	ctx      context.Context
+	now      time.Time
+
+	book    *client.OrderBookCache
+	paper   *client.PaperTxClient
+	sctx    *strategy.Context
+	markets map[uint8]*lighterapi.OrderBookDetail
+	marks   map[uint8]float64
+
+	pending    []pendingTx
+	nextTimer  time.Time
+	nextSample time.Time
+	synthetic  bool
+	errors     int
+	result     *Result
+}
+
+// Run replays events in time order and returns the result. It stops early when ctx is
+// canceled.
+func (b *Backtester) Run(ctx context.Context, events []Event) (*Result, error) {
+	if len(events) == 0 {
+		return nil, errors.New("backtest: no events")
+	}
+	events = append([]Event(nil), events...)
+	SortEvents(events)
+
+	r := &run{
+		cfg:        b.cfg,
+		strategy:   b.strategy,
+		ctx:        ctx,
+		now:        events[0].At,
+		book:       client.NewOrderBookCache(),
+		markets:    make(map[uint8]*lighterapi.OrderBookDetail),
+		marks:      make(map[uint8]float64),
+		synthetic:  true,
+		nextSample: events[0].At.Add(b.cfg.EquityInterval),
+		result:     &Result{},
+	}
+	if b.cfg.TimerInterval > 0 {
+		r.nextTimer = events[0].At.Add(b.cfg.TimerInterval)
+	}
+	for _, e := range events {
+		if e.Kind == BookEvent {
+			r.synthetic = false
+			break
+		}
+	}
+	if err := r.setup(); err != nil {
+		return nil, err
+	}
+	r.sample()
+
+	for _, e := range events {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r.advance(e.At)
+		if e.At.After(r.now) {
+			r.now = e.At
+		}
+		r.dispatch(e)
+		r.execute(r.now)
+	}
+	// Let in-flight transactions land before closing the books
+	if n := len(r.pending); n > 0 {
+		r.advance(r.pending[n-1].due)
+	}
+	r.sample()
+	r.result.Stats = computeStats(r.result, b.cfg.Collateral, b.cfg.EquityInterval, r.errors)
+	return r.result, nil
+}
+
+func (r *run) setup() error {
+	opts := []client.PaperOption{
+		client.WithPaperCollateral(r.cfg.Collateral),
+		client.WithPaperClock(func() time.Time { return r.now }),
+	}
+	for _, m := range r.cfg.Markets {
+		r.markets[m.MarketId] = m
+		opts = append(opts, client.WithPaperMarket(m))
+	}
+	paper, err := client.NewPaperTxClient(nil, r.book, r.cfg.AccountIndex, opts...)
+	if err != nil {
+		return err
+	}
+	r.paper = paper
+	r.sctx = strategy.NewContext(r.ctx, &simOrders{PaperTxClient: paper, run: r}, r.book, func() time.Time { return r.now })
+
+	paper.Start(r.ctx, r.report)
+	if _, err := paper.SubscribeOrders(client.LighterOrdersParamKey{AccountId: r.cfg.AccountIndex}, func(o client.LighterOrdersResponse) error {
+		r.sctx.TrackOrder(o)
+		return r.strategy.OnOrderUpdate(r.sctx, o)
+	}); err != nil {
+		return err
+	}
+	_, err = paper.SubscribeAccount(client.LighterAccountParamKey{AccountId: r.cfg.AccountIndex}, func(a client.LighterAccountResponse) error {
+		r.recordFills(a)
+		r.sctx.TrackAccount(a)
+		return r.strategy.OnAccount(r.sctx, a)
+	})
+	return err
+}
+
+// advance runs timers, equity samples and delayed transactions due up to t, in time order
+func (r *run) advance(t time.Time) {
+	for {
+		var (
+			next time.Time
+			kind int
+		)
+		consider := func(at time.Time, k int) {
+			if !at.IsZero() && !at.After(t) && (kind == 0 || at.Before(next)) {
+				next, kind = at, k
+			}
+		}
+		if len(r.pending) > 0 {
+			consider(r.pending[0].due, 1)
+		}
+		consider(r.nextTimer, 2)
+		consider(r.nextSample, 3)
+		if kind == 0 {
+			return
+		}
+		if next.After(r.now) {
+			r.now = next
+		}
+		switch kind {
+		case 1:
+			r.execute(next)
+		case 2:
+			r.nextTimer = r.nextTimer.Add(r.cfg.TimerInterval)
+			r.report(r.strategy.OnTimer(r.sctx, next))
+		case 3:
+			r.nextSample = r.nextSample.Add(r.cfg.EquityInterval)
+			r.sample()
+		}
+	}
+}
+
+// execute sends every pending transaction due by t to the paper exchange
+func (r *run) execute(t time.Time) {
+	for len(r.pending) > 0 && !r.pending[0].due.After(t) {
+		tx := r.pending[0]
+		r.pending = r.pending[1:]
+		if _, err := r.paper.Send(r.ctx, tx.info, nil); err != nil {
+			// Rejects are counted apart from errors but still reach OnError
+			r.result.Rejects = append(r.result.Rejects, Reject{At: r.now, TxType: tx.info.GetTxType(), Err: err.Error()})
+			if r.cfg.OnError != nil {
+				r.cfg.OnError(err)
+			}
+		}
+	}
+}
+
+func (r *run) dispatch(e Event) {
+	switch e.Kind {
+	case BookEvent:
+		r.report(r.paper.HandleOrderBook(*e.Book))
+		bid, okBid := r.book.BestBid(e.MarketId)
+		ask, okAsk := r.book.BestAsk(e.MarketId)
+		if okBid && okAsk {
+			r.marks[e.MarketId] = (bid.Price + ask.Price) / 2
+		}
+		r.report(r.strategy.OnBook(r.sctx, *e.Book))
+	case TradeEvent:
+		r.report(r.paper.HandleTrades(*e.Trade))
+		price, err := strconv.ParseFloat(e.Trade.Price, 64)
+		if err == nil {
+			r.marks[e.MarketId] = price
+		}
+		if r.synthetic && err == nil {
+			if book, ok := r.syntheticBook(*e.Trade, price); ok {
+				r.report(r.paper.HandleOrderBook(book))
+				r.report(r.strategy.OnBook(r.sctx, book))
+			}
+		}
+		r.report(r.strategy.OnTrade(r.sctx, *e.Trade))
+	case FundingEvent:
+		r.fund(e.MarketId, *e.Funding)
+	}
+}
+
+// syntheticBook places one level on each side of a trade
+func (r *run) syntheticBook(trade client.LighterTradesResponse, price float64) (client.LighterOrderBookResponse, bool) {
+	m := r.markets[trade.MarketId]
+	if m == nil {
+		return client.LighterOrderBookResponse{}, false
+	}
+	half := r.cfg.HalfSpread
+	if half <= 0 {
+		half = math.Pow10(-int(m.PriceDecimals))
+	}
+	depth := strconv.FormatFloat(r.cfg.SyntheticDepth, 'f', int(m.SizeDecimals), 64)
+	if r.cfg.SyntheticDepth <= 0 {
+		depth = trade.Quantity
+	}
+	format := func(p float64) string { return strconv.FormatFloat(p, 'f', int(m.PriceDecimals), 64) }
+	return client.LighterOrderBookResponse{
+		MarketId:   trade.MarketId,
+		Bids:       []client.PriceLevel{{Price: format(price - half), Quantity: depth}},
+		Asks:       []client.PriceLevel{{Price: format(price + half), Quantity: depth}},
+		Timestamp:  trade.Timestamp,
+		IsSnapshot: true,
+	}, true
+}
+
+// fund settles a funding payment on the open position. Rates are fractions of position
+// notional; a direction of "short" means shorts pay longs. Payments settle in the paper
+// account collateral, so the strategy sees them in its balance.
+func (r *run) fund(marketId uint8, f lighterapi.Funding) {
+	size, _ := r.paper.Position(marketId)
+	mark := r.marks[marketId]
+	if size == 0 || mark == 0 {
+		return
+	}
+	rate, err := strconv.ParseFloat(f.Rate, 64)
+	if err != nil {
+		r.report(fmt.Errorf("backtest: funding rate %q: %w", f.Rate, err))
+		return
+	}
+	if f.Direction == "short" {
+		rate = -rate
+	}
+	amount := -size * mark * rate
+	r.paper.AdjustCollateral(amount)
+	r.result.Funding = append(r.result.Funding, FundingPayment{
+		At:       r.now,
+		MarketId: marketId,
+		Rate:     rate,
+		Position: size,
+		Amount:   amount,
+	})
+}
+
+// recordFills collects the strategy's executions from a paper account event
+func (r *run) recordFills(a client.LighterAccountResponse) {
+	if a.RawAccountUpdate == nil {
+		return
+	}
+	for _, trades := range a.RawAccountUpdate.Trades {
+		for _, t := range trades {
+			// Paper trades only fill in the account's own side, so account 0 is not
+			// mistaken for the empty counterparty
+			isAsk := t.AskId != 0 && t.AskAccountId == r.cfg.AccountIndex
+			maker := t.IsMakerAsk == isAsk
+			price, _ := strconv.ParseFloat(t.Price, 64)
+			size, _ := strconv.ParseFloat(t.Size, 64)
+			rate := float64(t.TakerFee) / float64(txtypes.FeeTick)
+			if maker {
+				rate = float64(t.MakerFee) / float64(txtypes.FeeTick)
+			}
+			orderId := t.BidId
+			if isAsk {
+				orderId = t.AskId
+			}
+			r.result.Fills = append(r.result.Fills, Fill{
+				At:       time.UnixMilli(t.Timestamp).UTC(),
+				MarketId: uint8(t.MarketId),
+				OrderId:  orderId,
+				IsAsk:    isAsk,
+				Price:    price,
+				Size:     size,
+				Fee:      price * size * rate,
+				Maker:    maker,
+			})
+		}
+	}
+}
+
+// equity marks open positions at the latest mid or trade price
+func (r *run) equity() float64 {
+	equity := r.paper.Collateral()
+	for marketId := range r.markets {
+		size, entry := r.paper.Position(marketId)
+		if mark, ok := r.marks[marketId]; ok && size != 0 {
+			equity += size * (mark - entry)
+		}
+	}
+	return equity
+}
+
+func (r *run) sample() {
+	point := EquityPoint{At: r.now, Equity: r.equity()}
+	if n := len(r.result.Equity); n > 0 && r.result.Equity[n-1].At.Equal(point.At) {
+		r.result.Equity[n-1] = point
+		return
+	}
+	r.result.Equity = append(r.result.Equity, point)
+}
+
+func (r *run) report(err error) {
+	if err == nil {
+		return
+	}
+	r.errors++
+	if r.cfg.OnError != nil {
+		r.cfg.OnError(err)
+	}
+}
+
+// simOrders is the strategy's order client: it builds transactions with the paper client and
+// holds them for the configured latency before the paper exchange executes them
+type simOrders struct {
+	*client.PaperTxClient
+	run *run
+}
+
+func (s *simOrders) Send(_ context.Context, info txtypes.TxInfo, _ *bool) (*lighterapi.RespSendTx, error) {
+	hash, err := info.Hash(0)
+	if err != nil {
+		return nil, err
+	}
+	s.run.pending = append(s.run.pending, pendingTx{due: s.run.now.Add(s.run.cfg.Latency), info: info})
+	return &lighterapi.RespSendTx{Code: 200, TxHash: hex.EncodeToString(hash)}, nil
+}
+
+func (s *simOrders) SendRawTx(ctx context.Context, info txtypes.TxInfo, priceProtection *bool) (string, error) {
+	resp, err := s.Send(ctx, info, priceProtection)
+	if err != nil {
+		return "", err
+	}
+	return resp.TxHash, nil
+}
+
+func (s *simOrders) SendBatch(ctx context.Context, infos []txtypes.TxInfo) (*lighterapi.RespSendTxBatch, error) {
+	resp := &lighterapi.RespSendTxBatch{Code: 200}
+	for _, info := range infos {
+		hash, err := s.SendRawTx(ctx, info, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp.TxHash = append(resp.TxHash, hash)
+	}
+	return resp, nil
+}
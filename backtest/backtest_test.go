@@ -0,0 +1,133 @@
+package backtest_test
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/backtest"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/strategy"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+var eth = &lighterapi.OrderBookDetail{MarketId: 0, Symbol: "ETH", PriceDecimals: 2, SizeDecimals: 4, MinBaseAmount: "0.001", MakerFee: "0.02", TakerFee: "0.05"}
+
+// buyer sends one buy of 1 ETH at 3001.00 on the first book and keeps the balances it is shown
+type buyer struct {
+	strategy.Base
+	placed   bool
+	balances []string
+}
+
+func (b *buyer) OnBook(c *strategy.Context, _ client.LighterOrderBookResponse) error {
+	if b.placed {
+		return nil
+	}
+	b.placed = true
+	_, err := c.Place(types.CreateOrderTxReq{
+		MarketIndex:      0,
+		ClientOrderIndex: 1,
+		BaseAmount:       10000,
+		Price:            300100,
+		Type:             txtypes.LimitOrder,
+		TimeInForce:      txtypes.GoodTillTime,
+	})
+	return err
+}
+
+func (b *buyer) OnAccount(_ *strategy.Context, a client.LighterAccountResponse) error {
+	b.balances = append(b.balances, a.AvailableBalance)
+	return nil
+}
+
+func book(at time.Time, bid, ask string) backtest.Event {
+	return backtest.Event{At: at, Kind: backtest.BookEvent, Book: &client.LighterOrderBookResponse{
+		Bids:       []client.PriceLevel{{Price: bid, Quantity: "5.0000"}},
+		Asks:       []client.PriceLevel{{Price: ask, Quantity: "5.0000"}},
+		Timestamp:  at.UnixMilli(),
+		IsSnapshot: true,
+	}}
+}
+
+func TestBacktestLatencyFillsAndFunding(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sold := start.Add(200 * time.Millisecond)
+	events := []backtest.Event{
+		book(start, "3000.00", "3001.00"),
+		// The ask lifts away before a delayed order arrives
+		book(start.Add(50*time.Millisecond), "3000.50", "3001.50"),
+		{At: sold, Kind: backtest.TradeEvent, Trade: &client.LighterTradesResponse{Price: "3000.90", Quantity: "2.0000", Side: "sell", Timestamp: sold.UnixMilli()}},
+		book(start.Add(30*time.Minute), "3001.00", "3003.00"),
+		{At: start.Add(time.Hour), Kind: backtest.FundingEvent, Funding: &lighterapi.Funding{Direction: "long", Rate: "0.0001", Timestamp: start.Add(time.Hour).Unix()}},
+	}
+
+	for _, tt := range []struct {
+		name    string
+		latency time.Duration
+		at      time.Time
+		maker   bool
+		fee     float64
+	}{
+		{"immediate", 0, start, false, 3001 * 0.0005},
+		// Arriving after the ask moved, the order rests and the sell fills it
+		{"delayed", 100 * time.Millisecond, sold, true, 3001 * 0.0002},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &buyer{}
+			bt, err := backtest.New(backtest.Config{
+				Markets:        []*lighterapi.OrderBookDetail{eth},
+				AccountIndex:   1,
+				Collateral:     10000,
+				Latency:        tt.latency,
+				EquityInterval: time.Hour,
+				OnError:        func(err error) { t.Error(err) },
+			}, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := bt.Run(context.Background(), events)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(res.Fills) != 1 {
+				t.Fatalf("%d fills, want 1", len(res.Fills))
+			}
+			f := res.Fills[0]
+			if !f.At.Equal(tt.at) || f.IsAsk || f.Maker != tt.maker || f.Price != 3001 || f.Size != 1 || !near(f.Fee, tt.fee) {
+				t.Fatalf("fill %+v, want a buy of 1 at 3001 at %v, maker %v, for a fee of %v", f, tt.at, tt.maker, tt.fee)
+			}
+
+			// Longs pay one basis point of the position marked at the 3002 mid
+			funding := -3002 * 0.0001
+			if len(res.Funding) != 1 || !near(res.Funding[0].Amount, funding) || res.Funding[0].Position != 1 {
+				t.Fatalf("funding %+v, want a payment of %v on 1 ETH", res.Funding, -funding)
+			}
+			balance := 10000 - tt.fee + funding
+			if n := len(s.balances); n == 0 || s.balances[n-1] != strconv.FormatFloat(balance, 'f', 6, 64) {
+				t.Fatalf("strategy saw balances %v, want the last to be %.6f after funding", s.balances, balance)
+			}
+
+			st := res.Stats
+			equity := balance + (3002 - 3001)
+			if st.Fills != 1 || !near(st.Fees, tt.fee) || !near(st.Funding, funding) || !near(st.Volume, 3001) || st.Rejects != 0 || st.Errors != 0 {
+				t.Fatalf("stats %+v", st)
+			}
+			if !near(st.FinalEquity, equity) || !near(st.PnL, equity-10000) || !near(st.Return, (equity-10000)/10000) {
+				t.Fatalf("final equity %v and PnL %v, want %v counting funding once", st.FinalEquity, st.PnL, equity)
+			}
+			if n := len(res.Equity); n != 2 || !res.Equity[n-1].At.Equal(start.Add(time.Hour)) {
+				t.Fatalf("equity curve %+v, want hourly samples up to the funding", res.Equity)
+			}
+		})
+	}
+}
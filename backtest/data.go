@@ -0,0 +1,292 @@
+package backtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/history"
+)
+
+// EventKind tells which payload of an Event is set
+type EventKind int
+
+const (
+	BookEvent EventKind = iota
+	TradeEvent
+	FundingEvent
+)
+
+// Event is one timestamped input of a backtest
+type Event struct {
+	At       time.Time
+	Kind     EventKind
+	MarketId uint8
+	Book     *client.LighterOrderBookResponse
+	Trade    *client.LighterTradesResponse
+	Funding  *lighterapi.Funding
+}
+
+// SortEvents orders events by time, keeping the input order of simultaneous events
+func SortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+}
+
+// TradeEvents converts REST trades, as returned by Trades or written by history, into events
+func TradeEvents(trades []lighterapi.Trade) []Event {
+	events := make([]Event, 0, len(trades))
+	for _, t := range trades {
+		side := "buy"
+		if !t.IsMakerAsk {
+			side = "sell"
+		}
+		ts := client.UnixMillis(t.Timestamp)
+		events = append(events, Event{
+			At:       time.UnixMilli(ts).UTC(),
+			Kind:     TradeEvent,
+			MarketId: t.MarketId,
+			Trade: &client.LighterTradesResponse{
+				MarketId:  t.MarketId,
+				TradeId:   t.TradeId,
+				Price:     t.Price,
+				Quantity:  t.Size,
+				Side:      side,
+				Timestamp: ts,
+			},
+		})
+	}
+	return events
+}
+
+// CandleEvents expands each candle into four trades of a quarter of its base volume, walking
+// open, low, high, close for up bars and open, high, low, close for down bars, spread evenly
+// over the bar
+func CandleEvents(marketId uint8, candles []lighterapi.Candlestick, resolution time.Duration) []Event {
+	events := make([]Event, 0, 4*len(candles))
+	for _, c := range candles {
+		start := time.UnixMilli(client.UnixMillis(c.Timestamp)).UTC()
+		path := []float64{c.Open, c.Low, c.High, c.Close}
+		if c.Close < c.Open {
+			path = []float64{c.Open, c.High, c.Low, c.Close}
+		}
+		size := strconv.FormatFloat(c.Volume0/4, 'f', -1, 64)
+		for i, price := range path {
+			at := start.Add(resolution * time.Duration(i) / 4)
+			side := "buy"
+			if i > 0 && price < path[i-1] {
+				side = "sell"
+			}
+			events = append(events, Event{
+				At:       at,
+				Kind:     TradeEvent,
+				MarketId: marketId,
+				Trade: &client.LighterTradesResponse{
+					MarketId:  marketId,
+					Price:     strconv.FormatFloat(price, 'f', -1, 64),
+					Quantity:  size,
+					Side:      side,
+					Timestamp: at.UnixMilli(),
+				},
+			})
+		}
+	}
+	return events
+}
+
+// FundingEvents converts the funding history of a market, as returned by Fundings, into events
+func FundingEvents(marketId uint8, fundings []lighterapi.Funding) []Event {
+	events := make([]Event, 0, len(fundings))
+	for i := range fundings {
+		f := fundings[i]
+		events = append(events, Event{
+			At:       time.UnixMilli(client.UnixMillis(f.Timestamp)).UTC(),
+			Kind:     FundingEvent,
+			MarketId: marketId,
+			Funding:  &f,
+		})
+	}
+	return events
+}
+
+// wsFrame covers the order book and trade messages of a recorded stream
+type wsFrame struct {
+	Type      string `json:"type"`
+	Channel   string `json:"channel"`
+	OrderBook struct {
+		Asks []client.WSPriceLevel `json:"asks"`
+		Bids []client.WSPriceLevel `json:"bids"`
+	} `json:"order_book"`
+	Trades    []client.WSTrade `json:"trades"`
+	Timestamp int64            `json:"timestamp"`
+}
+
+// RecordingEvents reads order book and trade messages from a recorded WS session. Events are
+// stamped with the time each frame was received.
+func RecordingEvents(r *client.WSRecordingReader) ([]Event, error) {
+	var events []Event
+	for {
+		frame, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var msg wsFrame
+		if err := json.Unmarshal([]byte(frame.Data), &msg); err != nil {
+			continue
+		}
+		marketId, ok := channelMarket(msg.Channel)
+		if !ok {
+			continue
+		}
+		switch msg.Type {
+		case client.MessageTypeOrderBookSubscribed, client.MessageTypeOrderBookUpdate:
+			events = append(events, Event{
+				At:       frame.At,
+				Kind:     BookEvent,
+				MarketId: marketId,
+				Book: &client.LighterOrderBookResponse{
+					MarketId:   marketId,
+					Bids:       priceLevels(msg.OrderBook.Bids),
+					Asks:       priceLevels(msg.OrderBook.Asks),
+					Timestamp:  msg.Timestamp,
+					IsSnapshot: msg.Type == client.MessageTypeOrderBookSubscribed,
+				},
+			})
+		case client.MessageTypeTradeUpdate:
+			for i := range msg.Trades {
+				t := msg.Trades[i]
+				side := "buy"
+				if !t.IsMakerAsk {
+					side = "sell"
+				}
+				events = append(events, Event{
+					At:       frame.At,
+					Kind:     TradeEvent,
+					MarketId: marketId,
+					Trade: &client.LighterTradesResponse{
+						MarketId:  marketId,
+						TradeId:   t.TradeId,
+						Price:     t.Price,
+						Quantity:  t.Size,
+						Side:      side,
+						Timestamp: t.Timestamp,
+						RawTrade:  &t,
+					},
+				})
+			}
+		}
+	}
+}
+
+// LoadRecording reads events from a recording file written by WSRecorder
+func LoadRecording(path string) ([]Event, error) {
+	r, err := client.OpenWSRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return RecordingEvents(r)
+}
+
+// LoadHistory reads the trades, candles and fundings a history.Downloader wrote to dir.
+// Candles are only used when the directory holds no trades, since they cover the same flow.
+func LoadHistory(dir string) ([]Event, error) {
+	manifest, err := history.LoadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string][]string)
+	for _, entry := range manifest.Files {
+		paths[entry.Dataset] = append(paths[entry.Dataset], filepath.Join(dir, entry.Path+".jsonl"))
+	}
+	for _, p := range paths {
+		sort.Strings(p)
+	}
+
+	trades, err := readAll[lighterapi.Trade](paths[history.DatasetTrades])
+	if err != nil {
+		return nil, err
+	}
+	fundings, err := readAll[lighterapi.Funding](paths[history.DatasetFundings])
+	if err != nil {
+		return nil, err
+	}
+	events := append(TradeEvents(trades), FundingEvents(manifest.MarketId, fundings)...)
+	if len(trades) == 0 {
+		candles, err := readAll[lighterapi.Candlestick](paths[history.DatasetCandles])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, CandleEvents(manifest.MarketId, candles, candleResolution(candles))...)
+	}
+	SortEvents(events)
+	return events, nil
+}
+
+func readAll[T any](paths []string) ([]T, error) {
+	var records []T
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("backtest: %w", err)
+		}
+		dec := json.NewDecoder(bufio.NewReader(f))
+		for dec.More() {
+			var r T
+			if err := dec.Decode(&r); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("backtest: read %s: %w", path, err)
+			}
+			records = append(records, r)
+		}
+		f.Close()
+	}
+	return records, nil
+}
+
+// candleResolution infers the bar width from the smallest gap between candles
+func candleResolution(candles []lighterapi.Candlestick) time.Duration {
+	var best int64
+	for i := 1; i < len(candles); i++ {
+		gap := client.UnixMillis(candles[i].Timestamp) - client.UnixMillis(candles[i-1].Timestamp)
+		if gap > 0 && (best == 0 || gap < best) {
+			best = gap
+		}
+	}
+	if best == 0 {
+		return time.Minute
+	}
+	return time.Duration(best) * time.Millisecond
+}
+
+func channelMarket(channel string) (uint8, bool) {
+	i := strings.LastIndexAny(channel, ":/")
+	if i < 0 {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(channel[i+1:], 10, 8)
+	if err != nil {
+		return 0, false
+	}
+	return uint8(id), true
+}
+
+func priceLevels(levels []client.WSPriceLevel) []client.PriceLevel {
+	out := make([]client.PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		out = append(out, client.PriceLevel{Price: l.Price, Quantity: l.Size})
+	}
+	return out
+}
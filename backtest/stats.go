@@ -0,0 +1,91 @@
+package backtest
+
+import (
+	"math"
+	"time"
+)
+
+const daysPerYear = 365
+
+// Stats summarizes a run
+type Stats struct {
+	StartEquity float64
+	FinalEquity float64
+	PnL         float64
+	// Return is PnL relative to the starting collateral
+	Return float64
+	Fees   float64
+	// Funding is the net funding received; negative when paid
+	Funding    float64
+	Volume     float64
+	Fills      int
+	MakerFills int
+	// Rejects counts rejected transactions and Errors every other error reported
+	Rejects     int
+	Errors      int
+	MaxDrawdown float64
+	// Sharpe is annualized from the returns between equity samples
+	Sharpe float64
+}
+
+func computeStats(res *Result, collateral float64, interval time.Duration, errors int) Stats {
+	stats := Stats{StartEquity: collateral, Rejects: len(res.Rejects), Errors: errors}
+	if n := len(res.Equity); n > 0 {
+		stats.FinalEquity = res.Equity[n-1].Equity
+	}
+	stats.PnL = stats.FinalEquity - stats.StartEquity
+	if collateral > 0 {
+		stats.Return = stats.PnL / collateral
+	}
+	for _, f := range res.Fills {
+		stats.Fills++
+		if f.Maker {
+			stats.MakerFills++
+		}
+		stats.Fees += f.Fee
+		stats.Volume += f.Price * f.Size
+	}
+	for _, p := range res.Funding {
+		stats.Funding += p.Amount
+	}
+
+	peak := math.Inf(-1)
+	for _, p := range res.Equity {
+		peak = math.Max(peak, p.Equity)
+		if peak > 0 {
+			stats.MaxDrawdown = math.Max(stats.MaxDrawdown, (peak-p.Equity)/peak)
+		}
+	}
+	stats.Sharpe = sharpe(res.Equity, interval)
+	return stats
+}
+
+// sharpe annualizes the mean over the deviation of per-sample returns; it is 0 when equity is
+// not positive throughout or returns do not vary
+func sharpe(curve []EquityPoint, interval time.Duration) float64 {
+	if len(curve) < 3 || interval <= 0 {
+		return 0
+	}
+	returns := make([]float64, 0, len(curve)-1)
+	for i := 1; i < len(curve); i++ {
+		if curve[i-1].Equity <= 0 {
+			return 0
+		}
+		returns = append(returns, curve[i].Equity/curve[i-1].Equity-1)
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+	if std == 0 {
+		return 0
+	}
+	periods := float64(daysPerYear*24*time.Hour) / float64(interval)
+	return mean / std * math.Sqrt(periods)
+}
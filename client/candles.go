@@ -0,0 +1,352 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+)
+
+const (
+	candleChunkSize         = 500
+	defaultCandleMaxHistory = 10000
+)
+
+var candleResolutions = []struct {
+	duration   time.Duration
+	resolution lighterapi.CandlesticksParamsResolution
+}{
+	{24 * time.Hour, lighterapi.CandlesticksParamsResolutionN1d},
+	{4 * time.Hour, lighterapi.CandlesticksParamsResolutionN4h},
+	{time.Hour, lighterapi.CandlesticksParamsResolutionN1h},
+	{15 * time.Minute, lighterapi.CandlesticksParamsResolutionN15m},
+	{5 * time.Minute, lighterapi.CandlesticksParamsResolutionN5m},
+	{time.Minute, lighterapi.CandlesticksParamsResolutionN1m},
+}
+
+// Candle is an OHLCV bar covering [Start, End) in milliseconds
+type Candle struct {
+	MarketId    uint8   `json:"market_id"`
+	Start       int64   `json:"start"`
+	End         int64   `json:"end"`
+	Open        float64 `json:"open"`
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	Close       float64 `json:"close"`
+	Volume      float64 `json:"volume"`
+	QuoteVolume float64 `json:"quote_volume"`
+	Trades      int     `json:"trades"`
+	LastTradeId int64   `json:"last_trade_id"`
+}
+
+// CandleBuilder builds candles of arbitrary resolution. History is backfilled from
+// Candlesticks and extended live by passing HandleTrade to SubscribeTrades.
+// Bars are finalized on time boundaries by Run, even when no trade arrives.
+type CandleBuilder struct {
+	api        *Client
+	marketId   uint8
+	resolution time.Duration
+
+	mu          sync.Mutex
+	maxHistory  int
+	closed      []Candle
+	current     *Candle
+	lastTradeId int64
+	onClose     []func(Candle)
+}
+
+// NewCandleBuilder creates a builder for a market. The resolution must be a whole number of milliseconds.
+func NewCandleBuilder(api *Client, marketId uint8, resolution time.Duration) (*CandleBuilder, error) {
+	if resolution < time.Millisecond || resolution%time.Millisecond != 0 {
+		return nil, fmt.Errorf("client: invalid candle resolution %s", resolution)
+	}
+	return &CandleBuilder{
+		api:        api,
+		marketId:   marketId,
+		resolution: resolution,
+		maxHistory: defaultCandleMaxHistory,
+	}, nil
+}
+
+// SetMaxHistory bounds the number of closed candles kept in memory
+func (b *CandleBuilder) SetMaxHistory(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.maxHistory = n
+	b.trim()
+}
+
+// OnClose registers a callback invoked with every candle finalized live.
+// Backfilled candles do not trigger callbacks.
+func (b *CandleBuilder) OnClose(fn func(Candle)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onClose = append(b.onClose, fn)
+}
+
+// Candles returns the closed candles, oldest first
+func (b *CandleBuilder) Candles() []Candle {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]Candle, len(b.closed))
+	copy(out, b.closed)
+	return out
+}
+
+// Current returns the candle still in progress
+func (b *CandleBuilder) Current() (Candle, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.current == nil {
+		return Candle{}, false
+	}
+	return *b.current, true
+}
+
+// Backfill loads history between from and to from the Candlesticks endpoint, using the
+// coarsest supported resolution that evenly divides the builder resolution. Resolutions
+// below one minute, or not a multiple of it, cannot be backfilled.
+func (b *CandleBuilder) Backfill(ctx context.Context, from, to time.Time) error {
+	source, resolution, ok := b.sourceResolution()
+	if !ok {
+		return fmt.Errorf("client: resolution %s cannot be backfilled from candlesticks", b.resolution)
+	}
+
+	step := source.Milliseconds()
+	start := floorTo(from.UnixMilli(), b.resolution.Milliseconds())
+	end := to.UnixMilli()
+
+	byStart := make(map[int64]lighterapi.Candlestick)
+	for chunkStart := start; chunkStart < end; chunkStart += step * candleChunkSize {
+		chunkEnd := chunkStart + step*candleChunkSize
+		if chunkEnd > end {
+			chunkEnd = end
+		}
+		resp, err := b.api.Candlesticks(ctx, &lighterapi.CandlesticksParams{
+			MarketId:       b.marketId,
+			Resolution:     resolution,
+			StartTimestamp: chunkStart,
+			EndTimestamp:   chunkEnd,
+			CountBack:      candleChunkSize,
+		})
+		if err != nil {
+			return fmt.Errorf("client: backfill candles: %w", err)
+		}
+		for _, c := range resp.Candlesticks {
+			if c.Timestamp >= start && c.Timestamp < end {
+				byStart[c.Timestamp] = c
+			}
+		}
+	}
+
+	sources := make([]lighterapi.Candlestick, 0, len(byStart))
+	for _, c := range byStart {
+		sources = append(sources, c)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Timestamp < sources[j].Timestamp })
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := b.resolution.Milliseconds()
+	var bars []Candle
+	for _, c := range sources {
+		bucket := floorTo(c.Timestamp, res)
+		if len(bars) == 0 || bars[len(bars)-1].Start != bucket {
+			bars = append(bars, Candle{
+				MarketId: b.marketId,
+				Start:    bucket,
+				End:      bucket + res,
+				Open:     c.Open,
+				High:     c.High,
+				Low:      c.Low,
+			})
+		}
+		bar := &bars[len(bars)-1]
+		bar.High = math.Max(bar.High, c.High)
+		bar.Low = math.Min(bar.Low, c.Low)
+		bar.Close = c.Close
+		bar.Volume += c.Volume0
+		bar.QuoteVolume += c.Volume1
+		if c.LastTradeId > bar.LastTradeId {
+			bar.LastTradeId = c.LastTradeId
+		}
+		if c.LastTradeId > b.lastTradeId {
+			b.lastTradeId = c.LastTradeId
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	if n := len(bars); n > 0 && bars[n-1].End > now {
+		last := bars[n-1]
+		b.current = &last
+		bars = bars[:n-1]
+	}
+	b.closed = mergeCandles(b.closed, bars)
+	b.trim()
+	return nil
+}
+
+func (b *CandleBuilder) sourceResolution() (time.Duration, lighterapi.CandlesticksParamsResolution, bool) {
+	for _, r := range candleResolutions {
+		if b.resolution >= r.duration && b.resolution%r.duration == 0 {
+			return r.duration, r.resolution, true
+		}
+	}
+	return 0, "", false
+}
+
+// HandleTrade adds a live trade to the current candle. Trades already covered by the
+// backfill (by trade id) and trades older than the current candle are ignored.
+func (b *CandleBuilder) HandleTrade(resp LighterTradesResponse) error {
+	if resp.MarketId != b.marketId {
+		return nil
+	}
+	price := parseDecimal(resp.Price)
+	size := parseDecimal(resp.Quantity)
+	if price <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	if resp.TradeId != 0 && resp.TradeId <= b.lastTradeId {
+		b.mu.Unlock()
+		return nil
+	}
+	closed := b.advance(resp.Timestamp)
+	if b.current != nil && resp.Timestamp < b.current.Start {
+		b.mu.Unlock()
+		b.emit(closed)
+		return nil
+	}
+
+	if b.current == nil {
+		bucket := floorTo(resp.Timestamp, b.resolution.Milliseconds())
+		b.current = &Candle{
+			MarketId: b.marketId,
+			Start:    bucket,
+			End:      bucket + b.resolution.Milliseconds(),
+			Open:     price,
+			High:     price,
+			Low:      price,
+		}
+	}
+	c := b.current
+	c.High = math.Max(c.High, price)
+	c.Low = math.Min(c.Low, price)
+	c.Close = price
+	c.Volume += size
+	c.QuoteVolume += size * price
+	c.Trades++
+	if resp.TradeId != 0 {
+		c.LastTradeId = resp.TradeId
+		b.lastTradeId = resp.TradeId
+	}
+	b.mu.Unlock()
+
+	b.emit(closed)
+	return nil
+}
+
+// Run finalizes candles on every resolution boundary until ctx is cancelled
+func (b *CandleBuilder) Run(ctx context.Context) {
+	for {
+		res := b.resolution.Milliseconds()
+		now := time.Now().UnixMilli()
+		next := floorTo(now, res) + res
+		timer := time.NewTimer(time.Duration(next-now) * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			b.Flush(time.UnixMilli(next))
+		}
+	}
+}
+
+// Flush finalizes every candle that ends at or before t
+func (b *CandleBuilder) Flush(t time.Time) {
+	b.mu.Lock()
+	closed := b.advance(t.UnixMilli())
+	b.mu.Unlock()
+	b.emit(closed)
+}
+
+// advance closes the current candle and fills empty periods with flat candles up to ts.
+// Callers must hold b.mu.
+func (b *CandleBuilder) advance(ts int64) []Candle {
+	if b.current == nil && len(b.closed) > 0 {
+		last := b.closed[len(b.closed)-1]
+		b.current = flatCandle(last, b.resolution.Milliseconds())
+	}
+
+	var closed []Candle
+	for b.current != nil && b.current.End <= ts {
+		done := *b.current
+		closed = append(closed, done)
+		b.closed = append(b.closed, done)
+		b.current = flatCandle(done, b.resolution.Milliseconds())
+	}
+	b.trim()
+	return closed
+}
+
+// flatCandle opens the period following prev at its close price
+func flatCandle(prev Candle, res int64) *Candle {
+	return &Candle{
+		MarketId:    prev.MarketId,
+		Start:       prev.End,
+		End:         prev.End + res,
+		Open:        prev.Close,
+		High:        prev.Close,
+		Low:         prev.Close,
+		Close:       prev.Close,
+		LastTradeId: prev.LastTradeId,
+	}
+}
+
+func (b *CandleBuilder) emit(closed []Candle) {
+	if len(closed) == 0 {
+		return
+	}
+	b.mu.Lock()
+	callbacks := append([]func(Candle){}, b.onClose...)
+	b.mu.Unlock()
+
+	for _, c := range closed {
+		for _, fn := range callbacks {
+			fn(c)
+		}
+	}
+}
+
+func (b *CandleBuilder) trim() {
+	if b.maxHistory > 0 && len(b.closed) > b.maxHistory {
+		b.closed = append([]Candle(nil), b.closed[len(b.closed)-b.maxHistory:]...)
+	}
+}
+
+func mergeCandles(existing, incoming []Candle) []Candle {
+	byStart := make(map[int64]Candle, len(existing)+len(incoming))
+	for _, c := range existing {
+		byStart[c.Start] = c
+	}
+	for _, c := range incoming {
+		byStart[c.Start] = c
+	}
+	merged := make([]Candle, 0, len(byStart))
+	for _, c := range byStart {
+		merged = append(merged, c)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Start < merged[j].Start })
+	return merged
+}
+
+func floorTo(ts, step int64) int64 {
+	return ts - ((ts%step)+step)%step
+}
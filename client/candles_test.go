@@ -0,0 +1,119 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+)
+
+// serveMinuteCandles answers candlestick requests with nine one-minute candles from t0.
+// Candle i opens at 3000+i, trades 1 ETH between 2999+i and 3002+i, and closes at 3001+i.
+func serveMinuteCandles(t *testing.T, srv *lightertest.Server, t0 time.Time) {
+	srv.Handle("/api/v1/candlesticks", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if res := q.Get("resolution"); res != string(lighterapi.CandlesticksParamsResolutionN1m) {
+			t.Errorf("requested %s candles, want 1m", res)
+		}
+		start, _ := strconv.ParseInt(q.Get("start_timestamp"), 10, 64)
+		end, _ := strconv.ParseInt(q.Get("end_timestamp"), 10, 64)
+		resp := lighterapi.Candlesticks{Code: 200, Resolution: "1m"}
+		for i := 0; i < 9; i++ {
+			ts := t0.Add(time.Duration(i) * time.Minute).UnixMilli()
+			if ts < start || ts >= end {
+				continue
+			}
+			p := 3000 + float64(i)
+			resp.Candlesticks = append(resp.Candlesticks, lighterapi.Candlestick{
+				Timestamp: ts, Open: p, High: p + 2, Low: p - 1, Close: p + 1, Volume0: 1, Volume1: 3000, LastTradeId: 100 + int64(i),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Error(err)
+		}
+	}))
+}
+
+func TestCandleBuilderBackfillsAndExtendsLive(t *testing.T) {
+	srv, api, _ := lightertest.NewExchange(t)
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	serveMinuteCandles(t, srv, t0)
+
+	b, err := client.NewCandleBuilder(api, 0, 3*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var closed []client.Candle
+	b.OnClose(func(c client.Candle) { closed = append(closed, c) })
+	if err := b.Backfill(context.Background(), t0, t0.Add(9*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	bars := b.Candles()
+	if len(bars) != 3 {
+		t.Fatalf("%d backfilled candles, want 3", len(bars))
+	}
+	want := client.Candle{Start: t0.UnixMilli(), End: t0.Add(3 * time.Minute).UnixMilli(), Open: 3000, High: 3004, Low: 2999, Close: 3003, Volume: 3, QuoteVolume: 9000, LastTradeId: 102}
+	if bars[0] != want {
+		t.Fatalf("first candle %+v, want %+v", bars[0], want)
+	}
+	if len(closed) != 0 {
+		t.Fatal("backfilled candles were reported as closed")
+	}
+
+	trade := func(id int64, at time.Duration, price, size string) {
+		t.Helper()
+		if err := b.HandleTrade(client.LighterTradesResponse{MarketId: 0, TradeId: id, Price: price, Quantity: size, Timestamp: t0.Add(at).UnixMilli()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Trade 105 is already in the backfill
+	trade(105, 9*time.Minute, "2000.00", "5")
+	if _, ok := b.Current(); ok {
+		t.Fatal("a backfilled trade opened a candle")
+	}
+	trade(109, 9*time.Minute+10*time.Second, "3100.00", "0.5")
+	// The next trade closes the live candle and a flat one for the quiet period after it
+	trade(110, 15*time.Minute+time.Second, "3050.00", "1")
+	if len(closed) != 2 {
+		t.Fatalf("%d candles closed, want 2", len(closed))
+	}
+	if c := closed[0]; c.Open != 3009 || c.High != 3100 || c.Low != 3009 || c.Close != 3100 || c.Volume != 0.5 || c.Trades != 1 {
+		t.Fatalf("live candle %+v, want 3009/3100/3009/3100 with 0.5 traded", c)
+	}
+	if c := closed[1]; c.Open != 3100 || c.High != 3100 || c.Low != 3100 || c.Close != 3100 || c.Volume != 0 {
+		t.Fatalf("quiet candle %+v, want flat at 3100", c)
+	}
+
+	b.Flush(t0.Add(18 * time.Minute))
+	if len(closed) != 3 || closed[2].Close != 3050 || closed[2].Low != 3050 {
+		t.Fatalf("closed %+v, want the candle from 15m flushed at 3050", closed)
+	}
+	if n := len(b.Candles()); n != 6 {
+		t.Fatalf("%d candles in history, want 6", n)
+	}
+	b.SetMaxHistory(4)
+	if bars := b.Candles(); len(bars) != 4 || bars[0].Start != t0.Add(6*time.Minute).UnixMilli() {
+		t.Fatalf("history %+v, want the 4 latest candles", bars)
+	}
+}
+
+func TestCandleBuilderResolutions(t *testing.T) {
+	_, api, _ := lightertest.NewExchange(t)
+	if _, err := client.NewCandleBuilder(api, 0, 1500*time.Microsecond); err == nil {
+		t.Fatal("expected a resolution that is not whole milliseconds to be refused")
+	}
+	b, err := client.NewCandleBuilder(api, 0, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Backfill(context.Background(), time.Now().Add(-time.Hour), time.Now()); err == nil {
+		t.Fatal("expected a 30s resolution to be refused for backfill")
+	}
+}
@@ -0,0 +1,305 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces scrubbed credentials in cassettes
+const Redacted = "REDACTED"
+
+// scrubbedHeaders and scrubbedParams never reach a cassette in clear text
+var (
+	scrubbedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	scrubbedParams  = []string{"auth", "authorization"}
+)
+
+// RecordedRequest is the scrubbed request half of an interaction
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response half of an interaction
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// Interaction is one request/response pair
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Cassette is the file format shared by RecordingTransport and ReplayTransport
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("client: read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("client: decode cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as indented JSON
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("client: write cassette: %w", err)
+	}
+	return nil
+}
+
+// RecordingTransport passes requests to the next transport and records every exchange with
+// auth headers and auth query/form parameters scrubbed. Use it through
+// WithHTTPClient(&http.Client{Transport: rec}) and call Save when done.
+type RecordingTransport struct {
+	next http.RoundTripper
+	path string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecordingTransport records to path; a nil next uses http.DefaultTransport
+func NewRecordingTransport(path string, next http.RoundTripper) *RecordingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &RecordingTransport{next: next, path: path}
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := drainBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := drainBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    scrubURL(req.URL),
+			Header: scrubHeader(req.Header),
+			Body:   scrubBody(req.Header.Get("Content-Type"), reqBody),
+		},
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: scrubHeader(resp.Header),
+			Body:   string(respBody),
+		},
+	})
+	t.mu.Unlock()
+	return resp, nil
+}
+
+// Interactions returns the exchanges recorded so far
+func (t *RecordingTransport) Interactions() []Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Interaction(nil), t.cassette.Interactions...)
+}
+
+// Save writes the recorded interactions to the cassette file
+func (t *RecordingTransport) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cassette.Save(t.path)
+}
+
+// CassetteMatcher decides whether a recorded request answers an outgoing one. body is the
+// scrubbed outgoing body.
+type CassetteMatcher func(req *http.Request, body string, recorded RecordedRequest) bool
+
+// MatchMethodAndURL matches on method, path and scrubbed query, ignoring bodies; useful when
+// bodies carry nonces or signatures that change between runs
+func MatchMethodAndURL(req *http.Request, _ string, recorded RecordedRequest) bool {
+	return req.Method == recorded.Method && scrubURL(req.URL) == recorded.URL
+}
+
+// MatchRequest matches on method, path, scrubbed query and scrubbed body
+func MatchRequest(req *http.Request, body string, recorded RecordedRequest) bool {
+	return MatchMethodAndURL(req, body, recorded) && body == recorded.Body
+}
+
+// ReplayTransport answers requests from a cassette without touching the network.
+// Interactions that match the same request are served in recorded order, and the last one is
+// repeated once they are used up. Unmatched requests fail.
+type ReplayTransport struct {
+	match CassetteMatcher
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayTransport loads a cassette for replay, matching with MatchRequest
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	c, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayTransportFrom(c), nil
+}
+
+// NewReplayTransportFrom replays an in-memory cassette
+func NewReplayTransportFrom(c *Cassette) *ReplayTransport {
+	return &ReplayTransport{
+		match:        MatchRequest,
+		interactions: c.Interactions,
+		used:         make([]bool, len(c.Interactions)),
+	}
+}
+
+// SetMatcher replaces the request matcher
+func (t *ReplayTransport) SetMatcher(match CassetteMatcher) {
+	if match != nil {
+		t.match = match
+	}
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := drainBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	body := scrubBody(req.Header.Get("Content-Type"), reqBody)
+
+	t.mu.Lock()
+	found := -1
+	for i, in := range t.interactions {
+		if !t.match(req, body, in.Request) {
+			continue
+		}
+		found = i
+		if !t.used[i] {
+			break
+		}
+	}
+	if found >= 0 {
+		t.used[found] = true
+	}
+	t.mu.Unlock()
+
+	if found < 0 {
+		return nil, fmt.Errorf("client: no recorded response for %s %s", req.Method, scrubURL(req.URL))
+	}
+	rec := t.interactions[found].Response
+	header := rec.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Unused returns the recorded interactions no request has matched yet
+func (t *ReplayTransport) Unused() []Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var unused []Interaction
+	for i, in := range t.interactions {
+		if !t.used[i] {
+			unused = append(unused, in)
+		}
+	}
+	return unused
+}
+
+// drainBody reads a body and replaces it with an in-memory copy
+func drainBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// scrubURL drops the host and redacts auth parameters, so cassettes replay against any base URL
+func scrubURL(u *url.URL) string {
+	out := u.EscapedPath()
+	if u.RawQuery != "" {
+		out += "?" + scrubValues(u.Query()).Encode()
+	}
+	return out
+}
+
+func scrubHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := h.Clone()
+	for _, key := range scrubbedHeaders {
+		if out.Get(key) != "" {
+			out.Set(key, Redacted)
+		}
+	}
+	return out
+}
+
+func scrubBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			return scrubValues(values).Encode()
+		}
+	}
+	return string(body)
+}
+
+func scrubValues(values url.Values) url.Values {
+	for key := range values {
+		for _, name := range scrubbedParams {
+			if strings.EqualFold(key, name) {
+				values.Set(key, Redacted)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,118 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+)
+
+// cassetteCalls makes the requests the cassette test records and replays: a trade history
+// page authenticated by header, then raw calls carrying auth in the query and form body
+func cassetteCalls(t *testing.T, baseURL, token string, transport http.RoundTripper, from, to time.Time) []string {
+	t.Helper()
+	httpClient := &http.Client{Transport: transport}
+	api, err := client.New(baseURL, client.WithHTTPClient(httpClient), client.WithAuthTokenProvider(func() (string, error) { return token, nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trades, err := api.AccountTradeHistory(context.Background(), testAccount, nil, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("%d trades, want 1", len(trades))
+	}
+
+	read := func(resp *http.Response, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+	return []string{
+		read(httpClient.Get(baseURL + "/api/v1/export?type=trade&auth=" + token)),
+		read(httpClient.PostForm(baseURL+"/api/v1/notification/ack", url.Values{"auth": {token}, "notif_id": {"7"}})),
+	}
+}
+
+func TestCassetteScrubsAuthAndReplays(t *testing.T) {
+	srv, _, _ := lightertest.NewExchange(t)
+	if _, err := srv.PlaceOrder(otherAccount, 0, true, 300000, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.PlaceOrder(testAccount, 0, false, 300000, 1000); err != nil {
+		t.Fatal(err)
+	}
+	srv.Handle("/api/v1/export", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":200,"data_url":"/files/trade.csv"}`)
+	}))
+	srv.Handle("/api/v1/notification/ack", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"code":200,"message":"acked `+r.PostFormValue("notif_id")+`"}`)
+	}))
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec := client.NewRecordingTransport(path, nil)
+	recorded := cassetteCalls(t, srv.URL, "secret-token", rec, from, to)
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-token") {
+		t.Fatalf("cassette leaks the token:\n%s", data)
+	}
+	in := rec.Interactions()
+	if len(in) != 3 {
+		t.Fatalf("%d interactions recorded, want 3", len(in))
+	}
+	if got := in[0].Request.Header.Get("Authorization"); got != client.Redacted {
+		t.Fatalf("recorded Authorization %q, want it redacted", got)
+	}
+	if got := in[1].Request.URL; got != "/api/v1/export?auth=REDACTED&type=trade" {
+		t.Fatalf("recorded URL %q, want the auth parameter redacted", got)
+	}
+	if got := in[2].Request.Body; got != "auth=REDACTED&notif_id=7" {
+		t.Fatalf("recorded body %q, want the auth field redacted", got)
+	}
+
+	// Replay serves the same responses to another host and token, without the network
+	srv.Close()
+	replay, err := client.NewReplayTransport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := cassetteCalls(t, "http://replay.invalid", "other-token", replay, from, to)
+	for i := range recorded {
+		if replayed[i] != recorded[i] {
+			t.Fatalf("replayed response %d %q, want %q", i, replayed[i], recorded[i])
+		}
+	}
+	if unused := replay.Unused(); len(unused) != 0 {
+		t.Fatalf("%d interactions left unused", len(unused))
+	}
+
+	// The body takes part in matching
+	_, err = (&http.Client{Transport: replay}).PostForm("http://replay.invalid/api/v1/notification/ack", url.Values{"notif_id": {"8"}})
+	if err == nil {
+		t.Fatal("expected an unrecorded body to find no response")
+	}
+}
@@ -2,13 +2,15 @@ package client
 
 import (
 	"errors"
+	"fmt"
 
 	lighterapi "github.com/defi-maker/golighter/api"
 )
 
 type Client struct {
-	api  lighterapi.ClientWithResponsesInterface
-	opts options
+	api     lighterapi.ClientWithResponsesInterface
+	opts    options
+	baseURL string
 }
 
 func New(baseURL string, opts ...Option) (*Client, error) {
@@ -30,7 +32,23 @@ func New(baseURL string, opts ...Option) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{api: apiClient, opts: cfg}, nil
+	return &Client{api: apiClient, opts: cfg, baseURL: baseURL}, nil
+}
+
+// SetAuthTokenProvider sets the token source after construction, for example once a TxClient exists
+func (c *Client) SetAuthTokenProvider(fn AuthTokenFunc) {
+	c.opts.authToken = fn
+}
+
+func (c *Client) authToken() (*string, error) {
+	if c.opts.authToken == nil {
+		return nil, nil
+	}
+	token, err := c.opts.authToken()
+	if err != nil {
+		return nil, fmt.Errorf("client: auth token: %w", err)
+	}
+	return &token, nil
 }
 
 func (c *Client) API() lighterapi.ClientWithResponsesInterface {
@@ -2,6 +2,9 @@ package client
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"time"
 
 	lighterapi "github.com/defi-maker/golighter/api"
 )
@@ -21,3 +24,202 @@ func (c *Client) ApiKeysFor(ctx context.Context, accountIndex int64, apiKeyIndex
 	}
 	return c.Apikeys(ctx, params)
 }
+
+// OrderBookDetail returns the details of a single market
+func (c *Client) OrderBookDetail(ctx context.Context, marketId uint8) (*lighterapi.OrderBookDetail, error) {
+	resp, err := c.OrderBookDetails(ctx, &lighterapi.OrderBookDetailsParams{MarketId: &marketId})
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.OrderBookDetails {
+		if resp.OrderBookDetails[i].MarketId == marketId {
+			return &resp.OrderBookDetails[i], nil
+		}
+	}
+	return nil, fmt.Errorf("lighter api: market %d not found", marketId)
+}
+
+// AccountByIndex returns the detailed account for an account index
+func (c *Client) AccountByIndex(ctx context.Context, accountIndex int64) (*lighterapi.DetailedAccount, error) {
+	resp, err := c.Account(ctx, &lighterapi.AccountParams{By: lighterapi.AccountParamsByIndex, Value: strconv.FormatInt(accountIndex, 10)})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Accounts) == 0 {
+		return nil, fmt.Errorf("lighter api: account %d not found", accountIndex)
+	}
+	return &resp.Accounts[0], nil
+}
+
+// PositionFundingHistory pages through PositionFunding until entries older than since (ms) are reached.
+// A nil marketId covers all markets; auth comes from the configured AuthTokenFunc.
+func (c *Client) PositionFundingHistory(ctx context.Context, accountIndex int64, marketId *uint8, since int64) ([]lighterapi.PositionFunding, error) {
+	auth, err := c.authToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		entries []lighterapi.PositionFunding
+		cursor  *string
+	)
+	for {
+		resp, err := c.PositionFunding(ctx, &lighterapi.PositionFundingParams{
+			AccountIndex:  accountIndex,
+			MarketId:      marketId,
+			Cursor:        cursor,
+			Limit:         100,
+			Authorization: auth,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range resp.PositionFundings {
+			if UnixMillis(entry.Timestamp) < since {
+				return entries, nil
+			}
+			entries = append(entries, entry)
+		}
+		if len(resp.PositionFundings) == 0 || resp.NextCursor == nil || *resp.NextCursor == "" {
+			return entries, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
+
+// UnixMillis normalizes an API timestamp that may be in seconds to milliseconds
+func UnixMillis(ts int64) int64 {
+	if ts > 0 && ts < 1e12 {
+		return ts * 1000
+	}
+	return ts
+}
+
+// AccountTradeHistory returns an account's trades in [from, to), newest first.
+// Trades are walked backwards by timestamp, the only order the API supports.
+func (c *Client) AccountTradeHistory(ctx context.Context, accountIndex int64, marketId *uint8, from, to time.Time) ([]lighterapi.Trade, error) {
+	auth, err := c.authToken()
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := from.UnixMilli(), to.UnixMilli()
+	dir := lighterapi.TradesParamsSortDirDesc
+	var (
+		trades []lighterapi.Trade
+		cursor *string
+	)
+	for {
+		params := &lighterapi.TradesParams{
+			MarketId:      marketId,
+			AccountIndex:  &accountIndex,
+			SortBy:        lighterapi.TradesParamsSortByTimestamp,
+			SortDir:       &dir,
+			Limit:         100,
+			Cursor:        cursor,
+			Authorization: auth,
+		}
+		if cursor == nil {
+			params.From = &end
+		}
+		resp, err := c.Trades(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, trade := range resp.Trades {
+			ts := UnixMillis(trade.Timestamp)
+			if ts >= end {
+				continue
+			}
+			if ts < start {
+				return trades, nil
+			}
+			trades = append(trades, trade)
+		}
+		if len(resp.Trades) == 0 || resp.NextCursor == nil || *resp.NextCursor == "" {
+			return trades, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
+
+// AccountPnl returns an account's Pnl rows between from and to at the given resolution
+func (c *Client) AccountPnl(ctx context.Context, accountIndex int64, resolution lighterapi.PnlParamsResolution, from, to time.Time) ([]lighterapi.PnLEntry, error) {
+	auth, err := c.authToken()
+	if err != nil {
+		return nil, err
+	}
+	step := map[lighterapi.PnlParamsResolution]time.Duration{
+		lighterapi.N1m:  time.Minute,
+		lighterapi.N5m:  5 * time.Minute,
+		lighterapi.N15m: 15 * time.Minute,
+		lighterapi.N1h:  time.Hour,
+		lighterapi.N4h:  4 * time.Hour,
+		lighterapi.N1d:  24 * time.Hour,
+	}[resolution]
+	if step == 0 {
+		return nil, fmt.Errorf("client: unknown pnl resolution %q", resolution)
+	}
+	resp, err := c.Pnl(ctx, &lighterapi.PnlParams{
+		By:             lighterapi.Index,
+		Value:          strconv.FormatInt(accountIndex, 10),
+		Resolution:     resolution,
+		StartTimestamp: from.UnixMilli(),
+		EndTimestamp:   to.UnixMilli(),
+		CountBack:      int64(to.Sub(from)/step) + 1,
+		Authorization:  auth,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Pnl, nil
+}
+
+// ActiveOrders returns the open orders of an account in a market; auth comes from the
+// configured AuthTokenFunc.
+func (c *Client) ActiveOrders(ctx context.Context, accountIndex int64, marketId uint8) ([]lighterapi.Order, error) {
+	auth, err := c.authToken()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.AccountActiveOrders(ctx, &lighterapi.AccountActiveOrdersParams{
+		AccountIndex:  accountIndex,
+		MarketId:      marketId,
+		Authorization: auth,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Orders, nil
+}
+
+// RecentInactiveOrders returns up to limit of an account's most recently closed orders, newest
+// first. A nil marketId covers all markets; auth comes from the configured AuthTokenFunc.
+func (c *Client) RecentInactiveOrders(ctx context.Context, accountIndex int64, marketId *uint8, limit int) ([]lighterapi.Order, error) {
+	auth, err := c.authToken()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		orders []lighterapi.Order
+		cursor *string
+	)
+	for len(orders) < limit {
+		resp, err := c.AccountInactiveOrders(ctx, &lighterapi.AccountInactiveOrdersParams{
+			AccountIndex:  accountIndex,
+			MarketId:      marketId,
+			Cursor:        cursor,
+			Limit:         int64(min(limit-len(orders), 100)),
+			Authorization: auth,
+		})
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, resp.Orders...)
+		if len(resp.Orders) == 0 || resp.NextCursor == nil || *resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	return orders, nil
+}
@@ -0,0 +1,77 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+)
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// servePositionFunding pages through hourly fundings, newest first, two per page. The API
+// reports their timestamps in seconds.
+func servePositionFunding(srv *lightertest.Server, newest time.Time, n int) {
+	srv.Handle("/api/v1/positionFunding", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := 0
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			page = int(cursor[0] - '0')
+		}
+		resp := lighterapi.PositionFundings{Code: 200}
+		for i := 2 * page; i < 2*page+2 && i < n; i++ {
+			resp.PositionFundings = append(resp.PositionFundings, lighterapi.PositionFunding{
+				FundingId: int64(n - i),
+				Timestamp: newest.Add(-time.Duration(i) * time.Hour).Unix(),
+				Change:    "-0.10",
+			})
+		}
+		if 2*page+2 < n {
+			next := string(rune('0' + page + 1))
+			resp.NextCursor = &next
+		}
+		writeJSON(w, resp)
+	}))
+}
+
+func TestPositionFundingHistoryComparesMilliseconds(t *testing.T) {
+	srv, _, _ := lightertest.NewExchange(t)
+	newest := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	servePositionFunding(srv, newest, 6)
+	api, err := srv.Client(client.WithAuthTokenProvider(func() (string, error) { return "token", nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fundings at 12:00 down to 09:00 are at or after since; the walk stops on the third page
+	since := newest.Add(-3 * time.Hour).UnixMilli()
+	entries, err := api.PositionFundingHistory(context.Background(), testAccount, nil, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[3].FundingId != 3 {
+		t.Fatalf("%d entries %+v, want the 4 fundings since 09:00", len(entries), entries)
+	}
+	if n := srv.Requests("/api/v1/positionFunding"); n != 3 {
+		t.Fatalf("%d pages requested, want 3", n)
+	}
+}
+
+func TestUnixMillis(t *testing.T) {
+	for _, tt := range []struct{ in, want int64 }{
+		{1767225600, 1767225600000},
+		{1767225600000, 1767225600000},
+		{0, 0},
+	} {
+		if got := client.UnixMillis(tt.in); got != tt.want {
+			t.Fatalf("UnixMillis(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
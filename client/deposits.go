@@ -0,0 +1,329 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+)
+
+// DepositEventType is a stage in the life of a deposit
+type DepositEventType string
+
+const (
+	DepositSeen     DepositEventType = "seen"
+	DepositExecuted DepositEventType = "executed"
+	DepositCredited DepositEventType = "credited"
+	DepositFailed   DepositEventType = "failed"
+)
+
+const defaultDepositPollInterval = 5 * time.Second
+
+// DepositEvent reports that a deposit reached a stage. Tx is set once the L2 transaction
+// is known through TxFromL1TxHash.
+type DepositEvent struct {
+	Type     DepositEventType
+	L1TxHash string
+	Amount   float64
+	Item     *lighterapi.DepositHistoryItem
+	Tx       *lighterapi.EnrichedTx
+	Time     time.Time
+}
+
+type depositStage int
+
+const (
+	stageNone depositStage = iota
+	stageSeen
+	stageExecuted
+	stageDone
+)
+
+// trackedDeposit caches what is known about a deposit; the stage each caller has reported
+// is kept by poll, so concurrent and later waits all see every event
+type trackedDeposit struct {
+	item *lighterapi.DepositHistoryItem
+	tx   *lighterapi.EnrichedTx
+}
+
+// DepositWatcher follows deposits into an account through DepositHistory and TxFromL1TxHash.
+// DepositHistory is authenticated with the client's AuthTokenFunc.
+type DepositWatcher struct {
+	api          *Client
+	accountIndex int64
+	l1Address    string
+	interval     time.Duration
+
+	mu       sync.Mutex
+	deposits map[string]*trackedDeposit
+}
+
+// NewDepositWatcher creates a watcher for deposits from l1Address into accountIndex
+func NewDepositWatcher(api *Client, accountIndex int64, l1Address string) (*DepositWatcher, error) {
+	if api == nil {
+		return nil, errors.New("client: REST client is required")
+	}
+	if l1Address == "" {
+		return nil, errors.New("client: l1 address is required")
+	}
+	return &DepositWatcher{
+		api:          api,
+		accountIndex: accountIndex,
+		l1Address:    l1Address,
+		interval:     defaultDepositPollInterval,
+		deposits:     make(map[string]*trackedDeposit),
+	}, nil
+}
+
+// SetPollInterval changes how often the watcher polls; the default is 5 seconds
+func (w *DepositWatcher) SetPollInterval(interval time.Duration) {
+	if interval > 0 {
+		w.interval = interval
+	}
+}
+
+// WaitForTx follows the deposit made by an L1 transaction until it is credited, emitting
+// seen, executed and credited events in order. It returns the credited event, or an error
+// when the deposit fails or ctx is done.
+func (w *DepositWatcher) WaitForTx(ctx context.Context, l1TxHash string, onEvent func(DepositEvent)) (*DepositEvent, error) {
+	key := normalizeHash(l1TxHash)
+	if key == "" {
+		return nil, errors.New("client: l1 tx hash is required")
+	}
+	var final *DepositEvent
+	err := w.poll(ctx, func(item *lighterapi.DepositHistoryItem) bool {
+		return normalizeHash(item.L1TxHash) == key
+	}, []string{key}, func(ev DepositEvent) bool {
+		if onEvent != nil {
+			onEvent(ev)
+		}
+		if ev.Type == DepositCredited || ev.Type == DepositFailed {
+			final = &ev
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if final.Type == DepositFailed {
+		return final, fmt.Errorf("client: deposit %s failed", l1TxHash)
+	}
+	return final, nil
+}
+
+// WaitForNext waits for the next deposit not yet listed in DepositHistory and follows it
+// until it is credited
+func (w *DepositWatcher) WaitForNext(ctx context.Context, onEvent func(DepositEvent)) (*DepositEvent, error) {
+	known, err := w.history(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(known))
+	for _, item := range known {
+		existing[item.Id] = true
+	}
+
+	var (
+		final  *DepositEvent
+		target string
+	)
+	err = w.poll(ctx, func(item *lighterapi.DepositHistoryItem) bool {
+		if existing[item.Id] {
+			return false
+		}
+		if target == "" {
+			target = item.Id
+		}
+		return item.Id == target
+	}, nil, func(ev DepositEvent) bool {
+		if onEvent != nil {
+			onEvent(ev)
+		}
+		if ev.Type == DepositCredited || ev.Type == DepositFailed {
+			final = &ev
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if final.Type == DepositFailed {
+		return final, fmt.Errorf("client: deposit %s failed", final.L1TxHash)
+	}
+	return final, nil
+}
+
+// Watch emits events for every deposit that appears after it starts until ctx is done
+func (w *DepositWatcher) Watch(ctx context.Context, onEvent func(DepositEvent)) error {
+	known, err := w.history(ctx)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(known))
+	for _, item := range known {
+		existing[item.Id] = true
+	}
+	return w.poll(ctx, func(item *lighterapi.DepositHistoryItem) bool {
+		return !existing[item.Id]
+	}, nil, func(ev DepositEvent) bool {
+		onEvent(ev)
+		return false
+	})
+}
+
+// poll tracks deposits accepted by match, plus L1 hashes known up front, and calls emit for
+// each stage reached until emit returns true or ctx is done
+func (w *DepositWatcher) poll(ctx context.Context, match func(*lighterapi.DepositHistoryItem) bool, hashes []string, emit func(DepositEvent) bool) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	stages := make(map[string]depositStage)
+	for {
+		events, err := w.step(ctx, match, hashes, stages)
+		for _, ev := range events {
+			if emit(ev) {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return err
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// step refreshes the tracked deposits and returns the events of the stages reached since
+// the caller's stages were last updated. The lock is released while L2 transactions are
+// looked up, so concurrent waits are not serialized behind the network.
+func (w *DepositWatcher) step(ctx context.Context, match func(*lighterapi.DepositHistoryItem) bool, hashes []string, stages map[string]depositStage) ([]DepositEvent, error) {
+	items, err := w.history(ctx)
+
+	w.mu.Lock()
+	tracked := make(map[string]*trackedDeposit)
+	for _, hash := range hashes {
+		tracked[hash] = w.deposit(hash)
+	}
+	for i := range items {
+		item := &items[i]
+		if !match(item) {
+			continue
+		}
+		key := normalizeHash(item.L1TxHash)
+		if key == "" {
+			key = "id:" + item.Id
+		} else if pending, ok := w.deposits["id:"+item.Id]; ok {
+			// The L1 hash showed up after the entry was first tracked by id
+			delete(w.deposits, "id:"+item.Id)
+			w.deposits[key] = pending
+		}
+		if stage, ok := stages["id:"+item.Id]; ok && key != "id:"+item.Id {
+			delete(stages, "id:"+item.Id)
+			stages[key] = stage
+		}
+		d := w.deposit(key)
+		d.item = item
+		tracked[key] = d
+	}
+	var lookups []string
+	for hash, d := range tracked {
+		if stages[hash] != stageDone && !strings.HasPrefix(hash, "id:") && (d.tx == nil || d.tx.ExecutedAt == 0) {
+			lookups = append(lookups, hash)
+		}
+	}
+	w.mu.Unlock()
+
+	txs := make(map[string]*lighterapi.EnrichedTx, len(lookups))
+	for _, hash := range lookups {
+		if tx, txErr := w.api.TxFromL1TxHash(ctx, &lighterapi.TxFromL1TxHashParams{Hash: "0x" + hash}); txErr == nil && tx.Hash != "" {
+			txs[hash] = tx
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var events []DepositEvent
+	now := time.Now()
+	for hash, d := range tracked {
+		stage := stages[hash]
+		if stage == stageDone {
+			continue
+		}
+		// Another wait may have stored an executed transaction in the meantime
+		if tx, ok := txs[hash]; ok && (d.tx == nil || d.tx.ExecutedAt == 0) {
+			d.tx = tx
+		}
+
+		event := func(t DepositEventType) DepositEvent {
+			ev := DepositEvent{Type: t, L1TxHash: hash, Item: d.item, Tx: d.tx, Time: now}
+			if d.item != nil {
+				ev.L1TxHash = d.item.L1TxHash
+				ev.Amount = parseDecimal(d.item.Amount)
+			}
+			return ev
+		}
+
+		status := lighterapi.DepositHistoryItemStatus("")
+		if d.item != nil {
+			status = d.item.Status
+		}
+		if status == lighterapi.DepositHistoryItemStatusFailed {
+			stages[hash] = stageDone
+			events = append(events, event(DepositFailed))
+			continue
+		}
+		if stage < stageSeen && (d.item != nil || d.tx != nil) {
+			stage = stageSeen
+			events = append(events, event(DepositSeen))
+		}
+		if stage < stageExecuted && ((d.tx != nil && d.tx.ExecutedAt > 0) || status == lighterapi.DepositHistoryItemStatusCompleted) {
+			stage = stageExecuted
+			events = append(events, event(DepositExecuted))
+		}
+		if stage < stageDone && status == lighterapi.DepositHistoryItemStatusCompleted {
+			stage = stageDone
+			events = append(events, event(DepositCredited))
+		}
+		stages[hash] = stage
+	}
+	return events, err
+}
+
+func (w *DepositWatcher) deposit(key string) *trackedDeposit {
+	d, ok := w.deposits[key]
+	if !ok {
+		d = &trackedDeposit{}
+		w.deposits[key] = d
+	}
+	return d
+}
+
+func (w *DepositWatcher) history(ctx context.Context) ([]lighterapi.DepositHistoryItem, error) {
+	auth, err := w.api.authToken()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.api.DepositHistory(ctx, &lighterapi.DepositHistoryParams{
+		AccountIndex:  w.accountIndex,
+		L1Address:     w.l1Address,
+		Authorization: auth,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Deposits, nil
+}
+
+func normalizeHash(hash string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(hash), "0x"))
+}
@@ -0,0 +1,62 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+)
+
+func TestDepositWatcherLooksUpTxsWithoutTheLock(t *testing.T) {
+	srv, _, _ := lightertest.NewExchange(t)
+	srv.HandleJSON("/api/v1/deposit/history", lighterapi.DepositHistory{Code: 200, Deposits: []lighterapi.DepositHistoryItem{
+		{Id: "1", L1TxHash: "0xaa", Amount: "100", Status: lighterapi.DepositHistoryItemStatusPending},
+		{Id: "2", L1TxHash: "0xbb", Amount: "250", Status: lighterapi.DepositHistoryItemStatusCompleted},
+	}})
+	// The lookup of the pending deposit hangs until the test ends
+	release := make(chan struct{})
+	defer close(release)
+	srv.Handle("/api/v1/txFromL1TxHash", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("hash") == "0xaa" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+		writeJSON(w, lighterapi.EnrichedTx{Code: 200, Hash: "l2" + r.URL.Query().Get("hash"), ExecutedAt: 1})
+	}))
+	api, err := srv.Client(client.WithAuthTokenProvider(func() (string, error) { return "token", nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := client.NewDepositWatcher(api, testAccount, traderL1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetPollInterval(time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.WaitForTx(ctx, "0xaa", nil)
+	for srv.Requests("/api/v1/txFromL1TxHash") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	wait, cancelWait := context.WithTimeout(ctx, 2*time.Second)
+	defer cancelWait()
+	var events []client.DepositEventType
+	ev, err := w.WaitForTx(wait, "0xBB", func(ev client.DepositEvent) { events = append(events, ev.Type) })
+	if err != nil {
+		t.Fatalf("waiting for the credited deposit behind a hung lookup: %v", err)
+	}
+	if ev.Amount != 250 || ev.Tx == nil || ev.Tx.Hash != "l20xbb" {
+		t.Fatalf("final event %+v, want the credited 250 with its L2 transaction", ev)
+	}
+	if len(events) != 3 || events[0] != client.DepositSeen || events[1] != client.DepositExecuted || events[2] != client.DepositCredited {
+		t.Fatalf("events %v, want seen, executed and credited", events)
+	}
+}
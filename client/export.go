@@ -0,0 +1,219 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+)
+
+// ExportResult describes a downloaded export file and the records parsed from it. Trades
+// is set for trade exports and Fundings for funding exports.
+type ExportResult struct {
+	DataUrl  string
+	Bytes    int64
+	Trades   []lighterapi.Trade
+	Fundings []lighterapi.Funding
+}
+
+// ExportAndDownload requests an export and streams the file behind ExportData.DataUrl into dst
+// using the client's HTTP transport, then parses it into typed records. A nil dst only parses
+// the file. When neither Auth nor Authorization is set, a token from the configured
+// AuthTokenFunc is injected. The byte count is checked against Content-Length.
+func (c *Client) ExportAndDownload(ctx context.Context, params *lighterapi.ExportParams, dst io.Writer) (*ExportResult, error) {
+	if params == nil {
+		return nil, errors.New("client: export params are required")
+	}
+	p := *params
+	if p.Auth == nil && p.Authorization == nil {
+		token, err := c.authToken()
+		if err != nil {
+			return nil, err
+		}
+		p.Authorization = token
+	}
+
+	export, err := c.Export(ctx, &p)
+	if err != nil {
+		return nil, err
+	}
+	if export.DataUrl == "" {
+		return nil, fmt.Errorf("client: export returned no data_url")
+	}
+
+	dataURL, err := c.resolveURL(export.DataUrl)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.opts.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client: download export: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, statusError(resp.StatusCode, body)
+	}
+
+	if dst == nil {
+		dst = io.Discard
+	}
+	var file bytes.Buffer
+	n, err := io.Copy(dst, io.TeeReader(resp.Body, &file))
+	if err != nil {
+		return nil, fmt.Errorf("client: download export: %w", err)
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return nil, fmt.Errorf("client: export size mismatch: got %d bytes, expected %d", n, resp.ContentLength)
+	}
+
+	result := &ExportResult{DataUrl: dataURL, Bytes: n}
+	switch p.Type {
+	case lighterapi.ExportParamsTypeTrade:
+		result.Trades, err = ParseExportTrades(&file)
+	case lighterapi.ExportParamsTypeFunding:
+		result.Fundings, err = ParseExportFundings(&file)
+	}
+	return result, err
+}
+
+func (c *Client) resolveURL(ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("client: invalid data_url %q: %w", ref, err)
+	}
+	if u.IsAbs() {
+		return u.String(), nil
+	}
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+// ParseExportTrades decodes a trade export CSV. Columns are matched to lighterapi.Trade
+// fields by their JSON names, ignoring case and treating spaces as underscores.
+func ParseExportTrades(r io.Reader) ([]lighterapi.Trade, error) {
+	var trades []lighterapi.Trade
+	err := decodeExportCSV(r, func(header, row []string) error {
+		var t lighterapi.Trade
+		if err := decodeCSVRecord(header, row, &t); err != nil {
+			return err
+		}
+		trades = append(trades, t)
+		return nil
+	})
+	return trades, err
+}
+
+// ParseExportFundings decodes a funding export CSV into lighterapi.Funding records
+func ParseExportFundings(r io.Reader) ([]lighterapi.Funding, error) {
+	var fundings []lighterapi.Funding
+	err := decodeExportCSV(r, func(header, row []string) error {
+		var f lighterapi.Funding
+		if err := decodeCSVRecord(header, row, &f); err != nil {
+			return err
+		}
+		fundings = append(fundings, f)
+		return nil
+	})
+	return fundings, err
+}
+
+func decodeExportCSV(r io.Reader, fn func(header, row []string) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("client: read export header: %w", err)
+	}
+	for i, name := range header {
+		header[i] = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+	}
+
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("client: read export line %d: %w", line, err)
+		}
+		if err := fn(header, row); err != nil {
+			return fmt.Errorf("client: decode export line %d: %w", line, err)
+		}
+	}
+}
+
+// decodeCSVRecord assigns CSV columns to the struct fields whose json tag matches the column name
+func decodeCSVRecord(header, row []string, out interface{}) error {
+	v := reflect.ValueOf(out).Elem()
+	t := v.Type()
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" {
+			fields[name] = i
+		}
+	}
+
+	for i, column := range header {
+		idx, ok := fields[column]
+		if !ok || i >= len(row) {
+			continue
+		}
+		raw := strings.TrimSpace(row[i])
+		if raw == "" {
+			continue
+		}
+		field := v.Field(idx)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(raw)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, err)
+			}
+			field.SetBool(b)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, err)
+			}
+			field.SetInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, err)
+			}
+			field.SetUint(n)
+		case reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(raw, field.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, err)
+			}
+			field.SetFloat(f)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+)
+
+const (
+	tradeExport = "Trade ID,Market ID,Price,Size,Is Maker Ask,Timestamp\n" +
+		"7,0,3000.50,0.0100,true,1767225600000\n" +
+		"8,0,3001.00,0.2000,false,1767225601000\n"
+	fundingExport = "timestamp,direction,rate,value\n1767225600,long,0.0001,-0.30\n"
+)
+
+// serveExport answers export requests with a data_url pointing at the file for the requested
+// type, and records the auth each request carried
+func serveExport(t *testing.T, srv *lightertest.Server) *[]string {
+	t.Helper()
+	var auths []string
+	srv.Handle("/api/v1/export", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"code":200,"data_url":"/files/`+r.URL.Query().Get("type")+`.csv"}`)
+	}))
+	srv.Handle("/files/trade.csv", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, tradeExport)
+	}))
+	srv.Handle("/files/funding.csv", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, fundingExport)
+	}))
+	return &auths
+}
+
+func TestExportAndDownloadParsesRecords(t *testing.T) {
+	srv, _, _ := lightertest.NewExchange(t)
+	auths := serveExport(t, srv)
+	api, err := srv.Client(client.WithAuthTokenProvider(func() (string, error) { return "token", nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var file bytes.Buffer
+	res, err := api.ExportAndDownload(ctx, &lighterapi.ExportParams{Type: lighterapi.ExportParamsTypeTrade}, &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.String() != tradeExport || res.Bytes != int64(len(tradeExport)) {
+		t.Fatalf("downloaded %d bytes %q, want the trade export", res.Bytes, file.String())
+	}
+	if len(res.Trades) != 2 || res.Fundings != nil {
+		t.Fatalf("parsed %d trades and %d fundings, want 2 trades", len(res.Trades), len(res.Fundings))
+	}
+	want := lighterapi.Trade{TradeId: 7, MarketId: 0, Price: "3000.50", Size: "0.0100", IsMakerAsk: true, Timestamp: 1767225600000}
+	if res.Trades[0] != want {
+		t.Fatalf("first trade %+v, want %+v", res.Trades[0], want)
+	}
+
+	res, err = api.ExportAndDownload(ctx, &lighterapi.ExportParams{Type: lighterapi.ExportParamsTypeFunding}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Fundings) != 1 || res.Fundings[0] != (lighterapi.Funding{Timestamp: 1767225600, Direction: "long", Rate: "0.0001", Value: "-0.30"}) {
+		t.Fatalf("fundings %+v, want the funding row", res.Fundings)
+	}
+	if len(*auths) != 2 || (*auths)[0] != "token" || (*auths)[1] != "token" {
+		t.Fatalf("export requests carried auth %q, want the provider token", *auths)
+	}
+}
+
+func TestExportAndDownloadReportsMalformedFiles(t *testing.T) {
+	srv, api, _ := lightertest.NewExchange(t)
+	srv.HandleJSON("/api/v1/export", lighterapi.ExportData{Code: 200, DataUrl: "/files/bad.csv"})
+	srv.Handle("/files/bad.csv", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "trade_id,price\nseven,3000\n")
+	}))
+	auth := "token"
+	if _, err := api.ExportAndDownload(context.Background(), &lighterapi.ExportParams{Type: lighterapi.ExportParamsTypeTrade, Auth: &auth}, nil); err == nil {
+		t.Fatal("expected a malformed trade id to fail parsing")
+	}
+}
@@ -1,31 +1,59 @@
 package client
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	lighterapi "github.com/defi-maker/golighter/api"
 )
 
+// APIError is returned when the Lighter API answers with a non-success response
+type APIError struct {
+	Status  int
+	Code    int32
+	Message string
+	Body    string
+
+	hasCode bool
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.hasCode && e.Message != "":
+		return fmt.Sprintf("lighter api: code=%d status=%d message=%s", e.Code, e.Status, e.Message)
+	case e.hasCode:
+		return fmt.Sprintf("lighter api: code=%d status=%d", e.Code, e.Status)
+	case e.Body != "":
+		return fmt.Sprintf("lighter api: status=%d body=%s", e.Status, e.Body)
+	default:
+		return fmt.Sprintf("lighter api: status=%d", e.Status)
+	}
+}
+
+// IsRateLimited reports whether err is an APIError caused by HTTP 429
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusTooManyRequests
+}
+
 func resultCodeError(status int, body []byte, rc *lighterapi.ResultCode) error {
 	if rc != nil {
-		if msg := strings.TrimSpace(deref(rc.Message)); msg != "" {
-			return fmt.Errorf("lighter api: code=%d status=%d message=%s", rc.Code, status, msg)
-		}
-		return fmt.Errorf("lighter api: code=%d status=%d", rc.Code, status)
+		return &APIError{Status: status, Code: rc.Code, Message: strings.TrimSpace(deref(rc.Message)), hasCode: true}
 	}
 	return statusError(status, body)
 }
 
 func statusError(status int, body []byte) error {
 	if len(body) == 0 {
-		return fmt.Errorf("lighter api: status=%d", status)
+		return &APIError{Status: status}
 	}
 	snippet := strings.TrimSpace(string(body))
 	if len(snippet) > 256 {
 		snippet = snippet[:256]
 	}
-	return fmt.Errorf("lighter api: status=%d body=%s", status, snippet)
+	return &APIError{Status: status, Body: snippet}
 }
 
 func deref(s *string) string {
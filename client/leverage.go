@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const (
+	defaultConfirmTimeout  = 15 * time.Second
+	defaultConfirmInterval = 500 * time.Millisecond
+	marginConfirmTolerance = 0.01
+)
+
+// MarginUpdateResult describes a leverage or isolated margin change confirmed on the account
+type MarginUpdateResult struct {
+	TxHash   string
+	Position *lighterapi.AccountPosition
+}
+
+// SetPositionSource overrides how SetLeverage and the isolated margin helpers confirm
+// their effect. The REST account endpoint is used by default; pass
+// AccountPositionCache.Source() to confirm from the private account stream instead.
+func (c *TxClient) SetPositionSource(src PositionSource) { c.positions = src }
+
+func (c *TxClient) positionSource() PositionSource {
+	if c.positions != nil {
+		return c.positions
+	}
+	return RESTPositionSource(c.api)
+}
+
+// SetLeverage updates the leverage and margin mode of a market and waits until the account
+// reports the new InitialMarginFraction. Confirmation is bounded by the context deadline,
+// or 15 seconds when the context has none.
+func (c *TxClient) SetLeverage(ctx context.Context, marketId uint8, leverage float64, marginMode MarginMode) (*MarginUpdateResult, error) {
+	if marginMode != MarginModeCross && marginMode != MarginModeIsolated {
+		return nil, fmt.Errorf("client: invalid margin mode %d", marginMode)
+	}
+	detail, err := c.api.OrderBookDetail(ctx, marketId)
+	if err != nil {
+		return nil, err
+	}
+
+	imf := LeverageToMarginFraction(leverage)
+	if imf <= 0 || imf > int(txtypes.MarginFractionTick) {
+		return nil, fmt.Errorf("client: invalid leverage %s", formatFloat(leverage))
+	}
+	if imf < detail.MinInitialMarginFraction {
+		return nil, fmt.Errorf("client: leverage %s exceeds market %d maximum of %s",
+			formatFloat(leverage), marketId, formatFloat(MarginFractionToLeverage(detail.MinInitialMarginFraction)))
+	}
+
+	tx, err := c.GetUpdateLeverageTransaction(&types.UpdateLeverageTxReq{
+		MarketIndex:           marketId,
+		InitialMarginFraction: uint16(imf),
+		MarginMode:            uint8(marginMode),
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := c.SendRawTx(ctx, tx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := c.waitForPosition(ctx, marketId, func(pos *lighterapi.AccountPosition) bool {
+		return pos.MarginMode == int32(marginMode) && marginFractionTicks(pos.InitialMarginFraction) == imf
+	})
+	if err != nil {
+		return &MarginUpdateResult{TxHash: txHash}, fmt.Errorf("client: confirm leverage update %s: %w", txHash, err)
+	}
+	return &MarginUpdateResult{TxHash: txHash, Position: position}, nil
+}
+
+// AddIsolatedMargin moves amount USDC from cross collateral into an isolated position
+func (c *TxClient) AddIsolatedMargin(ctx context.Context, marketId uint8, amount float64) (*MarginUpdateResult, error) {
+	return c.updateIsolatedMargin(ctx, marketId, amount, txtypes.AddToIsolatedMargin)
+}
+
+// RemoveIsolatedMargin moves amount USDC from an isolated position back to cross collateral
+func (c *TxClient) RemoveIsolatedMargin(ctx context.Context, marketId uint8, amount float64) (*MarginUpdateResult, error) {
+	return c.updateIsolatedMargin(ctx, marketId, amount, txtypes.RemoveFromIsolatedMargin)
+}
+
+func (c *TxClient) updateIsolatedMargin(ctx context.Context, marketId uint8, amount float64, direction uint8) (*MarginUpdateResult, error) {
+	usdc := USDCFromFloat(amount)
+	if usdc <= 0 {
+		return nil, fmt.Errorf("client: margin amount must be positive")
+	}
+	if _, err := c.api.OrderBookDetail(ctx, marketId); err != nil {
+		return nil, err
+	}
+
+	before, err := c.positionSource()(ctx, c.accountIndex, marketId)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil || before.MarginMode != int32(MarginModeIsolated) {
+		return nil, fmt.Errorf("client: market %d is not in isolated margin mode", marketId)
+	}
+	allocated := parseDecimal(before.AllocatedMargin)
+	expected := allocated + amount
+	if direction == txtypes.RemoveFromIsolatedMargin {
+		if amount > allocated {
+			return nil, fmt.Errorf("client: cannot remove %s from allocated margin %s", formatFloat(amount), before.AllocatedMargin)
+		}
+		expected = allocated - amount
+	}
+
+	tx, err := c.GetUpdateMarginTransaction(&types.UpdateMarginTxReq{
+		MarketIndex: marketId,
+		USDCAmount:  usdc,
+		Direction:   direction,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := c.SendRawTx(ctx, tx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := c.waitForPosition(ctx, marketId, func(pos *lighterapi.AccountPosition) bool {
+		return math.Abs(parseDecimal(pos.AllocatedMargin)-expected) <= marginConfirmTolerance
+	})
+	if err != nil {
+		return &MarginUpdateResult{TxHash: txHash}, fmt.Errorf("client: confirm margin update %s: %w", txHash, err)
+	}
+	return &MarginUpdateResult{TxHash: txHash, Position: position}, nil
+}
+
+func (c *TxClient) waitForPosition(ctx context.Context, marketId uint8, done func(*lighterapi.AccountPosition) bool) (*lighterapi.AccountPosition, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultConfirmTimeout)
+		defer cancel()
+	}
+
+	source := c.positionSource()
+	ticker := time.NewTicker(defaultConfirmInterval)
+	defer ticker.Stop()
+
+	for {
+		pos, err := source(ctx, c.accountIndex, marketId)
+		if err == nil && pos != nil && done(pos) {
+			return pos, nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return nil, err
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// marginFractionTicks converts the percent string reported by the API into MarginFractionTick units
+func marginFractionTicks(percent string) int {
+	return int(math.Round(parseDecimal(percent) * float64(txtypes.MarginFractionTick) / 100))
+}
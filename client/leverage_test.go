@@ -0,0 +1,98 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+)
+
+func TestSetLeverage(t *testing.T) {
+	_, _, tx := lightertest.NewExchange(t)
+	ctx := context.Background()
+
+	res, err := tx.SetLeverage(ctx, 0, 10, client.MarginModeCross)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TxHash == "" || res.Position == nil || res.Position.InitialMarginFraction != "10.00" || res.Position.MarginMode != int32(client.MarginModeCross) {
+		t.Fatalf("result %+v, want a confirmed 10%% cross margin fraction", res)
+	}
+
+	// ETH allows at most 20x
+	if _, err := tx.SetLeverage(ctx, 0, 50, client.MarginModeCross); err == nil {
+		t.Fatal("expected 50x to be refused")
+	}
+	if _, err := tx.SetLeverage(ctx, 0, 10, client.MarginMode(7)); err == nil {
+		t.Fatal("expected an invalid margin mode to be refused")
+	}
+}
+
+func TestSetLeverageReportsUnconfirmedUpdate(t *testing.T) {
+	_, _, tx := lightertest.NewExchange(t)
+	tx.SetPositionSource(func(context.Context, int64, uint8) (*lighterapi.AccountPosition, error) {
+		return &lighterapi.AccountPosition{InitialMarginFraction: "5.00"}, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	res, err := tx.SetLeverage(ctx, 0, 10, client.MarginModeCross)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("returned %v, want a confirmation timeout", err)
+	}
+	if res == nil || res.TxHash == "" {
+		t.Fatalf("result %+v, want the hash of the sent transaction", res)
+	}
+}
+
+func TestIsolatedMargin(t *testing.T) {
+	srv, _, tx := lightertest.NewExchange(t)
+	ctx := context.Background()
+
+	if _, err := tx.AddIsolatedMargin(ctx, 0, 100); err == nil {
+		t.Fatal("expected adding margin to a cross position to fail")
+	}
+	if _, err := tx.SetLeverage(ctx, 0, 5, client.MarginModeIsolated); err != nil {
+		t.Fatal(err)
+	}
+	res, err := tx.AddIsolatedMargin(ctx, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Position.AllocatedMargin; got != "100.000000" {
+		t.Fatalf("allocated margin %s, want 100", got)
+	}
+	if _, err := tx.RemoveIsolatedMargin(ctx, 0, 150); err == nil {
+		t.Fatal("expected removing more than the allocated margin to fail")
+	}
+	if res, err = tx.RemoveIsolatedMargin(ctx, 0, 40); err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Position.AllocatedMargin; got != "60.000000" {
+		t.Fatalf("allocated margin %s, want 60", got)
+	}
+	if got := collateral(t, srv, testAccount); !approx(got, 9940) {
+		t.Fatalf("cross collateral %v, want 9940", got)
+	}
+	if _, err := tx.AddIsolatedMargin(ctx, 0, 0); err == nil {
+		t.Fatal("expected a zero amount to be refused")
+	}
+}
+
+func collateral(t *testing.T, srv *lightertest.Server, account int64) float64 {
+	t.Helper()
+	acc, err := srv.Account(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := strconv.ParseFloat(acc.Collateral, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,291 @@
+package client
+
+import (
+	"fmt"
+	"math"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// MarginMode mirrors the margin modes accepted by UpdateLeverage
+type MarginMode uint8
+
+const (
+	MarginModeCross    MarginMode = txtypes.CrossMargin
+	MarginModeIsolated MarginMode = txtypes.IsolatedMargin
+)
+
+func (m MarginMode) String() string {
+	if m == MarginModeIsolated {
+		return "isolated"
+	}
+	return "cross"
+}
+
+// MarginPosition is the calculator input for a single position
+type MarginPosition struct {
+	MarketId uint8
+	// Size is the signed position in base units (negative for shorts)
+	Size       float64
+	EntryPrice float64
+	MarkPrice  float64
+	Mode       MarginMode
+	// InitialMarginFraction in MarginFractionTick units; zero uses the market default
+	InitialMarginFraction int
+	// AllocatedMargin is the collateral assigned to an isolated position
+	AllocatedMargin float64
+}
+
+// PositionMargin holds the computed requirements of a position
+type PositionMargin struct {
+	MarketId          uint8
+	Mode              MarginMode
+	Size              float64
+	Notional          float64
+	UnrealizedPnl     float64
+	InitialMargin     float64
+	MaintenanceMargin float64
+	CloseoutMargin    float64
+	// LiquidationPrice is zero when the position cannot be liquidated by price moves alone
+	LiquidationPrice float64
+}
+
+// MarginSummary aggregates the cross account and all positions
+type MarginSummary struct {
+	// Collateral is the cross collateral, excluding margin allocated to isolated positions
+	Collateral        float64
+	AccountValue      float64
+	InitialMargin     float64
+	MaintenanceMargin float64
+	CloseoutMargin    float64
+	FreeCollateral    float64
+	Positions         []PositionMargin
+}
+
+// HypotheticalOrder describes a fill used in what-if queries
+type HypotheticalOrder struct {
+	MarketId uint8
+	// Size is signed: positive buys, negative sells
+	Size  float64
+	Price float64
+	// Mode is used when the order opens a new position
+	Mode MarginMode
+}
+
+// MarginCalculator estimates margin requirements locally using market margin fractions
+type MarginCalculator struct {
+	markets map[uint8]lighterapi.OrderBookDetail
+}
+
+// NewMarginCalculator creates a calculator for the given markets
+func NewMarginCalculator(details []lighterapi.OrderBookDetail) *MarginCalculator {
+	markets := make(map[uint8]lighterapi.OrderBookDetail, len(details))
+	for _, d := range details {
+		markets[d.MarketId] = d
+	}
+	return &MarginCalculator{markets: markets}
+}
+
+// MarginPositionsFromAccount converts REST account positions into calculator inputs.
+// Mark prices are derived from PositionValue; InitialMarginFraction is reported by the API in percent.
+func MarginPositionsFromAccount(account *lighterapi.DetailedAccount) []MarginPosition {
+	positions := make([]MarginPosition, 0, len(account.Positions))
+	for _, pos := range account.Positions {
+		size := float64(pos.Sign) * parseDecimal(pos.Position)
+		if size == 0 {
+			continue
+		}
+		mark := parseDecimal(pos.AvgEntryPrice)
+		if value := parseDecimal(pos.PositionValue); value != 0 {
+			mark = math.Abs(value / size)
+		}
+		positions = append(positions, MarginPosition{
+			MarketId:              pos.MarketId,
+			Size:                  size,
+			EntryPrice:            parseDecimal(pos.AvgEntryPrice),
+			MarkPrice:             mark,
+			Mode:                  MarginMode(pos.MarginMode),
+			InitialMarginFraction: marginFractionTicks(pos.InitialMarginFraction),
+			AllocatedMargin:       parseDecimal(pos.AllocatedMargin),
+		})
+	}
+	return positions
+}
+
+// Compute evaluates margin requirements, free collateral and liquidation prices
+func (m *MarginCalculator) Compute(collateral float64, positions []MarginPosition) (*MarginSummary, error) {
+	summary := &MarginSummary{
+		Collateral: collateral,
+		Positions:  make([]PositionMargin, len(positions)),
+	}
+
+	accountValue := collateral
+	for i, pos := range positions {
+		detail, ok := m.markets[pos.MarketId]
+		if !ok {
+			return nil, fmt.Errorf("margin: unknown market %d", pos.MarketId)
+		}
+		imf := pos.InitialMarginFraction
+		if imf == 0 {
+			imf = detail.DefaultInitialMarginFraction
+		}
+
+		notional := math.Abs(pos.Size) * pos.MarkPrice
+		pm := PositionMargin{
+			MarketId:          pos.MarketId,
+			Mode:              pos.Mode,
+			Size:              pos.Size,
+			Notional:          notional,
+			UnrealizedPnl:     pos.Size * (pos.MarkPrice - pos.EntryPrice),
+			InitialMargin:     notional * fraction(imf),
+			MaintenanceMargin: notional * fraction(detail.MaintenanceMarginFraction),
+			CloseoutMargin:    notional * fraction(detail.CloseoutMarginFraction),
+		}
+		summary.Positions[i] = pm
+
+		if pos.Mode == MarginModeIsolated {
+			continue
+		}
+		accountValue += pm.UnrealizedPnl
+		summary.InitialMargin += pm.InitialMargin
+		summary.MaintenanceMargin += pm.MaintenanceMargin
+		summary.CloseoutMargin += pm.CloseoutMargin
+	}
+	summary.AccountValue = accountValue
+	summary.FreeCollateral = accountValue - summary.InitialMargin
+
+	for i, pos := range positions {
+		pm := &summary.Positions[i]
+		mmf := fraction(m.markets[pos.MarketId].MaintenanceMarginFraction)
+		if pos.Mode == MarginModeIsolated {
+			value := pos.AllocatedMargin + pm.UnrealizedPnl
+			pm.LiquidationPrice = liquidationPrice(pos.Size, pos.MarkPrice, mmf, value, 0)
+			continue
+		}
+		others := summary.MaintenanceMargin - pm.MaintenanceMargin
+		pm.LiquidationPrice = liquidationPrice(pos.Size, pos.MarkPrice, mmf, accountValue, others)
+	}
+	return summary, nil
+}
+
+// liquidationPrice solves value + size*(p-mark) = others + |size|*p*mmf for p
+func liquidationPrice(size, mark, mmf, value, others float64) float64 {
+	denom := size - math.Abs(size)*mmf
+	if size == 0 || denom == 0 {
+		return 0
+	}
+	price := (others - value + size*mark) / denom
+	if price <= 0 {
+		return 0
+	}
+	return price
+}
+
+func fraction(ticks int) float64 {
+	return float64(ticks) / float64(txtypes.MarginFractionTick)
+}
+
+// WhatIfOrder recomputes the summary as if the order were fully filled.
+// Realized PnL from reducing fills is credited to collateral (cross) or allocated margin (isolated).
+// A new isolated position is funded from cross collateral at the market default initial margin.
+func (m *MarginCalculator) WhatIfOrder(collateral float64, positions []MarginPosition, order HypotheticalOrder) (*MarginSummary, error) {
+	if order.Size == 0 {
+		return m.Compute(collateral, positions)
+	}
+	detail, ok := m.markets[order.MarketId]
+	if !ok {
+		return nil, fmt.Errorf("margin: unknown market %d", order.MarketId)
+	}
+
+	next := clonePositions(positions)
+	idx := findPosition(next, order.MarketId)
+	if idx < 0 {
+		next = append(next, MarginPosition{
+			MarketId:   order.MarketId,
+			Mode:       order.Mode,
+			EntryPrice: order.Price,
+			MarkPrice:  order.Price,
+		})
+		idx = len(next) - 1
+		if order.Mode == MarginModeIsolated {
+			allocated := math.Abs(order.Size) * order.Price * fraction(detail.DefaultInitialMarginFraction)
+			next[idx].AllocatedMargin = allocated
+			collateral -= allocated
+		}
+	}
+
+	pos := &next[idx]
+	realized := 0.0
+	switch {
+	case pos.Size == 0 || sameSign(pos.Size, order.Size):
+		total := pos.Size + order.Size
+		pos.EntryPrice = (pos.Size*pos.EntryPrice + order.Size*order.Price) / total
+		pos.Size = total
+	case math.Abs(order.Size) <= math.Abs(pos.Size):
+		realized = -order.Size * (order.Price - pos.EntryPrice)
+		pos.Size += order.Size
+	default:
+		realized = pos.Size * (order.Price - pos.EntryPrice)
+		pos.Size += order.Size
+		pos.EntryPrice = order.Price
+	}
+	if pos.Mode == MarginModeIsolated {
+		pos.AllocatedMargin += realized
+	} else {
+		collateral += realized
+	}
+	return m.Compute(collateral, next)
+}
+
+// WhatIfLeverage recomputes the summary with a new leverage for a market, as sent by UpdateLeverage
+func (m *MarginCalculator) WhatIfLeverage(collateral float64, positions []MarginPosition, marketId uint8, leverage float64) (*MarginSummary, error) {
+	detail, ok := m.markets[marketId]
+	if !ok {
+		return nil, fmt.Errorf("margin: unknown market %d", marketId)
+	}
+	imf := LeverageToMarginFraction(leverage)
+	if imf < detail.MinInitialMarginFraction || imf > int(txtypes.MarginFractionTick) {
+		return nil, fmt.Errorf("margin: leverage %s outside market %d limits (max %s)",
+			formatFloat(leverage), marketId, formatFloat(MarginFractionToLeverage(detail.MinInitialMarginFraction)))
+	}
+	next := clonePositions(positions)
+	if idx := findPosition(next, marketId); idx >= 0 {
+		next[idx].InitialMarginFraction = imf
+	}
+	return m.Compute(collateral, next)
+}
+
+// WhatIfIsolatedMargin recomputes the summary after moving delta quote units from cross collateral
+// into an isolated position (negative delta removes margin), as sent by UpdateMargin
+func (m *MarginCalculator) WhatIfIsolatedMargin(collateral float64, positions []MarginPosition, marketId uint8, delta float64) (*MarginSummary, error) {
+	next := clonePositions(positions)
+	idx := findPosition(next, marketId)
+	if idx < 0 || next[idx].Mode != MarginModeIsolated {
+		return nil, fmt.Errorf("margin: no isolated position on market %d", marketId)
+	}
+	if next[idx].AllocatedMargin+delta < 0 {
+		return nil, fmt.Errorf("margin: cannot remove %s from allocated margin %s", formatFloat(-delta), formatFloat(next[idx].AllocatedMargin))
+	}
+	next[idx].AllocatedMargin += delta
+	return m.Compute(collateral-delta, next)
+}
+
+func clonePositions(positions []MarginPosition) []MarginPosition {
+	next := make([]MarginPosition, len(positions), len(positions)+1)
+	copy(next, positions)
+	return next
+}
+
+func findPosition(positions []MarginPosition, marketId uint8) int {
+	for i := range positions {
+		if positions[i].MarketId == marketId {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameSign(a, b float64) bool {
+	return (a > 0 && b > 0) || (a < 0 && b < 0)
+}
@@ -0,0 +1,182 @@
+package client_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// marginMarket trades at 20x (5% initial) with 3% maintenance and 2% closeout margin
+var marginMarket = lighterapi.OrderBookDetail{
+	MarketId:                     0,
+	Symbol:                       "ETH",
+	PriceDecimals:                2,
+	SizeDecimals:                 4,
+	MinInitialMarginFraction:     500,
+	DefaultInitialMarginFraction: 500,
+	MaintenanceMarginFraction:    300,
+	CloseoutMarginFraction:       200,
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestMarginCompute(t *testing.T) {
+	calc := client.NewMarginCalculator([]lighterapi.OrderBookDetail{marginMarket})
+	long := []client.MarginPosition{{MarketId: 0, Size: 1, EntryPrice: 3000, MarkPrice: 3100}}
+
+	s, err := calc.Compute(1000, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm := s.Positions[0]
+	if !approx(pm.Notional, 3100) || !approx(pm.UnrealizedPnl, 100) || !approx(pm.InitialMargin, 155) || !approx(pm.MaintenanceMargin, 93) || !approx(pm.CloseoutMargin, 62) {
+		t.Fatalf("position margin %+v", pm)
+	}
+	if !approx(s.AccountValue, 1100) || !approx(s.FreeCollateral, 945) {
+		t.Fatalf("account value %v free %v, want 1100 and 945", s.AccountValue, s.FreeCollateral)
+	}
+	// 1100 + (p - 3100) = 0.03 p
+	if !approx(pm.LiquidationPrice, 2000/0.97) {
+		t.Fatalf("liquidation price %v, want %v", pm.LiquidationPrice, 2000/0.97)
+	}
+
+	short := []client.MarginPosition{{MarketId: 0, Size: -1, EntryPrice: 3000, MarkPrice: 3000}}
+	s, err = calc.Compute(1000, short)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1000 - (p - 3000) = 0.03 p
+	if got := s.Positions[0].LiquidationPrice; !approx(got, 4000/1.03) {
+		t.Fatalf("short liquidation price %v, want %v", got, 4000/1.03)
+	}
+
+	if _, err := calc.Compute(1000, []client.MarginPosition{{MarketId: 9, Size: 1}}); err == nil {
+		t.Fatal("expected an unknown market error")
+	}
+}
+
+func TestMarginIsolatedPositionsStayOutOfCross(t *testing.T) {
+	calc := client.NewMarginCalculator([]lighterapi.OrderBookDetail{marginMarket})
+	s, err := calc.Compute(1000, []client.MarginPosition{
+		{MarketId: 0, Size: 1, EntryPrice: 3000, MarkPrice: 2900, Mode: client.MarginModeIsolated, AllocatedMargin: 200},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(s.AccountValue, 1000) || s.InitialMargin != 0 {
+		t.Fatalf("cross value %v initial margin %v, want 1000 and 0", s.AccountValue, s.InitialMargin)
+	}
+	// 200 - 100 + (p - 2900) = 0.03 p
+	if got := s.Positions[0].LiquidationPrice; !approx(got, 2800/0.97) {
+		t.Fatalf("isolated liquidation price %v, want %v", got, 2800/0.97)
+	}
+}
+
+func TestMarginWhatIf(t *testing.T) {
+	calc := client.NewMarginCalculator([]lighterapi.OrderBookDetail{marginMarket})
+	long := []client.MarginPosition{{MarketId: 0, Size: 1, EntryPrice: 3000, MarkPrice: 3100}}
+
+	tests := []struct {
+		name       string
+		order      client.HypotheticalOrder
+		collateral float64
+		size       float64
+		entry      float64
+	}{
+		{"add", client.HypotheticalOrder{MarketId: 0, Size: 1, Price: 3200}, 1000, 2, 3100},
+		{"reduce", client.HypotheticalOrder{MarketId: 0, Size: -0.5, Price: 3100}, 1050, 0.5, 3000},
+		{"flip", client.HypotheticalOrder{MarketId: 0, Size: -1.5, Price: 3100}, 1100, -0.5, 3100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := calc.WhatIfOrder(1000, long, tt.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !approx(s.Collateral, tt.collateral) || !approx(s.Positions[0].Size, tt.size) {
+				t.Fatalf("collateral %v size %v, want %v and %v", s.Collateral, s.Positions[0].Size, tt.collateral, tt.size)
+			}
+			// The entry price shows through the unrealized PnL at the 3100 mark
+			if want := tt.size * (3100 - tt.entry); !approx(s.Positions[0].UnrealizedPnl, want) {
+				t.Fatalf("unrealized pnl %v, want %v", s.Positions[0].UnrealizedPnl, want)
+			}
+		})
+	}
+	if long[0].Size != 1 {
+		t.Fatal("what-if changed the input positions")
+	}
+
+	s, err := calc.WhatIfOrder(1000, nil, client.HypotheticalOrder{MarketId: 0, Size: 1, Price: 2000, Mode: client.MarginModeIsolated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(s.Collateral, 900) {
+		t.Fatalf("new isolated position left %v cross collateral, want 900", s.Collateral)
+	}
+
+	s, err = calc.WhatIfLeverage(1000, long, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(s.InitialMargin, 310) {
+		t.Fatalf("10x initial margin %v, want 310", s.InitialMargin)
+	}
+	if _, err := calc.WhatIfLeverage(1000, long, 0, 50); err == nil {
+		t.Fatal("expected 50x to exceed the market limit")
+	}
+
+	isolated := []client.MarginPosition{{MarketId: 0, Size: 1, EntryPrice: 3000, MarkPrice: 3000, Mode: client.MarginModeIsolated, AllocatedMargin: 150}}
+	s, err = calc.WhatIfIsolatedMargin(1000, isolated, 0, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(s.Collateral, 950) {
+		t.Fatalf("cross collateral %v after adding margin, want 950", s.Collateral)
+	}
+	if _, err := calc.WhatIfIsolatedMargin(1000, isolated, 0, -200); err == nil {
+		t.Fatal("expected removing more than the allocated margin to fail")
+	}
+}
+
+func TestMarginFromFakeAccount(t *testing.T) {
+	srv, api, tx := lightertest.NewExchange(t)
+	if _, err := srv.PlaceOrder(otherAccount, 0, true, 300000, 1000); err != nil {
+		t.Fatal(err)
+	}
+	req := limitOrder(1, false, 300000, 1000)
+	req.TimeInForce, req.OrderExpiry = txtypes.ImmediateOrCancel, txtypes.NilOrderExpiry
+	info, err := tx.GetCreateOrderTransaction(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Send(context.Background(), info, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	details, err := api.OrderBookDetails(context.Background(), &lighterapi.OrderBookDetailsParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := api.AccountByIndex(context.Background(), testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions := client.MarginPositionsFromAccount(account)
+	if len(positions) != 1 || !approx(positions[0].Size, 0.1) || !approx(positions[0].MarkPrice, 3000) || positions[0].InitialMarginFraction != 500 {
+		t.Fatalf("positions %+v, want a 0.1 long marked at 3000 with a 500 margin fraction", positions)
+	}
+	s, err := client.NewMarginCalculator(details.OrderBookDetails).Compute(10000, positions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(s.InitialMargin, 15) {
+		t.Fatalf("initial margin %v, want 15", s.InitialMargin)
+	}
+}
@@ -16,8 +16,12 @@ type options struct {
 	httpClient      lighterapi.HttpRequestDoer
 	requestEditors  []lighterapi.RequestEditorFn
 	priceProtection *bool
+	authToken       AuthTokenFunc
 }
 
+// AuthTokenFunc returns an auth token for endpoints that require one
+type AuthTokenFunc func() (string, error)
+
 func (o options) toClientOptions() []lighterapi.ClientOption {
 	opts := make([]lighterapi.ClientOption, 0, 1+len(o.requestEditors))
 	if o.httpClient != nil {
@@ -78,6 +82,16 @@ func WithPriceProtection(enabled bool) Option {
 	}
 }
 
+// WithAuthTokenProvider sets the token source used by helpers that inject auth automatically,
+// typically TxClient.AuthTokenProvider
+func WithAuthTokenProvider(fn AuthTokenFunc) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.authToken = fn
+		}
+	}
+}
+
 func DefaultHTTPClient() *http.Client {
 	dialer := &net.Dialer{
 		Timeout:   10 * time.Second,
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"sort"
+	"sync"
+)
+
+// BookLevel is a parsed price level of a locally maintained order book.
+type BookLevel struct {
+	Price float64
+	Size  float64
+}
+
+// OrderBookCache maintains local order books from LighterWebsocketPublicService updates.
+// Its Handle method can be passed directly as the SubscribeOrderBook callback.
+type OrderBookCache struct {
+	mu    sync.RWMutex
+	books map[uint8]*WSOrderBookState
+}
+
+// NewOrderBookCache creates an empty order book cache
+func NewOrderBookCache() *OrderBookCache {
+	return &OrderBookCache{books: make(map[uint8]*WSOrderBookState)}
+}
+
+// Handle applies a snapshot or incremental update to the cached book
+func (c *OrderBookCache) Handle(resp LighterOrderBookResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.books[resp.MarketId]
+	if !ok || resp.IsSnapshot {
+		state = &WSOrderBookState{
+			MarketId: resp.MarketId,
+			Bids:     make(map[string]string),
+			Asks:     make(map[string]string),
+		}
+		c.books[resp.MarketId] = state
+	}
+	applyLevels(state.Bids, resp.Bids)
+	applyLevels(state.Asks, resp.Asks)
+	state.Timestamp = resp.Timestamp
+	return nil
+}
+
+func applyLevels(side map[string]string, levels []PriceLevel) {
+	for _, level := range levels {
+		if parseDecimal(level.Quantity) == 0 {
+			delete(side, level.Price)
+			continue
+		}
+		side[level.Price] = level.Quantity
+	}
+}
+
+// State returns a copy of the cached book for a market, or nil if none has been received
+func (c *OrderBookCache) State(marketId uint8) *WSOrderBookState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	state, ok := c.books[marketId]
+	if !ok {
+		return nil
+	}
+	cp := &WSOrderBookState{
+		MarketId:  state.MarketId,
+		Bids:      make(map[string]string, len(state.Bids)),
+		Asks:      make(map[string]string, len(state.Asks)),
+		Timestamp: state.Timestamp,
+	}
+	for k, v := range state.Bids {
+		cp.Bids[k] = v
+	}
+	for k, v := range state.Asks {
+		cp.Asks[k] = v
+	}
+	return cp
+}
+
+// Bids returns bid levels sorted from best to worst
+func (c *OrderBookCache) Bids(marketId uint8) []BookLevel {
+	return c.levels(marketId, false)
+}
+
+// Asks returns ask levels sorted from best to worst
+func (c *OrderBookCache) Asks(marketId uint8) []BookLevel {
+	return c.levels(marketId, true)
+}
+
+func (c *OrderBookCache) levels(marketId uint8, isAsk bool) []BookLevel {
+	c.mu.RLock()
+	state, ok := c.books[marketId]
+	if !ok {
+		c.mu.RUnlock()
+		return nil
+	}
+	side := state.Bids
+	if isAsk {
+		side = state.Asks
+	}
+	levels := make([]BookLevel, 0, len(side))
+	for price, size := range side {
+		levels = append(levels, BookLevel{Price: parseDecimal(price), Size: parseDecimal(size)})
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(levels, func(i, j int) bool {
+		if isAsk {
+			return levels[i].Price < levels[j].Price
+		}
+		return levels[i].Price > levels[j].Price
+	})
+	return levels
+}
+
+// BestBid returns the highest bid for a market
+func (c *OrderBookCache) BestBid(marketId uint8) (BookLevel, bool) {
+	return c.best(marketId, false)
+}
+
+// BestAsk returns the lowest ask for a market
+func (c *OrderBookCache) BestAsk(marketId uint8) (BookLevel, bool) {
+	return c.best(marketId, true)
+}
+
+func (c *OrderBookCache) best(marketId uint8, isAsk bool) (BookLevel, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	state, ok := c.books[marketId]
+	if !ok {
+		return BookLevel{}, false
+	}
+	side := state.Bids
+	if isAsk {
+		side = state.Asks
+	}
+
+	var best BookLevel
+	found := false
+	for price, size := range side {
+		p := parseDecimal(price)
+		if !found || (isAsk && p < best.Price) || (!isAsk && p > best.Price) {
+			best = BookLevel{Price: p, Size: parseDecimal(size)}
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Mid returns the midpoint between the best bid and best ask
+func (c *OrderBookCache) Mid(marketId uint8) (float64, bool) {
+	bid, okBid := c.BestBid(marketId)
+	ask, okAsk := c.BestAsk(marketId)
+	if !okBid || !okAsk {
+		return 0, false
+	}
+	return (bid.Price + ask.Price) / 2, true
+}
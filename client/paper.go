@@ -0,0 +1,922 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/elliottech/lighter-go/signer"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// OrderClient is the order entry API shared by TxClient and PaperTxClient, so strategies can
+// switch between live and paper trading with a single flag
+type OrderClient interface {
+	GetAccountIndex() int64
+	GetCreateOrderTransaction(tx *types.CreateOrderTxReq, ops *types.TransactOpts) (*txtypes.L2CreateOrderTxInfo, error)
+	GetCancelOrderTransaction(tx *types.CancelOrderTxReq, ops *types.TransactOpts) (*txtypes.L2CancelOrderTxInfo, error)
+	GetCancelAllOrdersTransaction(tx *types.CancelAllOrdersTxReq, ops *types.TransactOpts) (*txtypes.L2CancelAllOrdersTxInfo, error)
+	GetModifyOrderTransaction(tx *types.ModifyOrderTxReq, ops *types.TransactOpts) (*txtypes.L2ModifyOrderTxInfo, error)
+	Send(ctx context.Context, info txtypes.TxInfo, priceProtection *bool) (*lighterapi.RespSendTx, error)
+	SendRawTx(ctx context.Context, info txtypes.TxInfo, priceProtection *bool) (string, error)
+	SendBatch(ctx context.Context, infos []txtypes.TxInfo) (*lighterapi.RespSendTxBatch, error)
+}
+
+var (
+	_ OrderClient                     = (*TxClient)(nil)
+	_ OrderClient                     = (*PaperTxClient)(nil)
+	_ LighterWebsocketPrivateServiceI = (*PaperTxClient)(nil)
+)
+
+// PaperOption configures a PaperTxClient
+type PaperOption func(*PaperTxClient)
+
+// WithPaperCollateral sets the starting USDC collateral (default 0)
+func WithPaperCollateral(usdc float64) PaperOption {
+	return func(p *PaperTxClient) { p.collateral = usdc }
+}
+
+// WithPaperMarket preloads market details so the client does not fetch them over REST
+func WithPaperMarket(detail *lighterapi.OrderBookDetail) PaperOption {
+	return func(p *PaperTxClient) { p.markets[detail.MarketId] = detail }
+}
+
+// WithPaperClock replaces time.Now for event timestamps, e.g. with a simulated clock
+func WithPaperClock(now func() time.Time) PaperOption {
+	return func(p *PaperTxClient) { p.now = now }
+}
+
+// PaperTxClient builds transactions like TxClient but executes them locally against the live
+// order book instead of sending them. Incoming orders take liquidity from the cached book;
+// resting orders join the back of the queue at their price and fill when trades consume the
+// size ahead of them or the book moves through them. Fees come from the market's maker and
+// taker fees. Order and account events are delivered through the same callbacks as
+// LighterWebsocketPrivateService.
+//
+// Feed market data through HandleOrderBook and HandleTrades, passing them as the
+// SubscribeOrderBook and SubscribeTrades callbacks.
+type PaperTxClient struct {
+	*TxClient
+	book  *OrderBookCache
+	cache *AccountPositionCache
+	now   func() time.Time
+
+	mu         sync.Mutex
+	nonce      int64
+	nextOrder  int64
+	nextTrade  int64
+	seq        int64
+	collateral float64
+	markets    map[uint8]*lighterapi.OrderBookDetail
+	orders     map[int64]*paperOrder
+	positions  map[uint8]*paperPosition
+	taken      map[paperLevel]int64
+
+	subMu       sync.RWMutex
+	subID       int
+	accountSubs map[int]func(LighterAccountResponse) error
+	orderSubs   map[int]func(LighterOrdersResponse) error
+	onError     ErrHandler
+}
+
+type paperOrder struct {
+	index       int64
+	clientIndex int64
+	market      uint8
+	isAsk       bool
+	price       uint32
+	initial     int64
+	remaining   int64
+	typ         uint8
+	tif         uint8
+	reduceOnly  bool
+	// queue is the live size resting ahead of the order at its price
+	queue int64
+	seq   int64
+}
+
+type paperPosition struct {
+	size     int64 // signed base amount
+	entry    float64
+	realized float64
+}
+
+// paperLevel identifies live liquidity already consumed by paper orders since the last
+// book update of the market
+type paperLevel struct {
+	market uint8
+	isAsk  bool
+	price  float64
+}
+
+// paperEvents collects the callbacks produced while the state lock is held
+type paperEvents struct {
+	orders  []LighterOrdersResponse
+	trades  map[uint8][]WSTrade
+	account *LighterAccountResponse
+}
+
+// NewPaperTxClient creates a paper trading client for accountIndex. Transactions are signed
+// with a throwaway key and local nonces; api is only used to fetch market details that were
+// not preloaded with WithPaperMarket and may be nil when every traded market is preloaded.
+func NewPaperTxClient(api *Client, book *OrderBookCache, accountIndex int64, opts ...PaperOption) (*PaperTxClient, error) {
+	if book == nil {
+		return nil, fmt.Errorf("client: order book cache is required")
+	}
+	key := make([]byte, 40)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("client: generate paper key: %w", err)
+	}
+	keyManager, err := signer.NewKeyManager(key)
+	if err != nil {
+		return nil, fmt.Errorf("client: create key manager: %w", err)
+	}
+	tx := &TxClient{api: api, keyManager: keyManager, accountIndex: accountIndex}
+	p := &PaperTxClient{
+		TxClient:    tx,
+		book:        book,
+		cache:       NewAccountPositionCache(),
+		now:         time.Now,
+		nextOrder:   txtypes.MinOrderIndex,
+		nextTrade:   1,
+		markets:     make(map[uint8]*lighterapi.OrderBookDetail),
+		orders:      make(map[int64]*paperOrder),
+		positions:   make(map[uint8]*paperPosition),
+		taken:       make(map[paperLevel]int64),
+		accountSubs: make(map[int]func(LighterAccountResponse) error),
+		orderSubs:   make(map[int]func(LighterOrdersResponse) error),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	tx.SetNonceSource(p.nextNonce)
+	tx.SetPositionSource(p.cache.Source())
+	return p, nil
+}
+
+func (p *PaperTxClient) nextNonce(_ context.Context, _ int64, _ uint8) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	nonce := p.nonce
+	p.nonce++
+	return nonce, nil
+}
+
+// Collateral returns the simulated USDC collateral, including realized PnL net of fees
+func (p *PaperTxClient) Collateral() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.collateral
+}
+
+// AdjustCollateral credits usdc to the simulated collateral, or debits it when negative, as
+// funding payments do, and publishes the new balance to account subscribers
+func (p *PaperTxClient) AdjustCollateral(usdc float64) {
+	p.mu.Lock()
+	p.collateral += usdc
+	resp := p.accountSnapshot()
+	resp.IsSnapshot = false
+	resp.RawAccountUpdate.Type = MessageTypeAccountUpdate
+	p.mu.Unlock()
+	p.publish(&paperEvents{account: &resp})
+}
+
+// Position returns the simulated position of a market in base units, negative when short,
+// and its average entry price
+func (p *PaperTxClient) Position(marketId uint8) (size, entry float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pos, ok := p.positions[marketId]
+	if !ok {
+		return 0, 0
+	}
+	return BaseToFloat(p.markets[marketId], pos.size), pos.entry
+}
+
+// OpenOrders returns the resting paper orders of a market in priority order
+func (p *PaperTxClient) OpenOrders(marketId uint8) []LighterOrdersResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []LighterOrdersResponse
+	for _, o := range p.sortedOrders(int(marketId)) {
+		out = append(out, p.orderEvent(o, lighterapi.OrderStatusOpen, true))
+	}
+	return out
+}
+
+// Send executes a transaction locally. Order, grouped order, cancel, cancel all and modify
+// transactions are supported.
+func (p *PaperTxClient) Send(ctx context.Context, info txtypes.TxInfo, _ *bool) (*lighterapi.RespSendTx, error) {
+	if err := p.loadMarkets(ctx, info); err != nil {
+		return nil, err
+	}
+	hash, err := info.Hash(p.chainID)
+	if err != nil {
+		return nil, err
+	}
+	txHash := hex.EncodeToString(hash)
+
+	p.mu.Lock()
+	ev := &paperEvents{}
+	err = p.execute(info, txHash, ev)
+	p.accountEvent(ev)
+	p.mu.Unlock()
+
+	p.publish(ev)
+	if err != nil {
+		return nil, err
+	}
+	return &lighterapi.RespSendTx{Code: 200, TxHash: txHash}, nil
+}
+
+func (p *PaperTxClient) SendRawTx(ctx context.Context, info txtypes.TxInfo, priceProtection *bool) (string, error) {
+	resp, err := p.Send(ctx, info, priceProtection)
+	if err != nil {
+		return "", err
+	}
+	return resp.TxHash, nil
+}
+
+// SendBatch executes the transactions in order and stops at the first one that fails
+func (p *PaperTxClient) SendBatch(ctx context.Context, infos []txtypes.TxInfo) (*lighterapi.RespSendTxBatch, error) {
+	if p.risk != nil {
+		if err := p.risk.CheckBatch(infos); err != nil {
+			return nil, err
+		}
+	}
+	resp := &lighterapi.RespSendTxBatch{Code: 200}
+	for _, info := range infos {
+		hash, err := p.SendRawTx(ctx, info, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp.TxHash = append(resp.TxHash, hash)
+	}
+	return resp, nil
+}
+
+// HandleOrderBook updates the cached book and fills resting paper orders the book moved
+// through. Pass it as the SubscribeOrderBook callback instead of OrderBookCache.Handle.
+func (p *PaperTxClient) HandleOrderBook(resp LighterOrderBookResponse) error {
+	if err := p.book.Handle(resp); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	ev := &paperEvents{}
+	for level := range p.taken {
+		if level.market == resp.MarketId {
+			delete(p.taken, level)
+		}
+	}
+	if d := p.markets[resp.MarketId]; d != nil {
+		for _, o := range p.sortedOrders(int(resp.MarketId)) {
+			before := o.remaining
+			p.take(o, d, true, "", ev)
+			if o.remaining == 0 {
+				continue
+			}
+			if o.remaining < before {
+				ev.orders = append(ev.orders, p.orderEvent(o, lighterapi.OrderStatusOpen, false))
+			}
+			if live := p.liveSize(o, d); live < o.queue {
+				o.queue = live
+			}
+		}
+	}
+	p.accountEvent(ev)
+	p.mu.Unlock()
+
+	p.publish(ev)
+	return nil
+}
+
+// HandleTrades fills resting paper orders from public trades. Volume at an order's price first
+// consumes the queue ahead of it; trades through its price fill it directly. Pass it as the
+// SubscribeTrades callback.
+func (p *PaperTxClient) HandleTrades(resp LighterTradesResponse) error {
+	p.mu.Lock()
+	d := p.markets[resp.MarketId]
+	if d == nil {
+		p.mu.Unlock()
+		return nil
+	}
+	ev := &paperEvents{}
+	price := parseDecimal(resp.Price)
+	left := BaseFromFloat(d, parseDecimal(resp.Quantity))
+	// A taker buy lifts asks and a taker sell hits bids
+	hitAsks := resp.Side == "buy"
+	for _, o := range p.sortedOrders(int(resp.MarketId)) {
+		if left <= 0 {
+			break
+		}
+		if o.isAsk != hitAsks {
+			continue
+		}
+		own := PriceToFloat(d, o.price)
+		if (o.isAsk && price < own) || (!o.isAsk && price > own) {
+			continue
+		}
+		if price == own {
+			ahead := min(o.queue, left)
+			o.queue -= ahead
+			left -= ahead
+		}
+		qty := min(o.remaining, left)
+		if qty <= 0 {
+			continue
+		}
+		left -= qty
+		p.fill(o, d, qty, own, true, "", ev)
+		if o.remaining == 0 {
+			p.finish(o, lighterapi.OrderStatusFilled, ev)
+		} else {
+			ev.orders = append(ev.orders, p.orderEvent(o, lighterapi.OrderStatusOpen, false))
+		}
+	}
+	p.accountEvent(ev)
+	p.mu.Unlock()
+
+	p.publish(ev)
+	return nil
+}
+
+// Start implements LighterWebsocketPrivateServiceI; callback errors are passed to errHandler
+func (p *PaperTxClient) Start(_ context.Context, errHandler ErrHandler) error {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	p.onError = errHandler
+	return nil
+}
+
+// Close implements LighterWebsocketPrivateServiceI and drops every subscription
+func (p *PaperTxClient) Close() error {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	p.accountSubs = make(map[int]func(LighterAccountResponse) error)
+	p.orderSubs = make(map[int]func(LighterOrdersResponse) error)
+	return nil
+}
+
+// SubscribeAccount implements LighterWebsocketPrivateServiceI. The callback receives a
+// snapshot right away and an update after every paper fill.
+func (p *PaperTxClient) SubscribeAccount(
+	param LighterAccountParamKey,
+	callback func(LighterAccountResponse) error,
+) (func() error, error) {
+	if param.AccountId != p.accountIndex {
+		return nil, fmt.Errorf("client: paper client trades account %d, not %d", p.accountIndex, param.AccountId)
+	}
+	p.subMu.Lock()
+	id := p.subID
+	p.subID++
+	p.accountSubs[id] = callback
+	p.subMu.Unlock()
+
+	p.mu.Lock()
+	snapshot := p.accountSnapshot()
+	p.mu.Unlock()
+	p.report(callback(snapshot))
+
+	return func() error {
+		p.subMu.Lock()
+		defer p.subMu.Unlock()
+		delete(p.accountSubs, id)
+		return nil
+	}, nil
+}
+
+// SubscribeOrders implements LighterWebsocketPrivateServiceI. The callback receives the open
+// orders as snapshots right away and every paper order change afterwards.
+func (p *PaperTxClient) SubscribeOrders(
+	param LighterOrdersParamKey,
+	callback func(LighterOrdersResponse) error,
+) (func() error, error) {
+	if param.AccountId != p.accountIndex {
+		return nil, fmt.Errorf("client: paper client trades account %d, not %d", p.accountIndex, param.AccountId)
+	}
+	p.subMu.Lock()
+	id := p.subID
+	p.subID++
+	p.orderSubs[id] = callback
+	p.subMu.Unlock()
+
+	p.mu.Lock()
+	var open []LighterOrdersResponse
+	for _, o := range p.sortedOrders(anyMarket) {
+		open = append(open, p.orderEvent(o, lighterapi.OrderStatusOpen, true))
+	}
+	p.mu.Unlock()
+	for _, o := range open {
+		p.report(callback(o))
+	}
+
+	return func() error {
+		p.subMu.Lock()
+		defer p.subMu.Unlock()
+		delete(p.orderSubs, id)
+		return nil
+	}, nil
+}
+
+// loadMarkets fetches details for the markets a transaction touches
+func (p *PaperTxClient) loadMarkets(ctx context.Context, info txtypes.TxInfo) error {
+	var markets []uint8
+	switch tx := info.(type) {
+	case *txtypes.L2CreateOrderTxInfo:
+		markets = append(markets, tx.MarketIndex)
+	case *txtypes.L2CreateGroupedOrdersTxInfo:
+		for _, o := range tx.Orders {
+			markets = append(markets, o.MarketIndex)
+		}
+	case *txtypes.L2ModifyOrderTxInfo:
+		markets = append(markets, tx.MarketIndex)
+	}
+	for _, market := range markets {
+		p.mu.Lock()
+		_, ok := p.markets[market]
+		p.mu.Unlock()
+		if ok {
+			continue
+		}
+		if p.api == nil {
+			return fmt.Errorf("client: market %d details were not preloaded", market)
+		}
+		detail, err := p.api.OrderBookDetail(ctx, market)
+		if err != nil {
+			return err
+		}
+		p.mu.Lock()
+		p.markets[market] = detail
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+func (p *PaperTxClient) execute(info txtypes.TxInfo, txHash string, ev *paperEvents) error {
+	switch tx := info.(type) {
+	case *txtypes.L2CreateOrderTxInfo:
+		return p.createOrder(tx.OrderInfo, txHash, ev)
+	case *txtypes.L2CreateGroupedOrdersTxInfo:
+		for _, o := range tx.Orders {
+			if err := p.createOrder(o, txHash, ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *txtypes.L2CancelOrderTxInfo:
+		o, err := p.lookup(tx.MarketIndex, tx.Index)
+		if err != nil {
+			return err
+		}
+		p.finish(o, lighterapi.OrderStatusCanceled, ev)
+		return nil
+	case *txtypes.L2CancelAllOrdersTxInfo:
+		if tx.TimeInForce != txtypes.ImmediateCancelAll {
+			return fmt.Errorf("client: paper trading only supports immediate cancel all")
+		}
+		for _, o := range p.sortedOrders(anyMarket) {
+			p.finish(o, lighterapi.OrderStatusCanceled, ev)
+		}
+		return nil
+	case *txtypes.L2ModifyOrderTxInfo:
+		o, err := p.lookup(tx.MarketIndex, tx.Index)
+		if err != nil {
+			return err
+		}
+		return p.modify(o, tx.BaseAmount, tx.Price, txHash, ev)
+	}
+	return fmt.Errorf("client: paper trading does not support tx type %d", info.GetTxType())
+}
+
+func (p *PaperTxClient) createOrder(info *txtypes.OrderInfo, txHash string, ev *paperEvents) error {
+	if info == nil {
+		return fmt.Errorf("client: order is missing")
+	}
+	if info.Type != txtypes.LimitOrder && info.Type != txtypes.MarketOrder {
+		return fmt.Errorf("client: paper trading supports limit and market orders only")
+	}
+	d := p.markets[info.MarketIndex]
+	o := &paperOrder{
+		index:       p.nextOrder,
+		clientIndex: info.ClientOrderIndex,
+		market:      info.MarketIndex,
+		isAsk:       info.IsAsk == 1,
+		price:       info.Price,
+		initial:     info.BaseAmount,
+		remaining:   info.BaseAmount,
+		typ:         info.Type,
+		tif:         info.TimeInForce,
+		reduceOnly:  info.ReduceOnly == 1,
+	}
+	p.nextOrder++
+
+	if o.reduceOnly {
+		pos := p.positions[o.market]
+		if pos == nil || pos.size == 0 || (pos.size > 0) != o.isAsk {
+			p.finish(o, lighterapi.OrderStatusCanceledReduceOnly, ev)
+			return nil
+		}
+		o.remaining = min(o.remaining, abs64(pos.size))
+	}
+	return p.enter(o, d, txHash, ev)
+}
+
+// enter matches an incoming or repriced order and rests what is left
+func (p *PaperTxClient) enter(o *paperOrder, d *lighterapi.OrderBookDetail, txHash string, ev *paperEvents) error {
+	if o.tif == txtypes.PostOnly && o.typ == txtypes.LimitOrder && p.crossesBook(o, d) {
+		p.finish(o, lighterapi.OrderStatusCanceledPostOnly, ev)
+		return nil
+	}
+	p.take(o, d, false, txHash, ev)
+	switch {
+	case o.remaining == 0:
+		p.finish(o, lighterapi.OrderStatusFilled, ev)
+	case o.typ == txtypes.MarketOrder || o.tif == txtypes.ImmediateOrCancel:
+		p.finish(o, lighterapi.OrderStatusCanceled, ev)
+	default:
+		p.seq++
+		o.seq = p.seq
+		o.queue = p.liveSize(o, d)
+		p.orders[o.index] = o
+		ev.orders = append(ev.orders, p.orderEvent(o, lighterapi.OrderStatusOpen, false))
+	}
+	return nil
+}
+
+func (p *PaperTxClient) modify(o *paperOrder, baseAmount int64, price uint32, txHash string, ev *paperEvents) error {
+	filled := o.initial - o.remaining
+	if baseAmount <= filled {
+		return fmt.Errorf("client: modified size %d is not above the filled size %d", baseAmount, filled)
+	}
+	d := p.markets[o.market]
+	// Keep queue priority only when the price is unchanged and the size does not grow
+	if price == o.price && baseAmount <= o.initial {
+		o.initial = baseAmount
+		o.remaining = baseAmount - filled
+		ev.orders = append(ev.orders, p.orderEvent(o, lighterapi.OrderStatusOpen, false))
+		return nil
+	}
+	delete(p.orders, o.index)
+	o.price = price
+	o.initial = baseAmount
+	o.remaining = baseAmount - filled
+	return p.enter(o, d, txHash, ev)
+}
+
+func (p *PaperTxClient) lookup(market uint8, index int64) (*paperOrder, error) {
+	if o, ok := p.orders[index]; ok && o.market == market {
+		return o, nil
+	}
+	for _, o := range p.orders {
+		if o.market == market && o.clientIndex == index {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("client: paper order %d not found on market %d", index, market)
+}
+
+// crossesBook reports whether an order would trade against the best opposite level
+func (p *PaperTxClient) crossesBook(o *paperOrder, d *lighterapi.OrderBookDetail) bool {
+	best, ok := p.book.best(o.market, !o.isAsk)
+	return ok && crosses(o, d, best.Price)
+}
+
+func crosses(o *paperOrder, d *lighterapi.OrderBookDetail, level float64) bool {
+	if o.typ == txtypes.MarketOrder && o.price == 0 {
+		return true
+	}
+	price := PriceToFloat(d, o.price)
+	if o.isAsk {
+		return level >= price
+	}
+	return level <= price
+}
+
+// take fills o against opposite live levels it crosses. Resting orders crossed by the book
+// fill as makers at their own price; incoming orders take at each level's price.
+func (p *PaperTxClient) take(o *paperOrder, d *lighterapi.OrderBookDetail, maker bool, txHash string, ev *paperEvents) {
+	levels := p.book.Asks(o.market)
+	if o.isAsk {
+		levels = p.book.Bids(o.market)
+	}
+	for _, level := range levels {
+		if o.remaining == 0 || !crosses(o, d, level.Price) {
+			break
+		}
+		key := paperLevel{market: o.market, isAsk: !o.isAsk, price: level.Price}
+		available := BaseFromFloat(d, level.Size) - p.taken[key]
+		qty := min(o.remaining, available)
+		if qty <= 0 {
+			continue
+		}
+		p.taken[key] += qty
+		price := level.Price
+		if maker {
+			price = PriceToFloat(d, o.price)
+		}
+		p.fill(o, d, qty, price, maker, txHash, ev)
+	}
+	if maker && o.remaining == 0 {
+		p.finish(o, lighterapi.OrderStatusFilled, ev)
+	}
+}
+
+// liveSize is the live size resting at the order's price on its own side
+func (p *PaperTxClient) liveSize(o *paperOrder, d *lighterapi.OrderBookDetail) int64 {
+	levels := p.book.Bids(o.market)
+	if o.isAsk {
+		levels = p.book.Asks(o.market)
+	}
+	price := PriceToFloat(d, o.price)
+	for _, level := range levels {
+		if level.Price == price {
+			return BaseFromFloat(d, level.Size)
+		}
+	}
+	return 0
+}
+
+// fill books a paper trade: it updates the order and position, charges the fee and records
+// the trade for the next account event
+func (p *PaperTxClient) fill(o *paperOrder, d *lighterapi.OrderBookDetail, qty int64, price float64, maker bool, txHash string, ev *paperEvents) {
+	o.remaining -= qty
+	size := BaseToFloat(d, qty)
+	notional := size * price
+	makerRate := parseDecimal(d.MakerFee) / 100
+	takerRate := parseDecimal(d.TakerFee) / 100
+	rate := takerRate
+	if maker {
+		rate = makerRate
+	}
+
+	pos := p.positions[o.market]
+	if pos == nil {
+		pos = &paperPosition{}
+		p.positions[o.market] = pos
+	}
+	delta := qty
+	if o.isAsk {
+		delta = -qty
+	}
+	realized := pos.apply(delta, d, price)
+	p.collateral += realized - notional*rate
+
+	trade := WSTrade{
+		TradeId:    p.nextTrade,
+		TxHash:     txHash,
+		Type:       "trade",
+		MarketId:   int(o.market),
+		Size:       strconv.FormatFloat(size, 'f', int(d.SizeDecimals), 64),
+		Price:      strconv.FormatFloat(price, 'f', int(d.PriceDecimals), 64),
+		UsdAmount:  strconv.FormatFloat(notional, 'f', -1, 64),
+		IsMakerAsk: o.isAsk == maker,
+		Timestamp:  p.now().UnixMilli(),
+		MakerFee:   int(math.Round(makerRate * float64(txtypes.FeeTick))),
+		TakerFee:   int(math.Round(takerRate * float64(txtypes.FeeTick))),
+	}
+	if o.isAsk {
+		trade.AskId, trade.AskAccountId = o.index, p.accountIndex
+	} else {
+		trade.BidId, trade.BidAccountId = o.index, p.accountIndex
+	}
+	p.nextTrade++
+	if ev.trades == nil {
+		ev.trades = make(map[uint8][]WSTrade)
+	}
+	ev.trades[o.market] = append(ev.trades[o.market], trade)
+}
+
+// apply adds a signed base amount at price and returns the PnL realized by any reduction
+func (pos *paperPosition) apply(delta int64, d *lighterapi.OrderBookDetail, price float64) float64 {
+	if pos.size == 0 || (pos.size > 0) == (delta > 0) {
+		total := abs64(pos.size) + abs64(delta)
+		pos.entry = (pos.entry*float64(abs64(pos.size)) + price*float64(abs64(delta))) / float64(total)
+		pos.size += delta
+		return 0
+	}
+	closed := min(abs64(delta), abs64(pos.size))
+	realized := BaseToFloat(d, closed) * (price - pos.entry)
+	if pos.size < 0 {
+		realized = -realized
+	}
+	pos.realized += realized
+	pos.size += delta
+	switch {
+	case pos.size == 0:
+		pos.entry = 0
+	case (pos.size > 0) == (delta > 0):
+		// The fill flipped the position; the remainder opened at price
+		pos.entry = price
+	}
+	return realized
+}
+
+// finish removes an order from the book and reports its final status
+func (p *PaperTxClient) finish(o *paperOrder, status lighterapi.OrderStatus, ev *paperEvents) {
+	delete(p.orders, o.index)
+	ev.orders = append(ev.orders, p.orderEvent(o, status, false))
+}
+
+func (p *PaperTxClient) orderEvent(o *paperOrder, status lighterapi.OrderStatus, snapshot bool) LighterOrdersResponse {
+	d := p.markets[o.market]
+	isAsk := uint8(0)
+	if o.isAsk {
+		isAsk = 1
+	}
+	return LighterOrdersResponse{
+		AccountId:        p.accountIndex,
+		OrderId:          strconv.FormatInt(o.index, 10),
+		ClientOrderIndex: o.clientIndex,
+		MarketId:         o.market,
+		Status:           string(status),
+		BaseQuantity:     strconv.FormatFloat(BaseToFloat(d, o.initial), 'f', int(d.SizeDecimals), 64),
+		FilledQuantity:   strconv.FormatFloat(BaseToFloat(d, o.initial-o.remaining), 'f', int(d.SizeDecimals), 64),
+		Price:            strconv.FormatFloat(PriceToFloat(d, o.price), 'f', int(d.PriceDecimals), 64),
+		IsAsk:            isAsk,
+		Timestamp:        p.now().UnixMilli(),
+		IsSnapshot:       snapshot,
+	}
+}
+
+// anyMarket selects every market in sortedOrders
+const anyMarket = -1
+
+// sortedOrders returns resting orders of a market (or anyMarket) with better prices first and
+// earlier orders first within a price
+func (p *PaperTxClient) sortedOrders(market int) []*paperOrder {
+	orders := make([]*paperOrder, 0, len(p.orders))
+	for _, o := range p.orders {
+		if market == anyMarket || int(o.market) == market {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		a, b := orders[i], orders[j]
+		if a.market != b.market || a.isAsk != b.isAsk {
+			if a.market != b.market {
+				return a.market < b.market
+			}
+			return !a.isAsk
+		}
+		if a.price != b.price {
+			if a.isAsk {
+				return a.price < b.price
+			}
+			return a.price > b.price
+		}
+		return a.seq < b.seq
+	})
+	return orders
+}
+
+// accountEvent builds the account update for the markets that traded
+func (p *PaperTxClient) accountEvent(ev *paperEvents) {
+	if len(ev.trades) == 0 {
+		return
+	}
+	update := &WSAccountUpdate{
+		Account:   p.accountIndex,
+		Channel:   fmt.Sprintf("account_all:%d", p.accountIndex),
+		Type:      MessageTypeAccountUpdate,
+		Positions: make(map[string]*WSPosition),
+		Trades:    make(map[string][]WSTrade),
+	}
+	for market, trades := range ev.trades {
+		key := strconv.Itoa(int(market))
+		update.Positions[key] = p.wsPosition(market)
+		update.Trades[key] = trades
+	}
+	ev.account = p.accountResponse(update, false)
+}
+
+func (p *PaperTxClient) accountSnapshot() LighterAccountResponse {
+	update := &WSAccountUpdate{
+		Account:   p.accountIndex,
+		Channel:   fmt.Sprintf("account_all:%d", p.accountIndex),
+		Type:      MessageTypeAccountSubscribed,
+		Positions: make(map[string]*WSPosition),
+		Trades:    make(map[string][]WSTrade),
+	}
+	for market := range p.positions {
+		update.Positions[strconv.Itoa(int(market))] = p.wsPosition(market)
+	}
+	return *p.accountResponse(update, true)
+}
+
+func (p *PaperTxClient) accountResponse(update *WSAccountUpdate, snapshot bool) *LighterAccountResponse {
+	resp := &LighterAccountResponse{
+		AccountId:        p.accountIndex,
+		AvailableBalance: strconv.FormatFloat(p.collateral, 'f', 6, 64),
+		Timestamp:        p.now().UnixMilli(),
+		IsSnapshot:       snapshot,
+		RawAccountUpdate: update,
+	}
+	for _, pos := range update.Positions {
+		resp.MarketStats = append(resp.MarketStats, AccountMarketStats{
+			MarketId:       pos.MarketId,
+			OpenOrderCount: int64(pos.OpenOrderCount),
+			Sign:           pos.Sign,
+			Position:       pos.Position,
+			AvgEntryPrice:  pos.AvgEntryPrice,
+			PositionValue:  pos.PositionValue,
+			UnrealizedPnl:  pos.UnrealizedPnl,
+			RealizedPnl:    pos.RealizedPnl,
+		})
+	}
+	sort.Slice(resp.MarketStats, func(i, j int) bool { return resp.MarketStats[i].MarketId < resp.MarketStats[j].MarketId })
+	return resp
+}
+
+// wsPosition reports a paper position the way the account stream does, marked at the book mid
+func (p *PaperTxClient) wsPosition(market uint8) *WSPosition {
+	d := p.markets[market]
+	pos := p.positions[market]
+	size := BaseToFloat(d, abs64(pos.size))
+	mark := pos.entry
+	bid, okBid := p.book.BestBid(market)
+	ask, okAsk := p.book.BestAsk(market)
+	if okBid && okAsk {
+		mark = (bid.Price + ask.Price) / 2
+	}
+	var sign int8
+	unrealized := size * (mark - pos.entry)
+	switch {
+	case pos.size > 0:
+		sign = 1
+	case pos.size < 0:
+		sign = -1
+		unrealized = -unrealized
+	}
+	open := 0
+	for _, o := range p.orders {
+		if o.market == market {
+			open++
+		}
+	}
+	return &WSPosition{
+		MarketId:       market,
+		Symbol:         d.Symbol,
+		OpenOrderCount: open,
+		Sign:           sign,
+		Position:       strconv.FormatFloat(size, 'f', int(d.SizeDecimals), 64),
+		AvgEntryPrice:  strconv.FormatFloat(pos.entry, 'f', int(d.PriceDecimals), 64),
+		PositionValue:  strconv.FormatFloat(size*mark, 'f', 6, 64),
+		UnrealizedPnl:  strconv.FormatFloat(unrealized, 'f', 6, 64),
+		RealizedPnl:    strconv.FormatFloat(pos.realized, 'f', 6, 64),
+	}
+}
+
+// publish delivers collected events outside the state lock, so callbacks may trade
+func (p *PaperTxClient) publish(ev *paperEvents) {
+	if ev.account != nil {
+		p.cache.Handle(*ev.account)
+	}
+	p.subMu.RLock()
+	orderSubs := make([]func(LighterOrdersResponse) error, 0, len(p.orderSubs))
+	for _, cb := range p.orderSubs {
+		orderSubs = append(orderSubs, cb)
+	}
+	accountSubs := make([]func(LighterAccountResponse) error, 0, len(p.accountSubs))
+	for _, cb := range p.accountSubs {
+		accountSubs = append(accountSubs, cb)
+	}
+	p.subMu.RUnlock()
+
+	for _, o := range ev.orders {
+		for _, cb := range orderSubs {
+			p.report(cb(o))
+		}
+	}
+	if ev.account != nil {
+		for _, cb := range accountSubs {
+			p.report(cb(*ev.account))
+		}
+	}
+}
+
+func (p *PaperTxClient) report(err error) {
+	if err == nil {
+		return
+	}
+	p.subMu.RLock()
+	onError := p.onError
+	p.subMu.RUnlock()
+	if onError != nil {
+		onError(err)
+	}
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,210 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// paperMarket charges 2 bps to makers and 5 bps to takers; fees are in percent as the API
+// reports them
+var paperMarket = &lighterapi.OrderBookDetail{
+	MarketId:      0,
+	Symbol:        "ETH",
+	PriceDecimals: 2,
+	SizeDecimals:  4,
+	MinBaseAmount: "0.001",
+	MakerFee:      "0.02",
+	TakerFee:      "0.05",
+}
+
+// newPaper returns a paper client on a 2999/3001 book with one ETH on each side, and the
+// statuses of every order update it publishes
+func newPaper(t *testing.T) (*client.PaperTxClient, *[]string) {
+	t.Helper()
+	paper, err := client.NewPaperTxClient(nil, client.NewOrderBookCache(), testAccount,
+		client.WithPaperMarket(paperMarket), client.WithPaperCollateral(10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setBook(t, paper, "2999.00", "3001.00")
+	var statuses []string
+	if _, err := paper.SubscribeOrders(client.LighterOrdersParamKey{AccountId: testAccount}, func(o client.LighterOrdersResponse) error {
+		if !o.IsSnapshot {
+			statuses = append(statuses, o.Status)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return paper, &statuses
+}
+
+func setBook(t *testing.T, paper *client.PaperTxClient, bid, ask string) {
+	t.Helper()
+	if err := paper.HandleOrderBook(client.LighterOrderBookResponse{
+		MarketId:   0,
+		Bids:       []client.PriceLevel{{Price: bid, Quantity: "1.0000"}},
+		Asks:       []client.PriceLevel{{Price: ask, Quantity: "1.0000"}},
+		IsSnapshot: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func paperOrder(t *testing.T, paper *client.PaperTxClient, isAsk bool, price uint32, base int64, tif uint8) {
+	t.Helper()
+	req := limitOrder(0, isAsk, price, base)
+	req.TimeInForce = tif
+	if tif == txtypes.ImmediateOrCancel {
+		req.OrderExpiry = txtypes.NilOrderExpiry
+	}
+	info, err := paper.GetCreateOrderTransaction(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := paper.Send(context.Background(), info, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func publicTrade(t *testing.T, paper *client.PaperTxClient, side, price, quantity string) {
+	t.Helper()
+	if err := paper.HandleTrades(client.LighterTradesResponse{MarketId: 0, Price: price, Quantity: quantity, Side: side, Timestamp: time.Now().UnixMilli()}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectPosition(t *testing.T, paper *client.PaperTxClient, size, entry float64) {
+	t.Helper()
+	if gotSize, gotEntry := paper.Position(0); !approx(gotSize, size) || !approx(gotEntry, entry) {
+		t.Fatalf("position %v @ %v, want %v @ %v", gotSize, gotEntry, size, entry)
+	}
+}
+
+func TestPaperQueuePosition(t *testing.T) {
+	paper, statuses := newPaper(t)
+	// Joins behind the one ETH already bid at 2999
+	paperOrder(t, paper, false, 299900, 5000, txtypes.GoodTillTime)
+
+	publicTrade(t, paper, "sell", "2999.00", "0.8")
+	expectPosition(t, paper, 0, 0)
+
+	// 0.2 clears the queue ahead, the other 0.3 fills the order
+	publicTrade(t, paper, "sell", "2999.00", "0.5")
+	expectPosition(t, paper, 0.3, 2999)
+
+	// A trade through the price fills the rest at the order's own price
+	publicTrade(t, paper, "sell", "2998.00", "1")
+	expectPosition(t, paper, 0.5, 2999)
+	if open := paper.OpenOrders(0); len(open) != 0 {
+		t.Fatalf("%d orders still open", len(open))
+	}
+	if want := 10000 - 0.5*2999*0.0002; !approx(paper.Collateral(), want) {
+		t.Fatalf("collateral %v, want %v after maker fees", paper.Collateral(), want)
+	}
+	want := []string{"open", "open", "filled"}
+	if len(*statuses) != len(want) {
+		t.Fatalf("order updates %v, want %v", *statuses, want)
+	}
+	for i := range want {
+		if (*statuses)[i] != want[i] {
+			t.Fatalf("order updates %v, want %v", *statuses, want)
+		}
+	}
+}
+
+func TestPaperBookMovesThroughRestingOrder(t *testing.T) {
+	paper, _ := newPaper(t)
+	paperOrder(t, paper, false, 299900, 1000, txtypes.GoodTillTime)
+
+	// The ask drops below the bid; the resting buy fills as a maker at 2999
+	setBook(t, paper, "2997.00", "2998.50")
+	expectPosition(t, paper, 0.1, 2999)
+	if want := 10000 - 0.1*2999*0.0002; !approx(paper.Collateral(), want) {
+		t.Fatalf("collateral %v, want %v", paper.Collateral(), want)
+	}
+}
+
+func TestPaperBookMovePartiallyFillsRestingOrder(t *testing.T) {
+	paper, statuses := newPaper(t)
+	paperOrder(t, paper, false, 299900, 15000, txtypes.GoodTillTime)
+
+	// One ETH is offered through the bid; half an ETH stays resting
+	setBook(t, paper, "2997.00", "2998.50")
+	expectPosition(t, paper, 1, 2999)
+	open := paper.OpenOrders(0)
+	if len(open) != 1 || open[0].FilledQuantity != "1.0000" {
+		t.Fatalf("open orders %+v, want 1 of 1.5 filled and the rest resting", open)
+	}
+	// The order is reported open when placed and again after the partial fill
+	if len(*statuses) != 2 || (*statuses)[1] != string(lighterapi.OrderStatusOpen) {
+		t.Fatalf("order updates %v, want an open update for the partial fill", *statuses)
+	}
+
+	// A book that no longer crosses fills nothing and sends nothing
+	setBook(t, paper, "2997.00", "2999.50")
+	if len(*statuses) != 2 {
+		t.Fatalf("order updates %v, want no update without a fill", *statuses)
+	}
+}
+
+func TestPaperPostOnlyCrossingIsCanceled(t *testing.T) {
+	paper, statuses := newPaper(t)
+	paperOrder(t, paper, false, 300100, 1000, txtypes.PostOnly)
+	expectPosition(t, paper, 0, 0)
+	if len(*statuses) != 1 || (*statuses)[0] != string(lighterapi.OrderStatusCanceledPostOnly) {
+		t.Fatalf("order updates %v, want a post-only cancel", *statuses)
+	}
+
+	paperOrder(t, paper, false, 300000, 1000, txtypes.PostOnly)
+	if open := paper.OpenOrders(0); len(open) != 1 {
+		t.Fatalf("%d orders open, want the non-crossing post-only order", len(open))
+	}
+}
+
+func TestPaperIOCTakesLiquidityOnce(t *testing.T) {
+	paper, statuses := newPaper(t)
+	paperOrder(t, paper, false, 300100, 15000, txtypes.ImmediateOrCancel)
+	expectPosition(t, paper, 1, 3001)
+	if want := 10000 - 3001*0.0005; !approx(paper.Collateral(), want) {
+		t.Fatalf("collateral %v, want %v after the taker fee", paper.Collateral(), want)
+	}
+
+	// The level was taken; it stays consumed until the next book update
+	paperOrder(t, paper, false, 300100, 5000, txtypes.ImmediateOrCancel)
+	expectPosition(t, paper, 1, 3001)
+	setBook(t, paper, "2999.00", "3001.00")
+	paperOrder(t, paper, true, 299900, 5000, txtypes.ImmediateOrCancel)
+	expectPosition(t, paper, 0.5, 3001)
+
+	want := []string{"canceled", "canceled", "filled"}
+	if len(*statuses) != len(want) {
+		t.Fatalf("order updates %v, want %v", *statuses, want)
+	}
+	for i := range want {
+		if (*statuses)[i] != want[i] {
+			t.Fatalf("order updates %v, want %v", *statuses, want)
+		}
+	}
+}
+
+func TestPaperReduceOnly(t *testing.T) {
+	paper, statuses := newPaper(t)
+	req := &types.CreateOrderTxReq{BaseAmount: 1000, Price: 299900, IsAsk: 1, Type: txtypes.LimitOrder, TimeInForce: txtypes.ImmediateOrCancel, ReduceOnly: 1}
+	info, err := paper.GetCreateOrderTransaction(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := paper.Send(context.Background(), info, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(*statuses) != 1 || (*statuses)[0] != string(lighterapi.OrderStatusCanceledReduceOnly) {
+		t.Fatalf("order updates %v, want a reduce-only cancel without a position", *statuses)
+	}
+}
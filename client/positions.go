@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+)
+
+// PositionSource returns the current position of an account on a market.
+// It returns a nil position without error when the account has no position there yet.
+type PositionSource func(ctx context.Context, accountIndex int64, marketId uint8) (*lighterapi.AccountPosition, error)
+
+// RESTPositionSource reads positions from the account endpoint
+func RESTPositionSource(api *Client) PositionSource {
+	return func(ctx context.Context, accountIndex int64, marketId uint8) (*lighterapi.AccountPosition, error) {
+		account, err := api.AccountByIndex(ctx, accountIndex)
+		if err != nil {
+			return nil, err
+		}
+		for i := range account.Positions {
+			if account.Positions[i].MarketId == marketId {
+				return &account.Positions[i], nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// AccountPositionCache keeps the latest positions received on the private account stream.
+// Its Handle method can be passed directly as the SubscribeAccount callback.
+type AccountPositionCache struct {
+	mu        sync.RWMutex
+	positions map[int64]map[uint8]WSPosition
+}
+
+// NewAccountPositionCache creates an empty position cache
+func NewAccountPositionCache() *AccountPositionCache {
+	return &AccountPositionCache{positions: make(map[int64]map[uint8]WSPosition)}
+}
+
+// Handle stores positions from an account snapshot or update
+func (c *AccountPositionCache) Handle(resp LighterAccountResponse) error {
+	if resp.RawAccountUpdate == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	account := c.positions[resp.AccountId]
+	if account == nil || resp.IsSnapshot {
+		account = make(map[uint8]WSPosition)
+		c.positions[resp.AccountId] = account
+	}
+	for _, pos := range resp.RawAccountUpdate.Positions {
+		if pos != nil {
+			account[pos.MarketId] = *pos
+		}
+	}
+	return nil
+}
+
+// Get returns the cached stream position for a market
+func (c *AccountPositionCache) Get(accountIndex int64, marketId uint8) (WSPosition, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	pos, ok := c.positions[accountIndex][marketId]
+	return pos, ok
+}
+
+// All returns every cached position of an account
+func (c *AccountPositionCache) All(accountIndex int64) []WSPosition {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	positions := make([]WSPosition, 0, len(c.positions[accountIndex]))
+	for _, pos := range c.positions[accountIndex] {
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+// Source adapts the cache to a PositionSource
+func (c *AccountPositionCache) Source() PositionSource {
+	return func(ctx context.Context, accountIndex int64, marketId uint8) (*lighterapi.AccountPosition, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pos, ok := c.Get(accountIndex, marketId)
+		if !ok {
+			return nil, nil
+		}
+		return &lighterapi.AccountPosition{
+			AllocatedMargin:        pos.AllocatedMargin,
+			AvgEntryPrice:          pos.AvgEntryPrice,
+			InitialMarginFraction:  pos.InitialMarginFraction,
+			LiquidationPrice:       pos.LiquidationPrice,
+			MarginMode:             int32(pos.MarginMode),
+			MarketId:               pos.MarketId,
+			OpenOrderCount:         int64(pos.OpenOrderCount),
+			PendingOrderCount:      int64(pos.PendingOrderCount),
+			Position:               pos.Position,
+			PositionTiedOrderCount: int64(pos.PositionTiedOrderCount),
+			PositionValue:          pos.PositionValue,
+			RealizedPnl:            pos.RealizedPnl,
+			Sign:                   int32(pos.Sign),
+			Symbol:                 pos.Symbol,
+			UnrealizedPnl:          pos.UnrealizedPnl,
+		}, nil
+	}
+}
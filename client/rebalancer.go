@@ -0,0 +1,351 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+)
+
+// CollateralRange is the collateral band, in USDC, an account should stay within.
+// Accounts outside the band are brought back to Target, which defaults to the midpoint.
+type CollateralRange struct {
+	Min    float64
+	Max    float64
+	Target float64
+}
+
+func (r CollateralRange) target() float64 {
+	if r.Target > 0 {
+		return r.Target
+	}
+	return (r.Min + r.Max) / 2
+}
+
+// PlannedTransfer is one transfer of a rebalance plan
+type PlannedTransfer struct {
+	From   int64
+	To     int64
+	Amount float64
+	Fee    float64
+}
+
+// RebalancePlan lists the transfers needed to bring accounts back into range.
+// Unmet holds what could not be moved: positive amounts are still missing on an account,
+// negative amounts are still in excess.
+type RebalancePlan struct {
+	Transfers []PlannedTransfer
+	Unmet     map[int64]float64
+}
+
+// Audit statuses recorded by the Rebalancer
+const (
+	AuditDryRun      = "dry_run"
+	AuditSubmitted   = "submitted"
+	AuditConfirmed   = "confirmed"
+	AuditUnconfirmed = "unconfirmed"
+	AuditFailed      = "failed"
+)
+
+// AuditEntry records one rebalance transfer
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	From   int64     `json:"from"`
+	To     int64     `json:"to"`
+	Amount float64   `json:"amount"`
+	Fee    float64   `json:"fee"`
+	TxHash string    `json:"tx_hash,omitempty"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// RebalanceOptions configures a Rebalancer
+type RebalanceOptions struct {
+	// MinTransfer skips transfers smaller than this many USDC
+	MinTransfer float64
+	// DryRun plans and audits transfers without sending them
+	DryRun bool
+	// Memo is attached to every transfer; defaults to "rebalance"
+	Memo string
+	// AuditLog receives one JSON line per audit entry
+	AuditLog io.Writer
+	// ConfirmTimeout bounds the TransferHistory confirmation of each transfer; defaults to 15s
+	ConfirmTimeout time.Duration
+}
+
+// Rebalancer keeps the collateral of a SubAccountManager's accounts within their ranges
+type Rebalancer struct {
+	manager *SubAccountManager
+	opts    RebalanceOptions
+
+	mu     sync.Mutex
+	ranges map[int64]CollateralRange
+	audit  []AuditEntry
+}
+
+// NewRebalancer creates a rebalancer over the manager's accounts and signers
+func NewRebalancer(manager *SubAccountManager, opts RebalanceOptions) (*Rebalancer, error) {
+	if manager == nil {
+		return nil, errors.New("client: sub-account manager is required")
+	}
+	if opts.Memo == "" {
+		opts.Memo = "rebalance"
+	}
+	if opts.ConfirmTimeout <= 0 {
+		opts.ConfirmTimeout = defaultConfirmTimeout
+	}
+	return &Rebalancer{manager: manager, opts: opts, ranges: make(map[int64]CollateralRange)}, nil
+}
+
+// SetRange sets the collateral band of an account
+func (r *Rebalancer) SetRange(accountIndex int64, rng CollateralRange) error {
+	if rng.Min < 0 || rng.Max < rng.Min {
+		return fmt.Errorf("client: invalid collateral range [%s, %s]", formatFloat(rng.Min), formatFloat(rng.Max))
+	}
+	if rng.Target != 0 && (rng.Target < rng.Min || rng.Target > rng.Max) {
+		return fmt.Errorf("client: target %s outside range [%s, %s]", formatFloat(rng.Target), formatFloat(rng.Min), formatFloat(rng.Max))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ranges[accountIndex] = rng
+	return nil
+}
+
+// Audit returns the audit entries recorded so far
+func (r *Rebalancer) Audit() []AuditEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]AuditEntry(nil), r.audit...)
+}
+
+// flow is an amount an account must or may send or receive
+type flow struct {
+	index  int64
+	amount float64
+}
+
+// Plan computes the transfers needed from the current collateral of every account.
+//
+// Accounts below Min must be refilled to Target and accounts above Max must be drained to
+// Target. Required refills are funded first from drained accounts, then from in-range
+// accounts holding more than their target; excess with nowhere else to go tops up in-range
+// accounts below target. Each demand is served by the smallest single supplier able to cover
+// it, falling back to the largest, which keeps the number of transfers low.
+func (r *Rebalancer) Plan(ctx context.Context) (*RebalancePlan, error) {
+	accounts, err := r.manager.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	ranges := make(map[int64]CollateralRange, len(r.ranges))
+	for k, v := range r.ranges {
+		ranges[k] = v
+	}
+	r.mu.Unlock()
+
+	var needs, wants, excess, spare []*flow
+	for _, acc := range accounts {
+		rng, ok := ranges[acc.Index]
+		if !ok {
+			continue
+		}
+		target := rng.target()
+		canSend := r.manager.hasSigner(acc.Index)
+		sendable := math.Min(acc.Collateral-target, acc.AvailableBalance)
+		switch {
+		case acc.Collateral < rng.Min:
+			needs = append(needs, &flow{acc.Index, target - acc.Collateral})
+		case acc.Collateral > rng.Max && canSend && sendable > 0:
+			excess = append(excess, &flow{acc.Index, sendable})
+		case acc.Collateral > target && canSend && sendable > 0:
+			spare = append(spare, &flow{acc.Index, sendable})
+		case acc.Collateral < target:
+			wants = append(wants, &flow{acc.Index, target - acc.Collateral})
+		}
+	}
+
+	fees := make(map[[2]int64]float64)
+	fee := func(from, to int64) (float64, error) {
+		key := [2]int64{from, to}
+		if f, ok := fees[key]; ok {
+			return f, nil
+		}
+		f, err := r.manager.TransferFee(ctx, from, to)
+		if err != nil {
+			return 0, err
+		}
+		fees[key] = f
+		return f, nil
+	}
+
+	plan := &RebalancePlan{Unmet: make(map[int64]float64)}
+	// Required refills, from drained accounts before in-range ones
+	if err := r.match(plan, needs, append(excess, spare...), fee); err != nil {
+		return nil, err
+	}
+	// Leftover excess tops up in-range accounts
+	if err := r.match(plan, wants, excess, fee); err != nil {
+		return nil, err
+	}
+
+	for _, f := range needs {
+		if USDCFromFloat(f.amount) > 0 {
+			plan.Unmet[f.index] = f.amount
+		}
+	}
+	for _, f := range excess {
+		if USDCFromFloat(f.amount) > 0 {
+			plan.Unmet[f.index] = -f.amount
+		}
+	}
+	return plan, nil
+}
+
+func (r *Rebalancer) match(plan *RebalancePlan, demands, supplies []*flow, fee func(from, to int64) (float64, error)) error {
+	sort.SliceStable(demands, func(i, j int) bool { return demands[i].amount > demands[j].amount })
+	minAmount := math.Max(r.opts.MinTransfer, USDCToFloat(1))
+
+	for _, d := range demands {
+		for d.amount >= minAmount {
+			var best, largest *flow
+			var bestFee, largestFee float64
+			for _, s := range supplies {
+				if s.index == d.index || s.amount <= 0 {
+					continue
+				}
+				f, err := fee(s.index, d.index)
+				if err != nil {
+					return err
+				}
+				if s.amount-f >= d.amount && (best == nil || s.amount < best.amount) {
+					best, bestFee = s, f
+				}
+				if largest == nil || s.amount-f > largest.amount-largestFee {
+					largest, largestFee = s, f
+				}
+			}
+			if best == nil {
+				best, bestFee = largest, largestFee
+			}
+			if best == nil {
+				break
+			}
+			amount := math.Min(d.amount, best.amount-bestFee)
+			if amount < minAmount {
+				break
+			}
+			plan.Transfers = append(plan.Transfers, PlannedTransfer{From: best.index, To: d.index, Amount: amount, Fee: bestFee})
+			best.amount -= amount + bestFee
+			d.amount -= amount
+		}
+	}
+	return nil
+}
+
+// RebalanceOnce plans and executes transfers, auditing each one. In dry-run mode transfers
+// are only audited. Submitted transfers are confirmed through TransferHistory.
+func (r *Rebalancer) RebalanceOnce(ctx context.Context) (*RebalancePlan, error) {
+	plan, err := r.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range plan.Transfers {
+		entry := AuditEntry{Time: time.Now().UTC(), From: t.From, To: t.To, Amount: t.Amount, Fee: t.Fee}
+		if r.opts.DryRun {
+			entry.Status = AuditDryRun
+			r.record(entry)
+			continue
+		}
+
+		res, err := r.manager.Transfer(ctx, t.From, t.To, t.Amount, r.opts.Memo)
+		if err != nil {
+			entry.Status, entry.Error = AuditFailed, err.Error()
+			r.record(entry)
+			return plan, err
+		}
+		entry.TxHash, entry.Fee, entry.Status = res.TxHash, res.Fee, AuditSubmitted
+		r.record(entry)
+
+		entry.Time = time.Now().UTC()
+		if err := r.confirm(ctx, t.From, res.TxHash); err != nil {
+			entry.Status, entry.Error = AuditUnconfirmed, err.Error()
+		} else {
+			entry.Status = AuditConfirmed
+		}
+		r.record(entry)
+	}
+	return plan, nil
+}
+
+// Run rebalances every interval until ctx is done. Errors are passed to onError when set.
+func (r *Rebalancer) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RebalanceOnce(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// confirm polls the sender's TransferHistory until txHash is listed
+func (r *Rebalancer) confirm(ctx context.Context, from int64, txHash string) error {
+	signer, err := r.manager.signer(from)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.opts.ConfirmTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(defaultConfirmInterval)
+	defer ticker.Stop()
+	for {
+		token, err := signer.GetAuthToken(time.Now().Add(accountAuthTTL))
+		if err != nil {
+			return err
+		}
+		history, err := r.manager.api.TransferHistory(ctx, &lighterapi.TransferHistoryParams{
+			AccountIndex:  from,
+			Authorization: &token,
+		})
+		if err == nil {
+			for _, item := range history.Transfers {
+				if item.TxHash == txHash {
+					return nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("transfer %s not in history: %w", txHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Rebalancer) record(entry AuditEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = append(r.audit, entry)
+	if r.opts.AuditLog != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			_, _ = r.opts.AuditLog.Write(append(line, '\n'))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/defi-maker/golighter/client"
+)
+
+func TestRebalancerDryRunThenTransfers(t *testing.T) {
+	srv, m, sub := newSubAccounts(t)
+	srv.SetTransferFee(0.5)
+	ctx := context.Background()
+
+	var log bytes.Buffer
+	r, err := client.NewRebalancer(m, client.RebalanceOptions{DryRun: true, AuditLog: &log})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetRange(testAccount, client.CollateralRange{Min: 2000, Max: 8000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetRange(sub, client.CollateralRange{Min: 1000, Max: 3000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetRange(sub, client.CollateralRange{Min: 1000, Max: 3000, Target: 4000}); err == nil {
+		t.Fatal("expected a target outside the range to be refused")
+	}
+
+	// The trader is 5000 over its midpoint and the sub-account 2000 under; the trader's
+	// excess beyond the refill stays unmet
+	plan, err := r.RebalanceOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := client.PlannedTransfer{From: testAccount, To: sub, Amount: 2000, Fee: 0.5}
+	if len(plan.Transfers) != 1 || plan.Transfers[0] != want {
+		t.Fatalf("transfers %+v, want %+v", plan.Transfers, want)
+	}
+	if len(plan.Unmet) != 1 || !approx(plan.Unmet[testAccount], -2999.5) {
+		t.Fatalf("unmet %v, want 2999.5 of trader excess", plan.Unmet)
+	}
+	if got := collateral(t, srv, sub); got != 0 {
+		t.Fatalf("dry run moved collateral: sub-account holds %v", got)
+	}
+	var entry client.AuditEntry
+	if err := json.Unmarshal(log.Bytes(), &entry); err != nil || entry.Status != client.AuditDryRun || entry.Amount != 2000 {
+		t.Fatalf("audit log %q (%v), want one dry-run line", log.String(), err)
+	}
+
+	live, err := client.NewRebalancer(m, client.RebalanceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	live.SetRange(testAccount, client.CollateralRange{Min: 2000, Max: 8000})
+	live.SetRange(sub, client.CollateralRange{Min: 1000, Max: 3000})
+	if _, err := live.RebalanceOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	audit := live.Audit()
+	if len(audit) != 2 || audit[0].Status != client.AuditSubmitted || audit[1].Status != client.AuditConfirmed || audit[1].TxHash == "" {
+		t.Fatalf("audit %+v, want the transfer submitted then confirmed from transfer history", audit)
+	}
+	if got := collateral(t, srv, sub); !approx(got, 2000) {
+		t.Fatalf("sub-account collateral %v, want 2000", got)
+	}
+	if got := collateral(t, srv, testAccount); !approx(got, 7999.5) {
+		t.Fatalf("trader collateral %v, want 7999.5", got)
+	}
+}
+
+func TestSubAccountManagerRebalancesToTargets(t *testing.T) {
+	srv, m, sub := newSubAccounts(t)
+	api, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	subTx, err := srv.TxClient(api, sub, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddSigner(subTx)
+	ctx := context.Background()
+
+	m.SetTarget(testAccount, 9000)
+	m.SetTarget(sub, 1000)
+	results, err := m.Rebalance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].From != testAccount || results[0].To != sub || results[0].Amount != 1000 {
+		t.Fatalf("results %+v, want 1000 moved to the sub-account", results)
+	}
+
+	// The sub-account signs the way back once its target drops
+	m.SetTarget(sub, 400)
+	m.SetTarget(testAccount, 9600)
+	if results, err = m.Rebalance(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].From != sub || results[0].Amount != 600 {
+		t.Fatalf("results %+v, want 600 returned from the sub-account", results)
+	}
+	if got := collateral(t, srv, sub); !approx(got, 400) {
+		t.Fatalf("sub-account collateral %v, want 400", got)
+	}
+
+	m.SetTarget(sub, -1)
+	if targets := m.Targets(); len(targets) != 1 || targets[testAccount] != 9600 {
+		t.Fatalf("targets %v, want only the trader's", targets)
+	}
+}
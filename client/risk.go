@@ -0,0 +1,445 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// RiskCheck identifies the pre-trade check that rejected an order
+type RiskCheck string
+
+const (
+	RiskCheckUnknownMarket RiskCheck = "unknown_market"
+	RiskCheckMaxNotional   RiskCheck = "max_notional"
+	RiskCheckMaxPosition   RiskCheck = "max_position"
+	RiskCheckMaxOpenOrders RiskCheck = "max_open_orders"
+	RiskCheckPriceBand     RiskCheck = "price_band"
+	RiskCheckLeverage      RiskCheck = "leverage"
+)
+
+// RiskError is returned when an order is rejected before signing
+type RiskError struct {
+	Check    RiskCheck
+	MarketId uint8
+	Detail   string
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("risk: %s rejected order on market %d: %s", e.Check, e.MarketId, e.Detail)
+}
+
+// IsRiskRejection reports whether err (or any error it wraps) is a RiskError
+func IsRiskRejection(err error) bool {
+	var riskErr *RiskError
+	return errors.As(err, &riskErr)
+}
+
+// RiskLimits configures the pre-trade checks. Zero values disable the corresponding check.
+type RiskLimits struct {
+	// MaxOrderNotional caps price * size of a single order in quote units
+	MaxOrderNotional float64
+	// MaxPosition caps the absolute resulting position in base units for every market
+	MaxPosition float64
+	// MarketMaxPosition overrides MaxPosition for specific markets
+	MarketMaxPosition map[uint8]float64
+	// MaxOpenOrders caps the number of resting orders across all markets
+	MaxOpenOrders int
+	// PriceBand rejects limit prices further than this fraction from the local book mid
+	PriceBand float64
+	// MaxLeverage lowers the leverage allowed by MinInitialMarginFraction
+	MaxLeverage float64
+}
+
+// RiskMetrics receives the outcome of every pre-trade check
+type RiskMetrics interface {
+	OrderChecked(marketId uint8)
+	OrderRejected(marketId uint8, check RiskCheck)
+}
+
+// RiskCounters is an in-memory RiskMetrics implementation
+type RiskCounters struct {
+	mu       sync.Mutex
+	checked  atomic.Int64
+	rejected map[RiskCheck]int64
+}
+
+// NewRiskCounters creates empty risk counters
+func NewRiskCounters() *RiskCounters {
+	return &RiskCounters{rejected: make(map[RiskCheck]int64)}
+}
+
+func (m *RiskCounters) OrderChecked(uint8) { m.checked.Add(1) }
+
+func (m *RiskCounters) OrderRejected(_ uint8, check RiskCheck) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rejected[check]++
+}
+
+// Checked returns the number of orders evaluated
+func (m *RiskCounters) Checked() int64 { return m.checked.Load() }
+
+// Rejected returns the number of rejections for a check
+func (m *RiskCounters) Rejected(check RiskCheck) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rejected[check]
+}
+
+// MidPriceFunc returns the current mid price of a market
+type MidPriceFunc func(marketId uint8) (float64, bool)
+
+// RiskManager validates orders against RiskLimits before they are signed.
+// Attach it to a TxClient with SetRiskManager. Positions, open orders and collateral
+// are not mutated by checks; keep them current with UpdateFromAccount or the setters.
+type RiskManager struct {
+	mu            sync.RWMutex
+	limits        RiskLimits
+	markets       map[uint8]lighterapi.OrderBookDetail
+	positions     map[uint8]float64
+	openOrders    map[uint8]int
+	collateral    float64
+	hasCollateral bool
+	mid           MidPriceFunc
+	metrics       RiskMetrics
+}
+
+// NewRiskManager creates a risk manager with the given limits
+func NewRiskManager(limits RiskLimits) *RiskManager {
+	return &RiskManager{
+		limits:     limits,
+		markets:    make(map[uint8]lighterapi.OrderBookDetail),
+		positions:  make(map[uint8]float64),
+		openOrders: make(map[uint8]int),
+	}
+}
+
+// SetLimits replaces the configured limits
+func (r *RiskManager) SetLimits(limits RiskLimits) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limits = limits
+}
+
+// SetMetrics sets the metrics sink
+func (r *RiskManager) SetMetrics(metrics RiskMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = metrics
+}
+
+// SetMidSource sets the mid price source used by the price band and leverage checks,
+// typically OrderBookCache.Mid
+func (r *RiskManager) SetMidSource(fn MidPriceFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mid = fn
+}
+
+// SetMarkets registers market details used for unit conversion and margin limits
+func (r *RiskManager) SetMarkets(details []lighterapi.OrderBookDetail) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range details {
+		r.markets[d.MarketId] = d
+	}
+}
+
+// LoadMarkets fetches all market details over REST
+func (r *RiskManager) LoadMarkets(ctx context.Context, api *Client) error {
+	details, err := api.OrderBookDetails(ctx, &lighterapi.OrderBookDetailsParams{})
+	if err != nil {
+		return err
+	}
+	r.SetMarkets(details.OrderBookDetails)
+	return nil
+}
+
+// SetPosition sets the signed position in base units for a market
+func (r *RiskManager) SetPosition(marketId uint8, size float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.positions[marketId] = size
+}
+
+// SetOpenOrders sets the number of resting orders on a market
+func (r *RiskManager) SetOpenOrders(marketId uint8, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.openOrders[marketId] = n
+}
+
+// SetCollateral sets the account collateral in quote units used by the leverage check
+func (r *RiskManager) SetCollateral(collateral float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collateral = collateral
+	r.hasCollateral = true
+}
+
+// UpdateFromAccount refreshes positions and open orders from the private account stream
+func (r *RiskManager) UpdateFromAccount(resp LighterAccountResponse) error {
+	if resp.RawAccountUpdate == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if resp.IsSnapshot {
+		r.positions = make(map[uint8]float64)
+		r.openOrders = make(map[uint8]int)
+	}
+	for _, pos := range resp.RawAccountUpdate.Positions {
+		if pos == nil {
+			continue
+		}
+		r.positions[pos.MarketId] = float64(pos.Sign) * parseDecimal(pos.Position)
+		r.openOrders[pos.MarketId] = pos.OpenOrderCount
+	}
+	return nil
+}
+
+// UpdateFromDetailedAccount refreshes positions, open orders and collateral from a REST account
+func (r *RiskManager) UpdateFromDetailedAccount(account *lighterapi.DetailedAccount) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.positions = make(map[uint8]float64, len(account.Positions))
+	r.openOrders = make(map[uint8]int, len(account.Positions))
+	for _, pos := range account.Positions {
+		r.positions[pos.MarketId] = float64(pos.Sign) * parseDecimal(pos.Position)
+		r.openOrders[pos.MarketId] = int(pos.OpenOrderCount)
+	}
+	r.collateral = parseDecimal(account.Collateral)
+	r.hasCollateral = true
+}
+
+// CheckOrder validates a single order request
+func (r *RiskManager) CheckOrder(req *types.CreateOrderTxReq) error {
+	order := &txtypes.OrderInfo{
+		MarketIndex:  req.MarketIndex,
+		BaseAmount:   req.BaseAmount,
+		Price:        req.Price,
+		IsAsk:        req.IsAsk,
+		Type:         req.Type,
+		TimeInForce:  req.TimeInForce,
+		ReduceOnly:   req.ReduceOnly,
+		TriggerPrice: req.TriggerPrice,
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	proj := r.projection()
+	return r.check(order, proj)
+}
+
+// CheckModify validates the new size and price of a modified order against the notional
+// limit and price band. A modify does not carry the order's side, so position, open order
+// and leverage limits are not checked for it.
+func (r *RiskManager) CheckModify(req *types.ModifyOrderTxReq) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.checkModify(req.MarketIndex, req.BaseAmount, req.Price)
+}
+
+// CheckBatch validates the create-order transactions of a batch cumulatively, so that
+// open order and position limits account for every order in the batch. Modifies are
+// checked as CheckModify does.
+func (r *RiskManager) CheckBatch(infos []txtypes.TxInfo) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	proj := r.projection()
+	for _, info := range infos {
+		var err error
+		switch tx := info.(type) {
+		case *txtypes.L2CreateOrderTxInfo:
+			if tx.OrderInfo != nil {
+				err = r.check(tx.OrderInfo, proj)
+			}
+		case *txtypes.L2ModifyOrderTxInfo:
+			err = r.checkModify(tx.MarketIndex, tx.BaseAmount, tx.Price)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type riskProjection struct {
+	positions  map[uint8]float64
+	openOrders int
+}
+
+func (r *RiskManager) projection() *riskProjection {
+	positions := make(map[uint8]float64, len(r.positions))
+	for k, v := range r.positions {
+		positions[k] = v
+	}
+	openOrders := 0
+	for _, n := range r.openOrders {
+		openOrders += n
+	}
+	return &riskProjection{positions: positions, openOrders: openOrders}
+}
+
+func (r *RiskManager) check(order *txtypes.OrderInfo, proj *riskProjection) error {
+	return r.record(order.MarketIndex, r.evaluate(order, proj))
+}
+
+func (r *RiskManager) checkModify(marketId uint8, baseAmount int64, price uint32) error {
+	order := &txtypes.OrderInfo{MarketIndex: marketId, BaseAmount: baseAmount, Price: price, Type: txtypes.LimitOrder}
+	_, _, err := r.evaluatePrice(order)
+	return r.record(marketId, err)
+}
+
+// record reports a check to the metrics sink
+func (r *RiskManager) record(marketId uint8, err *RiskError) error {
+	if r.metrics != nil {
+		r.metrics.OrderChecked(marketId)
+	}
+	if err != nil {
+		if r.metrics != nil {
+			r.metrics.OrderRejected(err.MarketId, err.Check)
+		}
+		return err
+	}
+	return nil
+}
+
+func riskReject(order *txtypes.OrderInfo, check RiskCheck, format string, args ...interface{}) *RiskError {
+	return &RiskError{Check: check, MarketId: order.MarketIndex, Detail: fmt.Sprintf(format, args...)}
+}
+
+// evaluatePrice runs the checks that only depend on the order's size and price, and
+// returns both in float units
+func (r *RiskManager) evaluatePrice(order *txtypes.OrderInfo) (size, price float64, err *RiskError) {
+	detail, ok := r.markets[order.MarketIndex]
+	if !ok {
+		return 0, 0, riskReject(order, RiskCheckUnknownMarket, "no market details loaded")
+	}
+
+	size = BaseToFloat(&detail, order.BaseAmount)
+	price = PriceToFloat(&detail, order.Price)
+	notional := size * price
+
+	if r.limits.MaxOrderNotional > 0 && notional > r.limits.MaxOrderNotional {
+		return 0, 0, riskReject(order, RiskCheckMaxNotional, "notional %s exceeds %s", formatFloat(notional), formatFloat(r.limits.MaxOrderNotional))
+	}
+
+	mid, hasMid := 0.0, false
+	if r.mid != nil {
+		mid, hasMid = r.mid(order.MarketIndex)
+	}
+	if r.limits.PriceBand > 0 && hasMid && mid > 0 && order.Type == txtypes.LimitOrder {
+		deviation := math.Abs(price-mid) / mid
+		if deviation > r.limits.PriceBand {
+			return 0, 0, riskReject(order, RiskCheckPriceBand, "price %s is %.2f%% from mid %s", formatFloat(price), deviation*100, formatFloat(mid))
+		}
+	}
+	return size, price, nil
+}
+
+func (r *RiskManager) evaluate(order *txtypes.OrderInfo, proj *riskProjection) *RiskError {
+	reject := func(check RiskCheck, format string, args ...interface{}) *RiskError {
+		return riskReject(order, check, format, args...)
+	}
+
+	size, price, err := r.evaluatePrice(order)
+	if err != nil {
+		return err
+	}
+
+	if order.TimeInForce != txtypes.ImmediateOrCancel {
+		if r.limits.MaxOpenOrders > 0 && proj.openOrders+1 > r.limits.MaxOpenOrders {
+			return reject(RiskCheckMaxOpenOrders, "%d open orders at limit %d", proj.openOrders, r.limits.MaxOpenOrders)
+		}
+		proj.openOrders++
+	}
+
+	current := proj.positions[order.MarketIndex]
+	delta := size
+	if order.IsAsk == 1 {
+		delta = -size
+	}
+	next := current + delta
+	opening := math.Abs(next) > math.Abs(current) || next*current < 0
+	if order.ReduceOnly == 1 && opening {
+		// The exchange never lets a reduce-only order open or flip a position
+		if next*current < 0 {
+			next = 0
+		} else {
+			next = current
+		}
+		opening = false
+	}
+	if !opening {
+		proj.positions[order.MarketIndex] = next
+		return nil
+	}
+
+	maxPosition := r.limits.MaxPosition
+	if v, ok := r.limits.MarketMaxPosition[order.MarketIndex]; ok {
+		maxPosition = v
+	}
+	if maxPosition > 0 && math.Abs(next) > maxPosition {
+		return reject(RiskCheckMaxPosition, "resulting position %s exceeds %s", formatFloat(next), formatFloat(maxPosition))
+	}
+
+	if r.hasCollateral {
+		required := 0.0
+		for marketId, pos := range proj.positions {
+			if marketId == order.MarketIndex {
+				continue
+			}
+			required += r.initialMargin(marketId, pos, 0)
+		}
+		required += r.initialMargin(order.MarketIndex, next, price)
+		if required > r.collateral {
+			return reject(RiskCheckLeverage, "initial margin %s exceeds collateral %s", formatFloat(required), formatFloat(r.collateral))
+		}
+	}
+
+	proj.positions[order.MarketIndex] = next
+	return nil
+}
+
+// initialMargin estimates the margin needed for a position at the lower of the market and configured leverage
+func (r *RiskManager) initialMargin(marketId uint8, position, fallbackPrice float64) float64 {
+	detail, ok := r.markets[marketId]
+	if !ok || position == 0 {
+		return 0
+	}
+	price := fallbackPrice
+	if r.mid != nil {
+		if mid, ok := r.mid(marketId); ok && mid > 0 {
+			price = mid
+		}
+	}
+	if price == 0 {
+		price = detail.LastTradePrice
+	}
+
+	leverage := MarginFractionToLeverage(detail.MinInitialMarginFraction)
+	if r.limits.MaxLeverage > 0 && (leverage == 0 || r.limits.MaxLeverage < leverage) {
+		leverage = r.limits.MaxLeverage
+	}
+	if leverage == 0 {
+		return 0
+	}
+	return math.Abs(position) * price / leverage
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,220 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const (
+	testAccount  = lightertest.TraderAccount
+	otherAccount = lightertest.MakerAccount
+)
+
+func limitOrder(clientIndex int64, isAsk bool, price uint32, base int64) *types.CreateOrderTxReq {
+	req := &types.CreateOrderTxReq{
+		MarketIndex:      0,
+		ClientOrderIndex: clientIndex,
+		BaseAmount:       base,
+		Price:            price,
+		Type:             txtypes.LimitOrder,
+		TimeInForce:      txtypes.GoodTillTime,
+		OrderExpiry:      time.Now().Add(time.Hour).UnixMilli(),
+	}
+	if isAsk {
+		req.IsAsk = 1
+	}
+	return req
+}
+
+func riskCheck(err error) client.RiskCheck {
+	var riskErr *client.RiskError
+	if !errors.As(err, &riskErr) {
+		return ""
+	}
+	return riskErr.Check
+}
+
+func TestRiskChecks(t *testing.T) {
+	_, api, _ := lightertest.NewExchange(t)
+	risk := client.NewRiskManager(client.RiskLimits{})
+	if err := risk.LoadMarkets(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+	risk.SetMidSource(func(uint8) (float64, bool) { return 3000, true })
+	counters := client.NewRiskCounters()
+	risk.SetMetrics(counters)
+
+	tests := []struct {
+		name   string
+		limits client.RiskLimits
+		setup  func()
+		order  *types.CreateOrderTxReq
+		want   client.RiskCheck
+	}{
+		{"notional", client.RiskLimits{MaxOrderNotional: 500}, nil, limitOrder(1, false, 300000, 2000), client.RiskCheckMaxNotional},
+		{"notional within limit", client.RiskLimits{MaxOrderNotional: 500}, nil, limitOrder(1, false, 300000, 1000), ""},
+		{"price band", client.RiskLimits{PriceBand: 0.01}, nil, limitOrder(1, true, 306000, 100), client.RiskCheckPriceBand},
+		{"open orders", client.RiskLimits{MaxOpenOrders: 2}, func() { risk.SetOpenOrders(0, 2) }, limitOrder(1, false, 299000, 100), client.RiskCheckMaxOpenOrders},
+		{"position", client.RiskLimits{MaxPosition: 1}, func() { risk.SetPosition(0, 0.95) }, limitOrder(1, false, 299000, 1000), client.RiskCheckMaxPosition},
+		{"reducing a position over the limit", client.RiskLimits{MaxPosition: 1}, func() { risk.SetPosition(0, 2) }, limitOrder(1, true, 301000, 1000), ""},
+		{"market position override", client.RiskLimits{MaxPosition: 1, MarketMaxPosition: map[uint8]float64{0: 5}}, func() { risk.SetPosition(0, 0.95) }, limitOrder(1, false, 299000, 1000), ""},
+		// 20x needs 150 USDC for 1 ETH at 3000
+		{"leverage", client.RiskLimits{}, func() { risk.SetCollateral(100) }, limitOrder(1, false, 300000, 10000), client.RiskCheckLeverage},
+		{"leverage within collateral", client.RiskLimits{}, func() { risk.SetCollateral(100) }, limitOrder(1, false, 300000, 5000), ""},
+		{"configured leverage", client.RiskLimits{MaxLeverage: 2}, func() { risk.SetCollateral(1000) }, limitOrder(1, false, 300000, 10000), client.RiskCheckLeverage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risk.SetLimits(tt.limits)
+			risk.SetPosition(0, 0)
+			risk.SetOpenOrders(0, 0)
+			risk.SetCollateral(1e9)
+			if tt.setup != nil {
+				tt.setup()
+			}
+			err := risk.CheckOrder(tt.order)
+			if got := riskCheck(err); got != tt.want {
+				t.Fatalf("check %q (%v), want %q", got, err, tt.want)
+			}
+			if tt.want != "" && !client.IsRiskRejection(err) {
+				t.Fatalf("%v is not a risk rejection", err)
+			}
+		})
+	}
+	if got := counters.Checked(); got != int64(len(tests)) {
+		t.Fatalf("%d orders checked, want %d", got, len(tests))
+	}
+	if got := counters.Rejected(client.RiskCheckLeverage); got != 2 {
+		t.Fatalf("%d leverage rejections, want 2", got)
+	}
+
+	if err := client.NewRiskManager(client.RiskLimits{}).CheckOrder(limitOrder(1, false, 300000, 100)); riskCheck(err) != client.RiskCheckUnknownMarket {
+		t.Fatalf("order without market details returned %v, want %q", err, client.RiskCheckUnknownMarket)
+	}
+}
+
+func TestRiskBatchIsCheckedBeforeSending(t *testing.T) {
+	srv, api, tx := lightertest.NewExchange(t)
+	risk := client.NewRiskManager(client.RiskLimits{MaxOpenOrders: 3, MaxPosition: 0.05})
+	if err := risk.LoadMarkets(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+	account, err := api.AccountByIndex(context.Background(), testAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	risk.UpdateFromDetailedAccount(account)
+	tx.SetRiskManager(risk)
+
+	batch := func(n int, base int64) []txtypes.TxInfo {
+		t.Helper()
+		nonce, err := api.NextNonceValue(context.Background(), testAccount, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var infos []txtypes.TxInfo
+		for i := 0; i < n; i++ {
+			next := nonce + int64(i)
+			info, err := tx.GetCreateOrderTransaction(limitOrder(int64(i+1), false, uint32(299000-i), base), &types.TransactOpts{Nonce: &next})
+			if err != nil {
+				t.Fatal(err)
+			}
+			infos = append(infos, info)
+		}
+		return infos
+	}
+
+	// Each order fits on its own; the batch as a whole passes the open order limit
+	if _, err := tx.SendBatch(context.Background(), batch(4, 100)); riskCheck(err) != client.RiskCheckMaxOpenOrders {
+		t.Fatalf("batch of 4 returned %v, want an open orders rejection", err)
+	}
+	// Three orders of 0.02 would build a 0.06 long
+	if _, err := tx.SendBatch(context.Background(), batch(3, 200)); riskCheck(err) != client.RiskCheckMaxPosition {
+		t.Fatalf("batch building 0.06 returned %v, want a position rejection", err)
+	}
+	if n := srv.Requests("/api/v1/sendTxBatch"); n != 0 {
+		t.Fatalf("%d rejected batches reached the exchange", n)
+	}
+	if _, err := tx.SendBatch(context.Background(), batch(3, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if orders := srv.Orders(testAccount); len(orders) != 3 {
+		t.Fatalf("%d orders resting, want 3", len(orders))
+	}
+}
+
+func TestRiskBatchProjectsReducingAndFlippingOrders(t *testing.T) {
+	_, api, tx := lightertest.NewExchange(t)
+	risk := client.NewRiskManager(client.RiskLimits{MaxPosition: 0.1})
+	if err := risk.LoadMarkets(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+	sign := func(req *types.CreateOrderTxReq, nonce int64) txtypes.TxInfo {
+		t.Helper()
+		info, err := tx.GetCreateOrderTransaction(req, &types.TransactOpts{Nonce: &nonce})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info
+	}
+	reduce := limitOrder(1, true, 299000, 1500)
+	reduce.ReduceOnly = 1
+
+	tests := []struct {
+		name  string
+		batch []txtypes.TxInfo
+	}{
+		// +0.1 flips to -0.05, which is allowed, then the second sell builds a -0.2 short
+		{"flip", []txtypes.TxInfo{sign(limitOrder(1, true, 299000, 1500), 0), sign(limitOrder(2, true, 299000, 1500), 1)}},
+		// The reduce-only sell closes the long rather than leaving it open
+		{"reduce only", []txtypes.TxInfo{sign(reduce, 0), sign(limitOrder(2, true, 299000, 1500), 1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risk.SetPosition(0, 0.1)
+			if err := risk.CheckBatch(tt.batch[:1]); err != nil {
+				t.Fatalf("first order rejected: %v", err)
+			}
+			if err := risk.CheckBatch(tt.batch); riskCheck(err) != client.RiskCheckMaxPosition {
+				t.Fatalf("batch returned %v, want a position rejection", err)
+			}
+		})
+	}
+}
+
+func TestRiskModifyChecksSizeAndPrice(t *testing.T) {
+	_, api, tx := lightertest.NewExchange(t)
+	risk := client.NewRiskManager(client.RiskLimits{MaxOrderNotional: 500, PriceBand: 0.01, MaxPosition: 0.01})
+	if err := risk.LoadMarkets(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+	risk.SetMidSource(func(uint8) (float64, bool) { return 3000, true })
+	tx.SetRiskManager(risk)
+
+	tests := []struct {
+		name string
+		req  types.ModifyOrderTxReq
+		want client.RiskCheck
+	}{
+		{"notional", types.ModifyOrderTxReq{Index: 1, BaseAmount: 2000, Price: 300000}, client.RiskCheckMaxNotional},
+		{"price band", types.ModifyOrderTxReq{Index: 1, BaseAmount: 100, Price: 290000}, client.RiskCheckPriceBand},
+		// The side of the order is unknown, so the position limit does not apply
+		{"position not checked", types.ModifyOrderTxReq{Index: 1, BaseAmount: 1000, Price: 300000}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := int64(0)
+			_, err := tx.GetModifyOrderTransaction(&tt.req, &types.TransactOpts{Nonce: &nonce})
+			if got := riskCheck(err); got != tt.want {
+				t.Fatalf("check %q (%v), want %q", got, err, tt.want)
+			}
+		})
+	}
+}
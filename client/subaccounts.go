@@ -0,0 +1,289 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/elliottech/lighter-go/types"
+)
+
+const accountAuthTTL = 10 * time.Minute
+
+// SubAccount is an account owned by the manager's L1 address
+type SubAccount struct {
+	Index            int64
+	Name             string
+	Collateral       float64
+	AvailableBalance float64
+	Account          lighterapi.Account
+}
+
+// TransferResult describes a submitted collateral transfer
+type TransferResult struct {
+	TxHash string
+	From   int64
+	To     int64
+	Amount float64
+	Fee    float64
+}
+
+// SubAccountManager lists, creates and funds the accounts of one L1 address.
+//
+// Transfers are signed by the sending account, so every account that sends collateral
+// needs a signer registered with AddSigner. The API has no endpoint for writing account
+// metadata: names are read from AccountMetadata and can be overridden locally with SetName.
+type SubAccountManager struct {
+	api       *Client
+	l1Address string
+	master    *TxClient
+
+	mu      sync.RWMutex
+	signers map[int64]*TxClient
+	names   map[int64]string
+	targets map[int64]float64
+}
+
+// NewSubAccountManager creates a manager for l1Address; master signs CreateSubAccount transactions
+func NewSubAccountManager(api *Client, l1Address string, master *TxClient) (*SubAccountManager, error) {
+	if api == nil {
+		return nil, errors.New("client: REST client is required")
+	}
+	if l1Address == "" {
+		return nil, errors.New("client: l1 address is required")
+	}
+	m := &SubAccountManager{
+		api:       api,
+		l1Address: l1Address,
+		master:    master,
+		signers:   make(map[int64]*TxClient),
+		names:     make(map[int64]string),
+		targets:   make(map[int64]float64),
+	}
+	if master != nil {
+		m.signers[master.GetAccountIndex()] = master
+	}
+	return m, nil
+}
+
+// AddSigner registers the TxClient used to sign transfers out of its account
+func (m *SubAccountManager) AddSigner(tx *TxClient) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.signers[tx.GetAccountIndex()] = tx
+}
+
+// SetName labels an account; the label takes precedence over AccountMetadata
+func (m *SubAccountManager) SetName(accountIndex int64, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.names[accountIndex] = name
+}
+
+// SetTarget sets the collateral, in USDC, that Rebalance keeps on an account.
+// A negative target removes it.
+func (m *SubAccountManager) SetTarget(accountIndex int64, usdc float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if usdc < 0 {
+		delete(m.targets, accountIndex)
+		return
+	}
+	m.targets[accountIndex] = usdc
+}
+
+// Targets returns a copy of the collateral targets
+func (m *SubAccountManager) Targets() map[int64]float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make(map[int64]float64, len(m.targets))
+	for k, v := range m.targets {
+		out[k] = v
+	}
+	return out
+}
+
+// List returns every account of the L1 address ordered by index, with names and collateral
+func (m *SubAccountManager) List(ctx context.Context) ([]SubAccount, error) {
+	resp, err := m.api.AccountsByL1Address(ctx, &lighterapi.AccountsByL1AddressParams{L1Address: m.l1Address})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int64]string)
+	if meta, err := m.api.AccountMetadata(ctx, &lighterapi.AccountMetadataParams{
+		By:    lighterapi.AccountMetadataParamsByL1Address,
+		Value: m.l1Address,
+	}); err == nil {
+		for _, md := range meta.AccountMetadatas {
+			names[md.AccountIndex] = md.Name
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	accounts := make([]SubAccount, 0, len(resp.SubAccounts))
+	for _, acc := range resp.SubAccounts {
+		name := names[acc.Index]
+		if local, ok := m.names[acc.Index]; ok {
+			name = local
+		}
+		accounts = append(accounts, SubAccount{
+			Index:            acc.Index,
+			Name:             name,
+			Collateral:       parseDecimal(acc.Collateral),
+			AvailableBalance: parseDecimal(acc.AvailableBalance),
+			Account:          acc,
+		})
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Index < accounts[j].Index })
+	return accounts, nil
+}
+
+// Create submits a CreateSubAccount transaction from the master account and waits until the
+// new account is listed for the L1 address. Waiting is bounded by the context deadline, or
+// 15 seconds when the context has none.
+func (m *SubAccountManager) Create(ctx context.Context, name string) (*SubAccount, error) {
+	if m.master == nil {
+		return nil, errors.New("client: creating sub-accounts requires a master signer")
+	}
+	before, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]bool, len(before))
+	for _, acc := range before {
+		known[acc.Index] = true
+	}
+
+	tx, err := m.master.GetCreateSubAccountTransaction(nil)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := m.master.SendRawTx(ctx, tx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultConfirmTimeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(defaultConfirmInterval)
+	defer ticker.Stop()
+	for {
+		accounts, err := m.List(ctx)
+		if err == nil {
+			for _, acc := range accounts {
+				if known[acc.Index] {
+					continue
+				}
+				if name != "" {
+					m.SetName(acc.Index, name)
+					acc.Name = name
+				}
+				return &acc, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("client: confirm sub-account creation %s: %w", txHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// TransferFee returns the fee, in USDC, charged for a transfer between two accounts
+func (m *SubAccountManager) TransferFee(ctx context.Context, from, to int64) (float64, error) {
+	signer, err := m.signer(from)
+	if err != nil {
+		return 0, err
+	}
+	return signer.TransferFee(ctx, to)
+}
+
+// Transfer moves amount USDC from one account to another, paying the fee from TransferFeeInfo.
+// The memo is truncated to 32 bytes.
+func (m *SubAccountManager) Transfer(ctx context.Context, from, to int64, amount float64, memo string) (*TransferResult, error) {
+	if from == to {
+		return nil, errors.New("client: transfer source and destination are the same account")
+	}
+	usdc := USDCFromFloat(amount)
+	if usdc <= 0 {
+		return nil, errors.New("client: transfer amount must be positive")
+	}
+	signer, err := m.signer(from)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := m.TransferFee(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &types.TransferTxReq{
+		ToAccountIndex: to,
+		USDCAmount:     usdc,
+		Fee:            USDCFromFloat(fee),
+	}
+	copy(req.Memo[:], memo)
+	tx, err := signer.GetTransferTransaction(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := signer.SendRawTx(ctx, tx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &TransferResult{TxHash: txHash, From: from, To: to, Amount: USDCToFloat(usdc), Fee: fee}, nil
+}
+
+// Rebalance moves collateral so every account with a target ends up at it.
+// It runs a Rebalancer with a zero-width range around each target; use a Rebalancer
+// directly for ranges, dry runs and audit logs.
+func (m *SubAccountManager) Rebalance(ctx context.Context) ([]TransferResult, error) {
+	r, err := NewRebalancer(m, RebalanceOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for index, target := range m.Targets() {
+		if err := r.SetRange(index, CollateralRange{Min: target, Max: target, Target: target}); err != nil {
+			return nil, err
+		}
+	}
+	_, err = r.RebalanceOnce(ctx)
+
+	var results []TransferResult
+	for _, entry := range r.Audit() {
+		if entry.Status == AuditConfirmed || entry.Status == AuditUnconfirmed {
+			results = append(results, TransferResult{
+				TxHash: entry.TxHash,
+				From:   entry.From,
+				To:     entry.To,
+				Amount: entry.Amount,
+				Fee:    entry.Fee,
+			})
+		}
+	}
+	return results, err
+}
+
+func (m *SubAccountManager) signer(accountIndex int64) (*TxClient, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	signer, ok := m.signers[accountIndex]
+	if !ok {
+		return nil, fmt.Errorf("client: no signer registered for account %d", accountIndex)
+	}
+	return signer, nil
+}
+
+func (m *SubAccountManager) hasSigner(accountIndex int64) bool {
+	_, err := m.signer(accountIndex)
+	return err == nil
+}
@@ -0,0 +1,88 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+)
+
+const traderL1 = "0x0000000000000000000000000000000000000001"
+
+// newSubAccounts returns a manager for the trader's L1 address with the trader as master,
+// and the sub-account it created
+func newSubAccounts(t *testing.T) (*lightertest.Server, *client.SubAccountManager, int64) {
+	t.Helper()
+	srv, api, tx := lightertest.NewExchange(t)
+	srv.HandleJSON("/api/v1/accountMetadata", lighterapi.AccountMetadatas{Code: 200, AccountMetadatas: []lighterapi.AccountMetadata{
+		{AccountIndex: testAccount, Name: "main"},
+	}})
+	m, err := client.NewSubAccountManager(api, traderL1, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sub, err := m.Create(ctx, "hedge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, m, sub.Index
+}
+
+func TestSubAccountManagerCreatesAndLists(t *testing.T) {
+	_, m, sub := newSubAccounts(t)
+
+	accounts, err := m.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("%d accounts, want the trader and its sub-account", len(accounts))
+	}
+	if a := accounts[0]; a.Index != testAccount || a.Name != "main" || a.Collateral != 10000 {
+		t.Fatalf("first account %+v, want the trader named from metadata", a)
+	}
+	if a := accounts[1]; a.Index != sub || a.Name != "hedge" || a.Collateral != 0 {
+		t.Fatalf("second account %+v, want the empty hedge sub-account", a)
+	}
+
+	m.SetName(testAccount, "core")
+	if accounts, err = m.List(context.Background()); err != nil || accounts[0].Name != "core" {
+		t.Fatalf("first account %+v (%v), want the local name to win", accounts[0], err)
+	}
+}
+
+func TestSubAccountManagerTransfers(t *testing.T) {
+	srv, m, sub := newSubAccounts(t)
+	srv.SetTransferFee(0.5)
+	ctx := context.Background()
+
+	res, err := m.Transfer(ctx, testAccount, sub, 250, "fund hedge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TxHash == "" || res.From != testAccount || res.To != sub || res.Amount != 250 || res.Fee != 0.5 {
+		t.Fatalf("result %+v, want 250 sent to the sub-account for a 0.5 fee", res)
+	}
+	if got := collateral(t, srv, testAccount); !approx(got, 9749.5) {
+		t.Fatalf("trader collateral %v, want 9749.5", got)
+	}
+	if got := collateral(t, srv, sub); !approx(got, 250) {
+		t.Fatalf("sub-account collateral %v, want 250", got)
+	}
+
+	// Transfers out of the sub-account need its signer
+	if _, err := m.Transfer(ctx, sub, testAccount, 10, ""); err == nil {
+		t.Fatal("expected a transfer without a signer to be refused")
+	}
+	if _, err := m.Transfer(ctx, testAccount, testAccount, 10, ""); err == nil {
+		t.Fatal("expected a transfer to the same account to be refused")
+	}
+	if _, err := m.Transfer(ctx, testAccount, sub, 0, ""); err == nil {
+		t.Fatal("expected a zero transfer to be refused")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/elliottech/lighter-go/signer"
 	"github.com/elliottech/lighter-go/types"
 	"github.com/elliottech/lighter-go/types/txtypes"
+	p2 "github.com/elliottech/poseidon_crypto/hash/poseidon2_goldilocks"
 )
 
 const (
@@ -23,8 +24,14 @@ type TxClient struct {
 	keyManager   signer.KeyManager
 	accountIndex int64
 	apiKeyIndex  uint8
+	risk         *RiskManager
+	positions    PositionSource
+	nonces       NonceSource
 }
 
+// NonceSource returns the nonce to sign the next transaction of an API key with
+type NonceSource func(ctx context.Context, accountIndex int64, apiKeyIndex uint8) (int64, error)
+
 func NewTxClient(api *Client, apiKeyPrivateKey string, accountIndex int64, apiKeyIndex uint8, chainID uint32) (*TxClient, error) {
 	if api == nil {
 		return nil, fmt.Errorf("client: REST client is required")
@@ -61,6 +68,17 @@ func (c *TxClient) GetKeyManager() signer.KeyManager { return c.keyManager }
 
 func (c *TxClient) SwitchAPIKey(apiKey uint8) { c.apiKeyIndex = apiKey }
 
+// SetRiskManager enables pre-trade checks for GetCreateOrderTransaction,
+// GetModifyOrderTransaction and SendBatch.
+// Pass nil to disable them.
+func (c *TxClient) SetRiskManager(risk *RiskManager) { c.risk = risk }
+
+func (c *TxClient) GetRiskManager() *RiskManager { return c.risk }
+
+// SetNonceSource overrides how transactions built without an explicit nonce get one.
+// Pass nil to go back to the next nonce endpoint.
+func (c *TxClient) SetNonceSource(src NonceSource) { c.nonces = src }
+
 func (c *TxClient) CheckClient(ctx context.Context) error {
 	_, err := c.api.NextNonce(ctx, &lighterapi.NextNonceParams{
 		AccountIndex: c.accountIndex,
@@ -80,6 +98,13 @@ func (c *TxClient) GetAuthToken(deadline time.Time) (string, error) {
 	})
 }
 
+// AuthTokenProvider returns an AuthTokenFunc issuing tokens valid for ttl (at most 7 hours)
+func (c *TxClient) AuthTokenProvider(ttl time.Duration) AuthTokenFunc {
+	return func() (string, error) {
+		return c.GetAuthToken(time.Now().Add(ttl))
+	}
+}
+
 func (c *TxClient) fulfillDefaultOps(ops *types.TransactOpts) (*types.TransactOpts, error) {
 	if ops == nil {
 		ops = new(types.TransactOpts)
@@ -94,7 +119,11 @@ func (c *TxClient) fulfillDefaultOps(ops *types.TransactOpts) (*types.TransactOp
 		ops.ApiKeyIndex = &c.apiKeyIndex
 	}
 	if ops.Nonce == nil {
-		nonce, err := c.api.NextNonceValue(context.Background(), *ops.FromAccountIndex, *ops.ApiKeyIndex)
+		next := c.api.NextNonceValue
+		if c.nonces != nil {
+			next = c.nonces
+		}
+		nonce, err := next(context.Background(), *ops.FromAccountIndex, *ops.ApiKeyIndex)
 		if err != nil {
 			return nil, err
 		}
@@ -127,6 +156,12 @@ func (c *TxClient) SendRawTx(ctx context.Context, info txtypes.TxInfo, priceProt
 }
 
 func (c *TxClient) SendBatch(ctx context.Context, infos []txtypes.TxInfo) (*lighterapi.RespSendTxBatch, error) {
+	if c.risk != nil {
+		if err := c.risk.CheckBatch(infos); err != nil {
+			return nil, err
+		}
+	}
+
 	txTypes := make([]int, 0, len(infos))
 	txInfos := make([]string, 0, len(infos))
 	for _, info := range infos {
@@ -166,6 +201,11 @@ func (c *TxClient) GetChangePubKeyTransaction(tx *types.ChangePubKeyReq, ops *ty
 }
 
 func (c *TxClient) GetCreateOrderTransaction(tx *types.CreateOrderTxReq, ops *types.TransactOpts) (*txtypes.L2CreateOrderTxInfo, error) {
+	if c.risk != nil {
+		if err := c.risk.CheckOrder(tx); err != nil {
+			return nil, err
+		}
+	}
 	ops, err := c.fulfillDefaultOps(ops)
 	if err != nil {
 		return nil, err
@@ -229,15 +269,37 @@ func (c *TxClient) GetWithdrawTransaction(tx *types.WithdrawTxReq, ops *types.Tr
 	return types.ConstructWithdrawTx(c.keyManager, c.chainID, tx, ops)
 }
 
+// GetUpdateLeverageTransaction signs a leverage update. The SDK's ConvertUpdateLeverageTx
+// drops MarginMode, which the signed hash covers, so the transaction is built here.
 func (c *TxClient) GetUpdateLeverageTransaction(tx *types.UpdateLeverageTxReq, ops *types.TransactOpts) (*txtypes.L2UpdateLeverageTxInfo, error) {
 	ops, err := c.fulfillDefaultOps(ops)
 	if err != nil {
 		return nil, err
 	}
-	return types.ConstructUpdateLeverageTx(c.keyManager, c.chainID, tx, ops)
+	info := types.ConvertUpdateLeverageTx(tx, ops)
+	info.MarginMode = tx.MarginMode
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+	msgHash, err := info.Hash(c.chainID)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := c.keyManager.Sign(msgHash, p2.NewPoseidon2())
+	if err != nil {
+		return nil, err
+	}
+	info.SignedHash = hex.EncodeToString(msgHash)
+	info.Sig = sig
+	return info, nil
 }
 
 func (c *TxClient) GetModifyOrderTransaction(tx *types.ModifyOrderTxReq, ops *types.TransactOpts) (*txtypes.L2ModifyOrderTxInfo, error) {
+	if c.risk != nil {
+		if err := c.risk.CheckModify(tx); err != nil {
+			return nil, err
+		}
+	}
 	ops, err := c.fulfillDefaultOps(ops)
 	if err != nil {
 		return nil, err
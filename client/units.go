@@ -0,0 +1,64 @@
+package client
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// PriceToFloat converts an integer order price into quote units using the market price decimals.
+func PriceToFloat(detail *lighterapi.OrderBookDetail, price uint32) float64 {
+	return float64(price) / math.Pow10(int(detail.PriceDecimals))
+}
+
+// PriceFromFloat converts a human price into the integer representation expected by the signer.
+func PriceFromFloat(detail *lighterapi.OrderBookDetail, price float64) uint32 {
+	return uint32(math.Round(price * math.Pow10(int(detail.PriceDecimals))))
+}
+
+// BaseToFloat converts an integer base amount into base units using the market size decimals.
+func BaseToFloat(detail *lighterapi.OrderBookDetail, base int64) float64 {
+	return float64(base) / math.Pow10(int(detail.SizeDecimals))
+}
+
+// BaseFromFloat converts a human size into the integer base amount expected by the signer.
+func BaseFromFloat(detail *lighterapi.OrderBookDetail, size float64) int64 {
+	return int64(math.Round(size * math.Pow10(int(detail.SizeDecimals))))
+}
+
+// USDCToFloat converts an integer USDC amount (6 decimals) into dollars.
+func USDCToFloat(amount int64) float64 {
+	return float64(amount) / txtypes.OneUSDC
+}
+
+// USDCFromFloat converts dollars into the integer USDC amount used by transfers and withdrawals.
+func USDCFromFloat(amount float64) int64 {
+	return int64(math.Round(amount * txtypes.OneUSDC))
+}
+
+// MarginFractionToLeverage converts a margin fraction expressed in MarginFractionTick units into leverage.
+func MarginFractionToLeverage(fraction int) float64 {
+	if fraction <= 0 {
+		return 0
+	}
+	return float64(txtypes.MarginFractionTick) / float64(fraction)
+}
+
+// LeverageToMarginFraction converts leverage into a margin fraction expressed in MarginFractionTick units.
+func LeverageToMarginFraction(leverage float64) int {
+	if leverage <= 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(txtypes.MarginFractionTick) / leverage))
+}
+
+func parseDecimal(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
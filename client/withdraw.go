@@ -0,0 +1,306 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/elliottech/lighter-go/types"
+)
+
+// WithdrawRoute selects how funds leave L2
+type WithdrawRoute string
+
+const (
+	// WithdrawAuto uses the fast bridge when it is configured and the amount fits its limit
+	WithdrawAuto     WithdrawRoute = "auto"
+	WithdrawStandard WithdrawRoute = "secure"
+	WithdrawFast     WithdrawRoute = "fast"
+)
+
+const (
+	defaultWithdrawPollInterval = 10 * time.Second
+	defaultFastBridgeETA        = 5 * time.Minute
+)
+
+// WithdrawOptions configures Withdraw
+type WithdrawOptions struct {
+	Route WithdrawRoute
+	// FastBridgeAccount is the L2 account fronting fast withdrawals. Fast withdrawals are
+	// transfers to it carrying the destination L1Address as memo; without it every
+	// withdrawal takes the standard route.
+	FastBridgeAccount int64
+	L1Address         string
+	// FastBridgeETA is the expected fast bridge latency; defaults to 5 minutes
+	FastBridgeETA time.Duration
+	// NoWait returns once the withdrawal is submitted instead of tracking it to L1
+	NoWait       bool
+	PollInterval time.Duration
+	OnStatus     func(WithdrawUpdate)
+}
+
+// WithdrawUpdate reports a change in a withdrawal's status
+type WithdrawUpdate struct {
+	TxHash string
+	Route  WithdrawRoute
+	Status lighterapi.WithdrawHistoryItemStatus
+	Item   *lighterapi.WithdrawHistoryItem
+}
+
+// WithdrawStatusSubmitted is reported before the withdrawal appears in WithdrawHistory
+const WithdrawStatusSubmitted lighterapi.WithdrawHistoryItemStatus = "submitted"
+
+// WithdrawResult describes a submitted or completed withdrawal
+type WithdrawResult struct {
+	TxHash           string
+	Route            WithdrawRoute
+	Amount           float64
+	EstimatedArrival time.Time
+	Status           lighterapi.WithdrawHistoryItemStatus
+	L1TxHash         string
+	Item             *lighterapi.WithdrawHistoryItem
+}
+
+// Withdraw sends amount USDC to L1, choosing the bridge from FastbridgeInfo and the amount,
+// and estimating arrival from WithdrawalDelay. Unless opts.NoWait is set it then tracks the
+// withdrawal through WithdrawHistory until an L1TxHash appears, reporting each status change
+// to opts.OnStatus.
+func (c *TxClient) Withdraw(ctx context.Context, amount float64, opts *WithdrawOptions) (*WithdrawResult, error) {
+	var o WithdrawOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Route == "" {
+		o.Route = WithdrawAuto
+	}
+	usdc := USDCFromFloat(amount)
+	if usdc <= 0 {
+		return nil, errors.New("client: withdrawal amount must be positive")
+	}
+
+	route, err := c.withdrawRoute(ctx, amount, o)
+	if err != nil {
+		return nil, err
+	}
+	known, err := c.withdrawIds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		resp *lighterapi.RespSendTx
+		eta  time.Duration
+	)
+	if route == WithdrawFast {
+		fee, err := c.TransferFee(ctx, o.FastBridgeAccount)
+		if err != nil {
+			return nil, err
+		}
+		req := &types.TransferTxReq{ToAccountIndex: o.FastBridgeAccount, USDCAmount: usdc, Fee: USDCFromFloat(fee)}
+		if req.Memo, err = l1AddressMemo(o.L1Address); err != nil {
+			return nil, err
+		}
+		tx, err := c.GetTransferTransaction(req, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.Send(ctx, tx, nil); err != nil {
+			return nil, err
+		}
+		eta = o.FastBridgeETA
+		if eta <= 0 {
+			eta = defaultFastBridgeETA
+		}
+	} else {
+		delay, err := c.api.WithdrawalDelay(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := c.GetWithdrawTransaction(&types.WithdrawTxReq{USDCAmount: uint64(usdc)}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.Send(ctx, tx, nil); err != nil {
+			return nil, err
+		}
+		eta = time.Duration(delay.Seconds) * time.Second
+	}
+
+	result := &WithdrawResult{
+		TxHash:           resp.TxHash,
+		Route:            route,
+		Amount:           USDCToFloat(usdc),
+		EstimatedArrival: time.Now().Add(time.Duration(resp.PredictedExecutionTimeMs)*time.Millisecond + eta),
+		Status:           WithdrawStatusSubmitted,
+	}
+	if o.OnStatus != nil {
+		o.OnStatus(WithdrawUpdate{TxHash: result.TxHash, Route: route, Status: result.Status})
+	}
+	if o.NoWait {
+		return result, nil
+	}
+	return result, c.trackWithdrawal(ctx, result, known, o)
+}
+
+// withdrawRoute resolves WithdrawAuto and validates an explicit fast route
+func (c *TxClient) withdrawRoute(ctx context.Context, amount float64, o WithdrawOptions) (WithdrawRoute, error) {
+	switch o.Route {
+	case WithdrawStandard:
+		return WithdrawStandard, nil
+	case WithdrawFast, WithdrawAuto:
+	default:
+		return "", fmt.Errorf("client: unknown withdraw route %q", o.Route)
+	}
+
+	if o.FastBridgeAccount == 0 || o.L1Address == "" {
+		if o.Route == WithdrawFast {
+			return "", errors.New("client: fast withdrawals need FastBridgeAccount and L1Address")
+		}
+		return WithdrawStandard, nil
+	}
+	if _, err := l1AddressMemo(o.L1Address); err != nil {
+		return "", err
+	}
+	info, err := c.api.FastbridgeInfo(ctx)
+	if err != nil {
+		if o.Route == WithdrawFast {
+			return "", err
+		}
+		return WithdrawStandard, nil
+	}
+	if limit := parseDecimal(info.FastBridgeLimit); amount > limit {
+		if o.Route == WithdrawFast {
+			return "", fmt.Errorf("client: amount %s exceeds fast bridge limit %s", formatFloat(amount), info.FastBridgeLimit)
+		}
+		return WithdrawStandard, nil
+	}
+	return WithdrawFast, nil
+}
+
+// l1AddressMemo decodes a 0x-prefixed 20 byte L1 address into the leading bytes of a
+// transfer memo, the form the fast bridge reads the destination from
+func l1AddressMemo(address string) ([32]byte, error) {
+	var memo [32]byte
+	raw, ok := strings.CutPrefix(strings.TrimSpace(address), "0x")
+	if !ok {
+		raw, ok = strings.CutPrefix(strings.TrimSpace(address), "0X")
+	}
+	b, err := hex.DecodeString(raw)
+	if !ok || err != nil || len(b) != 20 {
+		return memo, fmt.Errorf("client: invalid L1 address %q, want 0x and 40 hex digits", address)
+	}
+	copy(memo[:], b)
+	return memo, nil
+}
+
+// TransferFee returns the fee, in USDC, TransferFeeInfo reports for a transfer to another account
+func (c *TxClient) TransferFee(ctx context.Context, to int64) (float64, error) {
+	token, err := c.GetAuthToken(time.Now().Add(accountAuthTTL))
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.api.TransferFeeInfo(ctx, &lighterapi.TransferFeeInfoParams{
+		AccountIndex:   c.accountIndex,
+		ToAccountIndex: &to,
+		Authorization:  &token,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return USDCToFloat(resp.TransferFeeUsdc), nil
+}
+
+func (c *TxClient) withdrawHistory(ctx context.Context) ([]lighterapi.WithdrawHistoryItem, error) {
+	token, err := c.GetAuthToken(time.Now().Add(accountAuthTTL))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.api.WithdrawHistory(ctx, &lighterapi.WithdrawHistoryParams{
+		AccountIndex:  c.accountIndex,
+		Authorization: &token,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Withdraws, nil
+}
+
+// withdrawIds returns the ids already listed in WithdrawHistory, so a new entry can be told apart
+func (c *TxClient) withdrawIds(ctx context.Context) (map[string]bool, error) {
+	items, err := c.withdrawHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(items))
+	for _, item := range items {
+		ids[item.Id] = true
+	}
+	return ids, nil
+}
+
+// trackWithdrawal polls WithdrawHistory for the first new entry of the same route and amount
+// and follows it until it carries an L1TxHash or fails
+func (c *TxClient) trackWithdrawal(ctx context.Context, result *WithdrawResult, known map[string]bool, o WithdrawOptions) error {
+	interval := o.PollInterval
+	if interval <= 0 {
+		interval = defaultWithdrawPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		items, err := c.withdrawHistory(ctx)
+		if err == nil {
+			if item := matchWithdrawal(items, known, result); item != nil {
+				if item.Status != result.Status {
+					result.Status = item.Status
+					if o.OnStatus != nil {
+						o.OnStatus(WithdrawUpdate{TxHash: result.TxHash, Route: result.Route, Status: item.Status, Item: item})
+					}
+				}
+				result.Item = item
+				result.L1TxHash = item.L1TxHash
+				switch {
+				case item.L1TxHash != "":
+					return nil
+				case item.Status == lighterapi.WithdrawHistoryItemStatusFailed || item.Status == lighterapi.WithdrawHistoryItemStatusRefunded:
+					return fmt.Errorf("client: withdrawal %s %s", result.TxHash, item.Status)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("client: track withdrawal %s: %w", result.TxHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func matchWithdrawal(items []lighterapi.WithdrawHistoryItem, known map[string]bool, result *WithdrawResult) *lighterapi.WithdrawHistoryItem {
+	if result.Item != nil {
+		for i := range items {
+			if items[i].Id == result.Item.Id {
+				return &items[i]
+			}
+		}
+		return nil
+	}
+	for i := range items {
+		item := &items[i]
+		if known[item.Id] || string(item.Type) != string(result.Route) {
+			continue
+		}
+		if math.Abs(parseDecimal(item.Amount)-result.Amount) < USDCToFloat(1) {
+			return item
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const withdrawL1 = "0x00112233445566778899AaBbCcDdEeFf00112233"
+
+// serveWithdrawals answers the withdrawal info endpoints: a 5000 USDC fast bridge limit, a
+// one hour standard delay, and a history that lists each item once calls reaches its index
+func serveWithdrawals(srv *lightertest.Server, items ...lighterapi.WithdrawHistoryItem) {
+	srv.HandleJSON("/api/v1/fastbridge/info", lighterapi.RespGetFastBridgeInfo{Code: 200, FastBridgeLimit: "5000"})
+	srv.HandleJSON("/api/v1/withdrawalDelay", lighterapi.RespWithdrawalDelay{Seconds: 3600})
+	calls := 0
+	srv.Handle("/api/v1/withdraw/history", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := lighterapi.WithdrawHistory{Code: 200}
+		if calls > 0 && len(items) > 0 {
+			resp.Withdraws = []lighterapi.WithdrawHistoryItem{items[min(calls, len(items))-1]}
+		}
+		calls++
+		writeJSON(w, resp)
+	}))
+}
+
+func TestWithdrawFastEncodesL1AddressMemo(t *testing.T) {
+	srv, _, tx := lightertest.NewExchange(t)
+	serveWithdrawals(srv)
+	srv.SetTransferFee(1)
+
+	res, err := tx.Withdraw(context.Background(), 1000, &client.WithdrawOptions{
+		FastBridgeAccount: lightertest.MakerAccount,
+		L1Address:         withdrawL1,
+		NoWait:            true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Route != client.WithdrawFast || res.Status != client.WithdrawStatusSubmitted || time.Until(res.EstimatedArrival) > 5*time.Minute+time.Second {
+		t.Fatalf("result %+v, want a fast withdrawal arriving within 5 minutes", res)
+	}
+	if got := collateral(t, srv, lightertest.MakerAccount); !approx(got, 11000) {
+		t.Fatalf("fast bridge collateral %v, want 11000", got)
+	}
+	if got := collateral(t, srv, testAccount); !approx(got, 8999) {
+		t.Fatalf("trader collateral %v, want 8999 after the amount and fee", got)
+	}
+
+	// The bridge reads the destination from the first 20 bytes of the memo
+	var memo [32]byte
+	srv.Handle("/api/v1/sendTx", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var info txtypes.L2TransferTxInfo
+		if err := json.Unmarshal([]byte(r.FormValue("tx_info")), &info); err != nil {
+			t.Error(err)
+		}
+		memo = info.Memo
+		writeJSON(w, lighterapi.RespSendTx{Code: 200, TxHash: "0xabc"})
+	}))
+	if _, err := tx.Withdraw(context.Background(), 10, &client.WithdrawOptions{
+		Route:             client.WithdrawFast,
+		FastBridgeAccount: lightertest.MakerAccount,
+		L1Address:         withdrawL1,
+		NoWait:            true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := [32]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00, 0x11, 0x22, 0x33}
+	if memo != want {
+		t.Fatalf("memo %x, want %x", memo, want)
+	}
+}
+
+func TestWithdrawRouteChoice(t *testing.T) {
+	srv, _, tx := lightertest.NewExchange(t)
+	serveWithdrawals(srv)
+	ctx := context.Background()
+	fast := func(route client.WithdrawRoute, l1Address string) *client.WithdrawOptions {
+		return &client.WithdrawOptions{Route: route, FastBridgeAccount: lightertest.MakerAccount, L1Address: l1Address, NoWait: true}
+	}
+
+	// Above the fast bridge limit auto falls back to the standard bridge
+	res, err := tx.Withdraw(ctx, 6000, fast(client.WithdrawAuto, withdrawL1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Route != client.WithdrawStandard || time.Until(res.EstimatedArrival) < 59*time.Minute {
+		t.Fatalf("result %+v, want a standard withdrawal arriving in about an hour", res)
+	}
+	if got := collateral(t, srv, testAccount); !approx(got, 4000) {
+		t.Fatalf("trader collateral %v, want 4000", got)
+	}
+	// Without a fast bridge account every withdrawal is standard
+	if res, err = tx.Withdraw(ctx, 10, &client.WithdrawOptions{NoWait: true}); err != nil || res.Route != client.WithdrawStandard {
+		t.Fatalf("route %+v (%v), want standard", res, err)
+	}
+
+	if _, err := tx.Withdraw(ctx, 6000, fast(client.WithdrawFast, withdrawL1)); err == nil {
+		t.Fatal("expected a fast withdrawal above the limit to be refused")
+	}
+	if _, err := tx.Withdraw(ctx, 10, fast(client.WithdrawFast, "0x1234")); err == nil {
+		t.Fatal("expected a malformed L1 address to be refused")
+	}
+	if _, err := tx.Withdraw(ctx, 10, fast("slow", withdrawL1)); err == nil {
+		t.Fatal("expected an unknown route to be refused")
+	}
+	if _, err := tx.Withdraw(ctx, 0, nil); err == nil {
+		t.Fatal("expected a zero amount to be refused")
+	}
+}
+
+func TestWithdrawTracksHistoryToL1(t *testing.T) {
+	srv, _, tx := lightertest.NewExchange(t)
+	pending := lighterapi.WithdrawHistoryItem{Id: "w1", Amount: "250.000000", Status: lighterapi.WithdrawHistoryItemStatusPending, Type: lighterapi.Secure}
+	done := pending
+	done.Status, done.L1TxHash = lighterapi.WithdrawHistoryItemStatusCompleted, "0xl1"
+	serveWithdrawals(srv, pending, done)
+
+	var statuses []lighterapi.WithdrawHistoryItemStatus
+	res, err := tx.Withdraw(context.Background(), 250, &client.WithdrawOptions{
+		PollInterval: time.Millisecond,
+		OnStatus:     func(u client.WithdrawUpdate) { statuses = append(statuses, u.Status) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.L1TxHash != "0xl1" || res.Status != lighterapi.WithdrawHistoryItemStatusCompleted {
+		t.Fatalf("result %+v, want the completed L1 transaction", res)
+	}
+	want := []lighterapi.WithdrawHistoryItemStatus{client.WithdrawStatusSubmitted, lighterapi.WithdrawHistoryItemStatusPending, lighterapi.WithdrawHistoryItemStatusCompleted}
+	if len(statuses) != len(want) {
+		t.Fatalf("statuses %v, want %v", statuses, want)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("statuses %v, want %v", statuses, want)
+		}
+	}
+}
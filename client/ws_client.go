@@ -35,6 +35,10 @@ type WSClient struct {
 	// Connection state callbacks - like Python version
 	onConnected    func()
 	onDisconnected func()
+
+	// Inbound frame recording; ownsRecorder is set when Connect opened it from RecordPath
+	recorder     *WSRecorder
+	ownsRecorder bool
 }
 
 type WSHandler func(data []byte) error
@@ -110,6 +114,15 @@ func (ws *WSClient) Connect(ctx context.Context) error {
 		return fmt.Errorf("failed to connect to WebSocket: %v", err)
 	}
 
+	if ws.config.RecordPath != "" && ws.recorder == nil {
+		rec, err := CreateWSRecorder(ws.config.RecordPath)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		ws.recorder, ws.ownsRecorder = rec, true
+	}
+
 	ws.conn = conn
 	ws.isConnected = true
 
@@ -126,6 +139,13 @@ func (ws *WSClient) Disconnect() error {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 
+	if ws.ownsRecorder {
+		if err := ws.recorder.Close(); err != nil {
+			log.Printf("[WSClient] Failed to close recording: %v", err)
+		}
+		ws.recorder, ws.ownsRecorder = nil, false
+	}
+
 	if !ws.isConnected {
 		return nil
 	}
@@ -274,6 +294,15 @@ func (ws *WSClient) readMessages(ctx context.Context) {
 				return
 			}
 
+			ws.mu.RLock()
+			recorder := ws.recorder
+			ws.mu.RUnlock()
+			if recorder != nil {
+				if err := recorder.Record(data); err != nil {
+					log.Printf("[WSClient] Failed to record frame: %v", err)
+				}
+			}
+
 			ws.handleMessage(data)
 		}
 	}
@@ -300,14 +329,17 @@ func (ws *WSClient) handleMessage(data []byte) {
 	}
 
 	// Only log occasional message types for debugging to avoid flooding output
-	if msg.Type != "update/order_book" && msg.Type != MessageTypePong && msg.Type != MessageTypePing {
+	if msg.Type != "update/order_book" && msg.Type != MessageTypeTradeUpdate && msg.Type != MessageTypePong && msg.Type != MessageTypePing {
 		log.Printf("[WSClient] Parsed message type: %s", msg.Type)
 	}
 
 	// Handle specific message types like Python version
 	switch msg.Type {
 	case MessageTypePing:
-		// Server sent ping, respond with pong
+		// Server sent ping, respond with pong; replayed pings have no connection to answer on
+		if !ws.IsConnected() {
+			return
+		}
 		pong := WSMessage{Type: MessageTypePong}
 		if err := ws.sendMessage(pong); err != nil {
 			log.Printf("[WSClient] Failed to send pong: %v", err)
@@ -345,9 +377,10 @@ func (ws *WSClient) handleMessage(data []byte) {
 	ws.mu.RLock()
 	handlers := ws.handlers[msg.Type]
 
-	// For orderbook messages, also try MarketID-specific handlers
+	// For orderbook and trade messages, also try MarketID-specific handlers
 	var marketIdHandlers []WSHandler
-	if msg.Type == MessageTypeOrderBookSubscribed || msg.Type == MessageTypeOrderBookUpdate {
+	if msg.Type == MessageTypeOrderBookSubscribed || msg.Type == MessageTypeOrderBookUpdate ||
+		msg.Type == MessageTypeTradeSubscribed || msg.Type == MessageTypeTradeUpdate {
 		// Extract MarketID from the message to route to specific handlers
 		marketId := ws.extractMarketIdFromMessage(data)
 		if marketId >= 0 {
@@ -388,9 +421,9 @@ func (ws *WSClient) handleMessage(data []byte) {
 	}
 }
 
-// extractMarketIdFromMessage extracts MarketID from orderbook messages
+// extractMarketIdFromMessage extracts MarketID from orderbook and trade messages
 func (ws *WSClient) extractMarketIdFromMessage(data []byte) int {
-	// Try to parse as orderbook message to extract MarketID
+	// Try to parse as market message to extract MarketID
 	var msg struct {
 		Channel string `json:"channel"`
 	}
@@ -399,8 +432,8 @@ func (ws *WSClient) extractMarketIdFromMessage(data []byte) int {
 		return -1
 	}
 
-	// Parse MarketID from channel format like "order_book:1"
-	if strings.HasPrefix(msg.Channel, "order_book:") {
+	// Parse MarketID from channel format like "order_book:1" or "trade:1"
+	if strings.HasPrefix(msg.Channel, ChannelOrderBook+":") || strings.HasPrefix(msg.Channel, ChannelTrade+":") {
 		if parts := strings.Split(msg.Channel, ":"); len(parts) == 2 {
 			if id, err := strconv.Atoi(parts[1]); err == nil {
 				return id
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
+
+	lighterapi "github.com/defi-maker/golighter/api"
 )
 
 // LighterWebsocketPrivateService implements the new Bybit-style private interface
@@ -177,20 +180,113 @@ func (s *LighterWebsocketPrivateService) SubscribeAccount(
 	return unsubFunc, nil
 }
 
-// SubscribeOrders implements LighterWebsocketPrivateServiceI
+// SubscribeOrders implements LighterWebsocketPrivateServiceI over the account_all_orders
+// channel. The subscription snapshot lists the open orders; each later update carries the
+// orders that changed, including their final status.
 func (s *LighterWebsocketPrivateService) SubscribeOrders(
 	param LighterOrdersParamKey,
 	callback func(LighterOrdersResponse) error,
 ) (func() error, error) {
-	// For now, orders are included in account updates
-	// We can extract order-specific data from account updates
-	return s.SubscribeAccount(
-		LighterAccountParamKey{AccountId: param.AccountId},
-		func(accountResponse LighterAccountResponse) error {
-			// TODO: Extract order updates from account response if needed
-			// For now, we don't have separate order updates
-			log.Printf("[LighterWS] Order updates are included in account updates for account %d", param.AccountId)
+	key := fmt.Sprintf("orders_%d", param.AccountId)
+
+	// Check if already subscribed
+	s.mu.RLock()
+	if _, exists := s.subscriptions[key]; exists {
+		s.mu.RUnlock()
+		return nil, fmt.Errorf("already subscribed to orders of account %d", param.AccountId)
+	}
+	s.mu.RUnlock()
+
+	// Create subscription context
+	subCtx, subCancel := context.WithCancel(s.ctx)
+
+	handler := func(data []byte) error {
+		if subCtx.Err() != nil {
+			return nil
+		}
+		var update WSAccountOrdersUpdate
+		if err := json.Unmarshal(data, &update); err != nil {
+			return fmt.Errorf("failed to unmarshal account orders update: %v", err)
+		}
+		if update.Account != 0 && update.Account != param.AccountId {
 			return nil
-		},
-	)
+		}
+		snapshot := update.Type == MessageTypeAccountOrdersSubscribed
+		for _, orders := range update.Orders {
+			for _, o := range orders {
+				if o.OwnerAccountIndex != 0 && o.OwnerAccountIndex != param.AccountId {
+					continue
+				}
+				if err := callback(orderResponse(o, param.AccountId, snapshot)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	s.wsClient.AddHandler(MessageTypeAccountOrdersUpdate, handler)
+	s.wsClient.AddHandler(MessageTypeAccountOrdersSubscribed, handler)
+
+	// Connect to WebSocket first
+	if err := s.wsClient.Connect(subCtx); err != nil {
+		subCancel()
+		return nil, fmt.Errorf("failed to connect to WebSocket: %w", err)
+	}
+
+	channel := fmt.Sprintf("%s/%d", ChannelAccountOrders, param.AccountId)
+	if err := s.wsClient.Subscribe(channel, ""); err != nil {
+		subCancel()
+		return nil, fmt.Errorf("failed to subscribe to channel %s: %w", channel, err)
+	}
+
+	unsubFunc := func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if sub, exists := s.subscriptions[key]; exists {
+			if sub.cancelFunc != nil {
+				sub.cancelFunc()
+			}
+			delete(s.subscriptions, key)
+			log.Printf("[LighterWS] Unsubscribed from orders of account %d", param.AccountId)
+		}
+		return nil
+	}
+
+	s.mu.Lock()
+	s.subscriptions[key] = &Subscription{
+		key:        key,
+		unsubFunc:  unsubFunc,
+		cancelFunc: subCancel,
+	}
+	s.mu.Unlock()
+
+	log.Printf("[LighterWS] Subscribed to orders of account %d", param.AccountId)
+	return unsubFunc, nil
+}
+
+// orderResponse converts an order of the account_all_orders channel
+func orderResponse(o lighterapi.Order, accountId int64, snapshot bool) LighterOrdersResponse {
+	orderId := o.OrderId
+	if orderId == "" {
+		orderId = strconv.FormatInt(o.OrderIndex, 10)
+	}
+	isAsk := uint8(0)
+	if o.IsAsk {
+		isAsk = 1
+	}
+	return LighterOrdersResponse{
+		AccountId:        accountId,
+		OrderId:          orderId,
+		ClientOrderIndex: o.ClientOrderIndex,
+		MarketId:         o.MarketIndex,
+		Status:           string(o.Status),
+		BaseQuantity:     o.InitialBaseAmount,
+		FilledQuantity:   o.FilledBaseAmount,
+		Price:            o.Price,
+		IsAsk:            isAsk,
+		Timestamp:        o.Timestamp,
+		IsSnapshot:       snapshot,
+	}
 }
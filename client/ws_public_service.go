@@ -161,11 +161,51 @@ func (s *LighterWebsocketPublicService) SubscribeTrades(
 	param LighterTradesParamKey,
 	callback func(LighterTradesResponse) error,
 ) (func() error, error) {
-	_ = fmt.Sprintf("trades_%d", param.MarketId) // TODO: implement
+	key := fmt.Sprintf("trades_%d", param.MarketId)
 
-	// Implementation similar to SubscribeOrderBook...
-	// For now, return a placeholder
-	return func() error { return nil }, fmt.Errorf("trades subscription not yet implemented")
+	// Check if already subscribed
+	s.mu.RLock()
+	if _, exists := s.subscriptions[key]; exists {
+		s.mu.RUnlock()
+		return nil, fmt.Errorf("already subscribed to trades for market %d", param.MarketId)
+	}
+	s.mu.RUnlock()
+
+	// Create subscription context
+	subCtx, subCancel := context.WithCancel(s.ctx)
+
+	err := s.startTradeService(subCtx, param.MarketId, callback)
+	if err != nil {
+		subCancel()
+		return nil, fmt.Errorf("failed to start trade service: %w", err)
+	}
+
+	// Create unsubscribe function
+	unsubFunc := func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if sub, exists := s.subscriptions[key]; exists {
+			if sub.cancelFunc != nil {
+				sub.cancelFunc()
+			}
+			delete(s.subscriptions, key)
+			log.Printf("[LighterWS] Unsubscribed from trades market %d", param.MarketId)
+		}
+		return nil
+	}
+
+	// Store subscription
+	s.mu.Lock()
+	s.subscriptions[key] = &Subscription{
+		key:        key,
+		unsubFunc:  unsubFunc,
+		cancelFunc: subCancel,
+	}
+	s.mu.Unlock()
+
+	log.Printf("[LighterWS] Subscribed to trades market %d", param.MarketId)
+	return unsubFunc, nil
 }
 
 // SubscribeAccount implements LighterWebsocketPublicServiceI
@@ -220,6 +260,82 @@ func (s *LighterWebsocketPublicService) startOrderBookService(
 	return nil
 }
 
+// startTradeService is the internal method that handles trade subscriptions
+func (s *LighterWebsocketPublicService) startTradeService(
+	ctx context.Context,
+	marketId uint8,
+	callback func(LighterTradesResponse) error,
+) error {
+	channel := fmt.Sprintf("%s/%d", ChannelTrade, marketId)
+	if err := s.wsClient.Subscribe(channel, ""); err != nil {
+		return fmt.Errorf("failed to subscribe to channel %s: %w", channel, err)
+	}
+
+	snapshotKey := fmt.Sprintf("%s_%d", MessageTypeTradeSubscribed, marketId)
+	updateKey := fmt.Sprintf("%s_%d", MessageTypeTradeUpdate, marketId)
+
+	snapshotHandler := func(data []byte) error {
+		return s.handleTrades(data, marketId, true, callback)
+	}
+
+	updateHandler := func(data []byte) error {
+		return s.handleTrades(data, marketId, false, callback)
+	}
+
+	s.wsClient.AddHandler(snapshotKey, snapshotHandler)
+	s.wsClient.AddHandler(updateKey, updateHandler)
+
+	// Wait for context cancellation
+	go func() {
+		<-ctx.Done()
+		s.wsClient.RemoveHandler(snapshotKey)
+		s.wsClient.RemoveHandler(updateKey)
+		s.wsClient.Unsubscribe(channel, "")
+	}()
+
+	return nil
+}
+
+// handleTrades processes trade snapshot and update messages, invoking the callback once per trade
+func (s *LighterWebsocketPublicService) handleTrades(
+	data []byte,
+	marketId uint8,
+	isSnapshot bool,
+	callback func(LighterTradesResponse) error,
+) error {
+	var msg struct {
+		Type    string    `json:"type"`
+		Channel string    `json:"channel"`
+		Trades  []WSTrade `json:"trades"`
+	}
+
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to unmarshal trades: %w", err)
+	}
+
+	for i := range msg.Trades {
+		trade := msg.Trades[i]
+		side := "buy"
+		if !trade.IsMakerAsk {
+			side = "sell"
+		}
+		response := LighterTradesResponse{
+			MarketId:   marketId,
+			TradeId:    trade.TradeId,
+			Price:      trade.Price,
+			Quantity:   trade.Size,
+			Side:       side,
+			Timestamp:  trade.Timestamp,
+			IsSnapshot: isSnapshot,
+			RawTrade:   &trade,
+		}
+		if err := callback(response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // handleOrderBookSnapshot processes snapshot messages
 func (s *LighterWebsocketPublicService) handleOrderBookSnapshot(
 	data []byte,
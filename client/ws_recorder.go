@@ -0,0 +1,183 @@
+package client
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WSFrame is one recorded inbound frame. Offset is measured on the monotonic clock from the
+// start of the recording.
+type WSFrame struct {
+	Offset time.Duration `json:"t"`
+	At     time.Time     `json:"at"`
+	Data   string        `json:"data"`
+}
+
+// WSRecorder writes inbound frames as gzip-compressed JSON lines
+type WSRecorder struct {
+	mu     sync.Mutex
+	start  time.Time
+	gz     *gzip.Writer
+	enc    *json.Encoder
+	closer io.Closer
+	err    error
+}
+
+// NewWSRecorder records frames to w. Close flushes the compressed stream but does not close w.
+func NewWSRecorder(w io.Writer) *WSRecorder {
+	gz := gzip.NewWriter(w)
+	return &WSRecorder{start: time.Now(), gz: gz, enc: json.NewEncoder(gz)}
+}
+
+// CreateWSRecorder records frames to a file, appending a new gzip member when it already exists
+func CreateWSRecorder(path string) (*WSRecorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("client: open ws recording: %w", err)
+	}
+	rec := NewWSRecorder(f)
+	rec.closer = f
+	return rec, nil
+}
+
+// Record appends a frame. The first write error is kept and returned by later calls and Close.
+func (r *WSRecorder) Record(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	now := time.Now()
+	r.err = r.enc.Encode(WSFrame{Offset: now.Sub(r.start), At: now.UTC(), Data: string(data)})
+	return r.err
+}
+
+// Close flushes the recording and closes the file opened by CreateWSRecorder
+func (r *WSRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.gz.Close()
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+		r.closer = nil
+	}
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+// WSRecordingReader reads frames written by WSRecorder
+type WSRecordingReader struct {
+	gz     *gzip.Reader
+	dec    *json.Decoder
+	closer io.Closer
+}
+
+// NewWSRecordingReader reads a recording from r
+func NewWSRecordingReader(r io.Reader) (*WSRecordingReader, error) {
+	gz, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("client: read ws recording: %w", err)
+	}
+	return &WSRecordingReader{gz: gz, dec: json.NewDecoder(gz)}, nil
+}
+
+// OpenWSRecording opens a recording file
+func OpenWSRecording(path string) (*WSRecordingReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("client: open ws recording: %w", err)
+	}
+	reader, err := NewWSRecordingReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	reader.closer = f
+	return reader, nil
+}
+
+// Next returns the next frame, or io.EOF at the end of the recording
+func (r *WSRecordingReader) Next() (WSFrame, error) {
+	var frame WSFrame
+	if err := r.dec.Decode(&frame); err != nil {
+		if errors.Is(err, io.EOF) {
+			return WSFrame{}, io.EOF
+		}
+		return WSFrame{}, fmt.Errorf("client: decode ws frame: %w", err)
+	}
+	return frame, nil
+}
+
+// Close releases the reader and the file opened by OpenWSRecording
+func (r *WSRecordingReader) Close() error {
+	err := r.gz.Close()
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// SetRecorder records every inbound frame to rec before it is handled; pass nil to stop.
+// WSConfig.RecordPath sets one up on Connect instead.
+func (ws *WSClient) SetRecorder(rec *WSRecorder) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.recorder = rec
+}
+
+// Replay feeds recorded frames through the client's message handling as if they had just
+// been received, so registered handlers and services see the original stream. A speed of 1
+// keeps the recorded pacing, 2 plays twice as fast, and 0 replays as fast as possible.
+func (ws *WSClient) Replay(ctx context.Context, r *WSRecordingReader, speed float64) error {
+	var (
+		last    time.Duration
+		started bool
+	)
+	for {
+		frame, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Offsets restart in each appended recording session
+		if gap := frame.Offset - last; started && speed > 0 && gap > 0 {
+			timer := time.NewTimer(time.Duration(float64(gap) / speed))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		last, started = frame.Offset, true
+		ws.handleMessage([]byte(frame.Data))
+	}
+}
+
+// ReplayFile replays a recording file through the client; see Replay
+func (ws *WSClient) ReplayFile(ctx context.Context, path string, speed float64) error {
+	r, err := OpenWSRecording(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return ws.Replay(ctx, r, speed)
+}
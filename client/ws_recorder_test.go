@@ -0,0 +1,170 @@
+package client_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+)
+
+// frameLog collects the frames handlers see
+type frameLog struct {
+	mu     sync.Mutex
+	frames []string
+}
+
+func (l *frameLog) handler(data []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.frames = append(l.frames, string(data))
+	return nil
+}
+
+func (l *frameLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.frames...)
+}
+
+func watchOrderBook(ws *client.WSClient, log *frameLog) {
+	ws.AddHandler(client.MessageTypeOrderBookSubscribed, log.handler)
+	ws.AddHandler(client.MessageTypeOrderBookUpdate, log.handler)
+}
+
+func TestWSRecordingReplaysThroughHandlers(t *testing.T) {
+	fake := lightertest.NewWSServer()
+	t.Cleanup(fake.Close)
+	fake.SetOrderBook(0, []client.WSPriceLevel{{Price: "3000.00", Size: "1.0000"}}, []client.WSPriceLevel{{Price: "3001.00", Size: "2.0000"}})
+	path := filepath.Join(t.TempDir(), "stream.gz")
+	cfg := fake.Config()
+	cfg.RecordPath = path
+
+	var live frameLog
+	ws := client.NewWSClient(cfg)
+	watchOrderBook(ws, &live)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ws.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Subscribe("order_book/0", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.WaitForSubscription(ctx, "order_book/0"); err != nil {
+		t.Fatal(err)
+	}
+	fake.PublishOrderBook(0, []client.WSPriceLevel{{Price: "3000.00", Size: "0"}}, nil)
+	for len(live.get()) < 2 {
+		if ctx.Err() != nil {
+			t.Fatalf("saw %d order book frames, want 2", len(live.get()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := ws.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second session appends its own gzip member
+	rec, err := client.CreateWSRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appended := `{"type":"update/order_book","channel":"order_book:0","order_book":{"code":0,"bids":[],"asks":[{"price":"3001.00","size":"0"}],"offset":2}}`
+	for _, frame := range []string{`{"type":"ping"}`, appended} {
+		if err := rec.Record([]byte(frame)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := client.OpenWSRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for {
+		frame, err := r.Next()
+		if err != nil {
+			break
+		}
+		var msg client.WSMessage
+		if err := json.Unmarshal([]byte(frame.Data), &msg); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, msg.Type)
+	}
+	r.Close()
+	want := []string{client.MessageTypeConnected, client.MessageTypeOrderBookSubscribed, client.MessageTypeOrderBookUpdate, client.MessageTypePing, client.MessageTypeOrderBookUpdate}
+	if len(types) != len(want) {
+		t.Fatalf("recorded %v, want %v", types, want)
+	}
+
+	// Replaying needs no connection; the replayed ping is not answered
+	var replayed, byMarket frameLog
+	replay := client.NewWSClient(client.DefaultWSConfig())
+	watchOrderBook(replay, &replayed)
+	replay.AddHandler(client.MessageTypeOrderBookUpdate+"_0", byMarket.handler)
+	if err := replay.ReplayFile(context.Background(), path, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, recorded := replayed.get(), append(live.get(), appended)
+	if len(got) != len(recorded) {
+		t.Fatalf("replayed %d frames, want %d", len(got), len(recorded))
+	}
+	for i := range got {
+		if got[i] != recorded[i] {
+			t.Fatalf("replayed frame %d %s, want %s", i, got[i], recorded[i])
+		}
+	}
+	if n := len(byMarket.get()); n != 2 {
+		t.Fatalf("market handler saw %d updates, want 2", n)
+	}
+}
+
+func TestWSReplayKeepsPacing(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	update := `{"type":"update/order_book","channel":"order_book:0","order_book":{"code":0,"bids":[],"asks":[],"offset":1}}`
+	for _, offset := range []time.Duration{0, time.Hour} {
+		if err := enc.Encode(client.WSFrame{Offset: offset, Data: update}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	recording := buf.Bytes()
+
+	replay := func(speed float64, timeout time.Duration) (int, error) {
+		var log frameLog
+		ws := client.NewWSClient(client.DefaultWSConfig())
+		watchOrderBook(ws, &log)
+		r, err := client.NewWSRecordingReader(bytes.NewReader(recording))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err = ws.Replay(ctx, r, speed)
+		return len(log.get()), err
+	}
+
+	// At recorded speed the hour-long gap outlasts the context
+	if n, err := replay(1, 50*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) || n != 1 {
+		t.Fatalf("replayed %d frames (%v), want 1 before the deadline", n, err)
+	}
+	if n, err := replay(360_000, time.Second); err != nil || n != 2 {
+		t.Fatalf("replayed %d frames (%v), want both with the gap shrunk to 10ms", n, err)
+	}
+}
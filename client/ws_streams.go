@@ -5,7 +5,7 @@ package client
 
 // Note: StreamTicker was removed because Lighter WebSocket API does not support ticker streams
 
-// Note: StreamTrades was removed because Lighter WebSocket API does not support trades streams
+// Note: StreamTrades was removed; use LighterWebsocketPublicService.SubscribeTrades() instead
 
 // Note: StreamMarkPrice was removed because Lighter WebSocket API does not support mark price streams
 
@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
 )
 
 // WebSocket message types
@@ -33,8 +35,8 @@ type WSOrderBookUpdate struct {
 	Timestamp int64      `json:"timestamp"`
 }
 
-// Note: WSTickerUpdate and WSTradeUpdate types removed because
-// ticker and trade streams are not supported by Lighter WebSocket API
+// Note: WSTickerUpdate type removed because ticker streams are not supported
+// by Lighter WebSocket API; trade messages are decoded into WSTrade
 
 // Account data types
 type WSAccountUpdate struct {
@@ -97,12 +99,22 @@ type WSTrade struct {
 	TakerPositionSizeBefore          string `json:"taker_position_size_before"`
 	TakerEntryQuoteBefore            string `json:"taker_entry_quote_before"`
 	TakerInitialMarginFractionBefore int    `json:"taker_initial_margin_fraction_before"`
+	TakerFee                         int    `json:"taker_fee"`
 	MakerFee                         int    `json:"maker_fee"`
 	MakerPositionSizeBefore          string `json:"maker_position_size_before"`
 	MakerEntryQuoteBefore            string `json:"maker_entry_quote_before"`
 	MakerInitialMarginFractionBefore int    `json:"maker_initial_margin_fraction_before"`
 }
 
+// WSAccountOrdersUpdate is a message of the account_all_orders channel. Orders are keyed by
+// market id and carry the same fields as REST orders.
+type WSAccountOrdersUpdate struct {
+	Account int64                         `json:"account"`
+	Channel string                        `json:"channel"`
+	Type    string                        `json:"type"`
+	Orders  map[string][]lighterapi.Order `json:"orders"`
+}
+
 type WSOrderUpdate struct {
 	AccountIndex     int64  `json:"account_index"`
 	OrderId          string `json:"order_id"`
@@ -117,14 +129,15 @@ type WSOrderUpdate struct {
 }
 
 // Channel constants - based on Python implementation
-// Note: Only order_book and account_all are actually supported by Lighter WebSocket API
 const (
 	ChannelOrderBook = "order_book"
 	ChannelAccount   = "account_all"
 	ChannelOrders    = "orders"
+	ChannelTrade     = "trade"
+	// ChannelAccountOrders streams every order change of an account; it needs an auth token
+	ChannelAccountOrders = "account_all_orders"
 	// The following channels are not supported by Lighter WebSocket API:
 	// ChannelTicker    = "ticker"      // REMOVED - not supported
-	// ChannelTrades    = "trades"      // REMOVED - not supported
 	// ChannelMarkPrice = "markprice"   // REMOVED - not supported
 )
 
@@ -141,10 +154,15 @@ const (
 	// Subscription confirmation messages
 	MessageTypeOrderBookSubscribed = "subscribed/order_book"
 	MessageTypeAccountSubscribed   = "subscribed/account_all"
+	MessageTypeTradeSubscribed     = "subscribed/trade"
+
+	MessageTypeAccountOrdersSubscribed = "subscribed/account_all_orders"
+	MessageTypeAccountOrdersUpdate     = "update/account_all_orders"
 
 	// Data update messages (the actual data streams)
 	MessageTypeOrderBookUpdate = "update/order_book"
 	MessageTypeAccountUpdate   = "update/account_all"
+	MessageTypeTradeUpdate     = "update/trade"
 
 	// Deprecated: Use MessageTypeOrderBookUpdate instead
 	MessageTypeOrderBook = "update/order_book"
@@ -190,6 +208,8 @@ type WSConfig struct {
 	ReadTimeout    time.Duration
 	WriteTimeout   time.Duration
 	MaxReconnects  int
+	// RecordPath, when set, records every inbound frame to this gzip file (see WSRecorder)
+	RecordPath string
 }
 
 func DefaultWSConfig() *WSConfig {
@@ -270,12 +290,15 @@ type LighterOrderBookResponse struct {
 // LighterTickerResponse removed - not supported by Lighter
 
 type LighterTradesResponse struct {
-	MarketId  uint8  `json:"market_id"`
-	Symbol    string `json:"symbol"`
-	Price     string `json:"price"`
-	Quantity  string `json:"quantity"`
-	Side      string `json:"side"`
-	Timestamp int64  `json:"timestamp"`
+	MarketId   uint8    `json:"market_id"`
+	Symbol     string   `json:"symbol"`
+	TradeId    int64    `json:"trade_id"`
+	Price      string   `json:"price"`
+	Quantity   string   `json:"quantity"`
+	Side       string   `json:"side"` // taker side: "buy" or "sell"
+	Timestamp  int64    `json:"timestamp"`
+	IsSnapshot bool     `json:"is_snapshot"`
+	RawTrade   *WSTrade `json:"-"`
 }
 
 type LighterAccountResponse struct {
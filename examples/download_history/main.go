@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/examples/internal/shared"
+	"github.com/defi-maker/golighter/history"
+)
+
+func main() {
+	_ = godotenv.Load()
+
+	market := flag.Uint("market", 0, "market id")
+	from := flag.String("from", "", "start of the range (RFC3339 or YYYY-MM-DD, UTC)")
+	to := flag.String("to", "", "end of the range, exclusive (RFC3339 or YYYY-MM-DD, UTC); defaults to now")
+	out := flag.String("out", "data", "output directory")
+	datasets := flag.String("datasets", "trades,candles,fundings", "comma separated datasets: trades,candles,fundings,liquidations")
+	resolution := flag.String("resolution", "1m", "candle resolution: 1m,5m,15m,1h,4h,1d")
+	account := flag.Int64("account", -1, "account index (required for liquidations)")
+	interval := flag.Duration("interval", 250*time.Millisecond, "minimum delay between requests")
+	flag.Parse()
+
+	if *market > math.MaxUint8 {
+		log.Fatalf("[download-history] invalid -market %d, must be at most %d", *market, math.MaxUint8)
+	}
+	start, err := parseTime(*from)
+	if err != nil || *from == "" {
+		log.Fatalf("[download-history] invalid -from %q", *from)
+	}
+	end := time.Now().UTC()
+	if *to != "" {
+		if end, err = parseTime(*to); err != nil {
+			log.Fatalf("[download-history] invalid -to %q", *to)
+		}
+	}
+
+	var accountIndex *int64
+	if *account >= 0 {
+		accountIndex = account
+	}
+
+	restClient, err := client.New(shared.Endpoint())
+	if err != nil {
+		log.Fatalf("[download-history] new client: %v", err)
+	}
+
+	downloader, err := history.NewDownloader(restClient, history.Options{
+		Dir:              *out,
+		MarketId:         uint8(*market),
+		From:             start,
+		To:               end,
+		Datasets:         strings.Split(*datasets, ","),
+		CandleResolution: lighterapi.CandlesticksParamsResolution(*resolution),
+		AccountIndex:     accountIndex,
+		Auth:             os.Getenv("LIGHTER_AUTH_TOKEN"),
+		MinInterval:      *interval,
+	})
+	if err != nil {
+		log.Fatalf("[download-history] %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := downloader.Run(ctx); err != nil {
+		log.Fatalf("[download-history] %v (rerun to resume from the checkpoint)", err)
+	}
+	log.Printf("[download-history] done, files listed in %s/manifest.json", *out)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -4,6 +4,7 @@ go 1.23.0
 
 require (
 	github.com/elliottech/lighter-go v0.0.0-20250909130901-5dfe1fc06ab3
+	github.com/elliottech/poseidon_crypto v0.0.11
 	github.com/gorilla/websocket v1.5.3
 	github.com/joho/godotenv v1.5.1
 	github.com/oapi-codegen/runtime v1.1.2
@@ -13,7 +14,6 @@ require (
 	github.com/apapsch/go-jsonmerge/v2 v2.0.0 // indirect
 	github.com/bits-and-blooms/bitset v1.17.0 // indirect
 	github.com/consensys/gnark-crypto v0.14.0 // indirect
-	github.com/elliottech/poseidon_crypto v0.0.11 // indirect
 	github.com/ethereum/go-ethereum v1.15.6 // indirect
 	github.com/google/uuid v1.5.0 // indirect
 	github.com/holiman/uint256 v1.3.2 // indirect
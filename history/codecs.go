@@ -0,0 +1,64 @@
+package history
+
+import (
+	"strconv"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+)
+
+var tradeCodec = codec[lighterapi.Trade]{
+	name: DatasetTrades,
+	header: []string{
+		"trade_id", "timestamp", "market_id", "type", "price", "size", "usd_amount", "is_maker_ask",
+		"ask_account_id", "bid_account_id", "ask_id", "bid_id", "maker_fee", "taker_fee", "block_height", "tx_hash",
+	},
+	key: func(t lighterapi.Trade) int64 { return t.TradeId },
+	ts:  func(t lighterapi.Trade) int64 { return t.Timestamp },
+	row: func(t lighterapi.Trade) []string {
+		return []string{
+			itoa(t.TradeId), itoa(t.Timestamp), itoa(int64(t.MarketId)), string(t.Type), t.Price, t.Size, t.UsdAmount,
+			strconv.FormatBool(t.IsMakerAsk), itoa(t.AskAccountId), itoa(t.BidAccountId), itoa(t.AskId), itoa(t.BidId),
+			itoa(int64(t.MakerFee)), itoa(int64(t.TakerFee)), itoa(t.BlockHeight), t.TxHash,
+		}
+	},
+}
+
+var candleCodec = codec[lighterapi.Candlestick]{
+	name:   DatasetCandles,
+	header: []string{"timestamp", "open", "high", "low", "close", "volume0", "volume1", "last_trade_id"},
+	key:    func(c lighterapi.Candlestick) int64 { return c.Timestamp },
+	ts:     func(c lighterapi.Candlestick) int64 { return c.Timestamp },
+	row: func(c lighterapi.Candlestick) []string {
+		return []string{
+			itoa(c.Timestamp), ftoa(c.Open), ftoa(c.High), ftoa(c.Low), ftoa(c.Close),
+			ftoa(c.Volume0), ftoa(c.Volume1), itoa(c.LastTradeId),
+		}
+	},
+}
+
+var fundingCodec = codec[lighterapi.Funding]{
+	name:   DatasetFundings,
+	header: []string{"timestamp", "rate", "value", "direction"},
+	key:    func(f lighterapi.Funding) int64 { return f.Timestamp },
+	ts:     func(f lighterapi.Funding) int64 { return f.Timestamp },
+	row: func(f lighterapi.Funding) []string {
+		return []string{itoa(f.Timestamp), f.Rate, f.Value, f.Direction}
+	},
+}
+
+var liquidationCodec = codec[lighterapi.Liquidation]{
+	name:   DatasetLiquidations,
+	header: []string{"id", "executed_at", "market_id", "type", "price", "size", "maker_fee", "taker_fee"},
+	key:    func(l lighterapi.Liquidation) int64 { return l.Id },
+	ts:     func(l lighterapi.Liquidation) int64 { return l.ExecutedAt },
+	row: func(l lighterapi.Liquidation) []string {
+		return []string{
+			itoa(l.Id), itoa(l.ExecutedAt), itoa(int64(l.MarketId)), string(l.Type),
+			l.Trade.Price, l.Trade.Size, l.Trade.MakerFee, l.Trade.TakerFee,
+		}
+	},
+}
+
+func itoa(v int64) string { return strconv.FormatInt(v, 10) }
+
+func ftoa(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
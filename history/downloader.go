@@ -0,0 +1,403 @@
+// Package history downloads historical market data from the Lighter REST API into
+// partitioned CSV and newline-delimited JSON files, one per day per market.
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+)
+
+// Dataset names, also used as output subdirectories
+const (
+	DatasetTrades       = "trades"
+	DatasetCandles      = "candles"
+	DatasetFundings     = "fundings"
+	DatasetLiquidations = "liquidations"
+)
+
+const (
+	tradePageSize       = 100
+	liquidationPageSize = 100
+	windowSize          = 500
+	defaultMinInterval  = 250 * time.Millisecond
+	maxRateLimitRetries = 8
+)
+
+// Options configures a download
+type Options struct {
+	Dir      string
+	MarketId uint8
+	From     time.Time
+	To       time.Time
+	// Datasets defaults to trades, candles and fundings
+	Datasets []string
+	// CandleResolution defaults to 1m
+	CandleResolution lighterapi.CandlesticksParamsResolution
+	// AccountIndex and Auth are required for liquidations, which the API scopes to an account
+	AccountIndex *int64
+	Auth         string
+	// MinInterval is the minimum delay between requests; defaults to 250ms
+	MinInterval time.Duration
+	// Logf receives progress messages; defaults to log.Printf
+	Logf func(format string, args ...interface{})
+}
+
+// Downloader walks the history endpoints and writes partitioned files, resuming from
+// the checkpoint stored in the output directory
+type Downloader struct {
+	api        *client.Client
+	opts       Options
+	pacer      *pacer
+	manifest   *Manifest
+	checkpoint *Checkpoint
+}
+
+// NewDownloader validates options and prepares the output directory
+func NewDownloader(api *client.Client, opts Options) (*Downloader, error) {
+	if api == nil {
+		return nil, errors.New("history: REST client is required")
+	}
+	if opts.Dir == "" {
+		return nil, errors.New("history: output directory is required")
+	}
+	if !opts.To.After(opts.From) {
+		return nil, errors.New("history: empty time range")
+	}
+	if len(opts.Datasets) == 0 {
+		opts.Datasets = []string{DatasetTrades, DatasetCandles, DatasetFundings}
+	}
+	if opts.CandleResolution == "" {
+		opts.CandleResolution = lighterapi.CandlesticksParamsResolutionN1m
+	}
+	if opts.MinInterval == 0 {
+		opts.MinInterval = defaultMinInterval
+	}
+	if opts.Logf == nil {
+		opts.Logf = log.Printf
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	manifest, err := LoadManifest(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	manifest.MarketId, manifest.From, manifest.To = opts.MarketId, opts.From.UTC(), opts.To.UTC()
+
+	checkpoint, err := loadCheckpoint(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	from, to := opts.From.UnixMilli(), opts.To.UnixMilli()
+	if checkpoint.MarketId != opts.MarketId || checkpoint.From != from || checkpoint.To != to {
+		// A different request invalidates the resume positions but keeps the files
+		checkpoint = &Checkpoint{MarketId: opts.MarketId, From: from, To: to, Datasets: make(map[string]DatasetCheckpoint)}
+	}
+
+	return &Downloader{
+		api:        api,
+		opts:       opts,
+		pacer:      newPacer(opts.MinInterval),
+		manifest:   manifest,
+		checkpoint: checkpoint,
+	}, nil
+}
+
+// Run downloads every configured dataset
+func (d *Downloader) Run(ctx context.Context) error {
+	for _, dataset := range d.opts.Datasets {
+		if cp, ok := d.checkpoint.get(dataset); ok && cp.Done {
+			d.opts.Logf("[history] %s already complete, skipping", dataset)
+			continue
+		}
+		var err error
+		switch dataset {
+		case DatasetTrades:
+			err = d.downloadTrades(ctx)
+		case DatasetCandles:
+			err = d.downloadCandles(ctx)
+		case DatasetFundings:
+			err = d.downloadFundings(ctx)
+		case DatasetLiquidations:
+			err = d.downloadLiquidations(ctx)
+		default:
+			err = fmt.Errorf("history: unknown dataset %q", dataset)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit persists the manifest and the new resume position of a dataset
+func (d *Downloader) commit(dataset string, cp DatasetCheckpoint) error {
+	if err := d.manifest.save(d.opts.Dir); err != nil {
+		return err
+	}
+	d.checkpoint.set(dataset, cp)
+	return d.checkpoint.save(d.opts.Dir)
+}
+
+// downloadTrades walks trades newest first (the only order the API supports) from To back to From
+func (d *Downloader) downloadTrades(ctx context.Context) error {
+	from, to := d.opts.From.UnixMilli(), d.opts.To.UnixMilli()
+	position := to
+	if cp, ok := d.checkpoint.get(DatasetTrades); ok && cp.Position > 0 {
+		position = cp.Position
+	}
+	part := newPartitioner(tradeCodec, d.opts.Dir, d.opts.MarketId, d.manifest)
+	marketId := d.opts.MarketId
+	dir := lighterapi.TradesParamsSortDirDesc
+	start := position
+
+	var cursor *string
+	for {
+		params := &lighterapi.TradesParams{
+			MarketId: &marketId,
+			SortBy:   lighterapi.TradesParamsSortByTimestamp,
+			SortDir:  &dir,
+			Limit:    tradePageSize,
+			Cursor:   cursor,
+		}
+		if cursor == nil {
+			params.From = &start
+		}
+		var resp *lighterapi.Trades
+		err := d.pacer.do(ctx, func() (err error) {
+			resp, err = d.api.Trades(ctx, params)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("history: trades: %w", err)
+		}
+
+		reachedStart := len(resp.Trades) == 0
+		for _, trade := range resp.Trades {
+			ts := client.UnixMillis(trade.Timestamp)
+			if ts >= to {
+				continue
+			}
+			if ts < from {
+				reachedStart = true
+				break
+			}
+			flushed, err := part.add(trade)
+			if err != nil {
+				return err
+			}
+			if flushed {
+				// Every trade at or after the start of the flushed day is on disk
+				if err := d.commit(DatasetTrades, DatasetCheckpoint{Position: dayStart(ts) + dayMillis}); err != nil {
+					return err
+				}
+			}
+		}
+		if reachedStart || resp.NextCursor == nil || *resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+
+	if err := part.flush(); err != nil {
+		return err
+	}
+	d.opts.Logf("[history] trades complete for market %d", d.opts.MarketId)
+	return d.commit(DatasetTrades, DatasetCheckpoint{Position: from, Done: true})
+}
+
+func (d *Downloader) downloadCandles(ctx context.Context) error {
+	step, err := resolutionMillis(string(d.opts.CandleResolution))
+	if err != nil {
+		return err
+	}
+	part := newPartitioner(candleCodec, d.opts.Dir, d.opts.MarketId, d.manifest)
+	return d.walkWindows(ctx, DatasetCandles, step, func(start, end int64) (bool, error) {
+		var resp *lighterapi.Candlesticks
+		err := d.pacer.do(ctx, func() (err error) {
+			resp, err = d.api.Candlesticks(ctx, &lighterapi.CandlesticksParams{
+				MarketId:       d.opts.MarketId,
+				Resolution:     d.opts.CandleResolution,
+				StartTimestamp: start,
+				EndTimestamp:   end,
+				CountBack:      windowSize,
+			})
+			return err
+		})
+		if err != nil {
+			return false, fmt.Errorf("history: candles: %w", err)
+		}
+		flushedAny := false
+		for _, c := range resp.Candlesticks {
+			if ts := client.UnixMillis(c.Timestamp); ts < start || ts >= end {
+				continue
+			}
+			flushed, err := part.add(c)
+			if err != nil {
+				return false, err
+			}
+			flushedAny = flushedAny || flushed
+		}
+		return flushedAny, nil
+	}, part.flush)
+}
+
+func (d *Downloader) downloadFundings(ctx context.Context) error {
+	part := newPartitioner(fundingCodec, d.opts.Dir, d.opts.MarketId, d.manifest)
+	return d.walkWindows(ctx, DatasetFundings, time.Hour.Milliseconds(), func(start, end int64) (bool, error) {
+		var resp *lighterapi.Fundings
+		err := d.pacer.do(ctx, func() (err error) {
+			resp, err = d.api.Fundings(ctx, &lighterapi.FundingsParams{
+				MarketId:       d.opts.MarketId,
+				Resolution:     lighterapi.FundingsParamsResolutionN1h,
+				StartTimestamp: start,
+				EndTimestamp:   end,
+				CountBack:      windowSize,
+			})
+			return err
+		})
+		if err != nil {
+			return false, fmt.Errorf("history: fundings: %w", err)
+		}
+		flushedAny := false
+		for _, f := range resp.Fundings {
+			if ts := client.UnixMillis(f.Timestamp); ts < start || ts >= end {
+				continue
+			}
+			flushed, err := part.add(f)
+			if err != nil {
+				return false, err
+			}
+			flushedAny = flushedAny || flushed
+		}
+		return flushedAny, nil
+	}, part.flush)
+}
+
+// walkWindows fetches [From, To) forward in windows of windowSize steps. The checkpoint
+// advances to the start of a window whenever a day has been flushed before it.
+func (d *Downloader) walkWindows(ctx context.Context, dataset string, step int64, fetch func(start, end int64) (bool, error), flush func() error) error {
+	from, to := d.opts.From.UnixMilli(), d.opts.To.UnixMilli()
+	start := from
+	if cp, ok := d.checkpoint.get(dataset); ok && cp.Position > start {
+		start = cp.Position
+	}
+	committed := start
+
+	for start < to {
+		end := start + step*windowSize
+		if end > to {
+			end = to
+		}
+		flushed, err := fetch(start, end)
+		if err != nil {
+			return err
+		}
+		if flushed {
+			// Days before the one still buffered are complete on disk
+			if err := d.commit(dataset, DatasetCheckpoint{Position: committed}); err != nil {
+				return err
+			}
+		}
+		committed = dayStart(end)
+		start = end
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+	d.opts.Logf("[history] %s complete for market %d", dataset, d.opts.MarketId)
+	return d.commit(dataset, DatasetCheckpoint{Position: to, Done: true})
+}
+
+// downloadLiquidations walks the account liquidations newest first. The endpoint has no
+// time filter, so a resumed run starts over and relies on deduplication by id.
+func (d *Downloader) downloadLiquidations(ctx context.Context) error {
+	if d.opts.AccountIndex == nil || d.opts.Auth == "" {
+		return errors.New("history: liquidations require an account index and auth token")
+	}
+	from, to := d.opts.From.UnixMilli(), d.opts.To.UnixMilli()
+	part := newPartitioner(liquidationCodec, d.opts.Dir, d.opts.MarketId, d.manifest)
+	marketId := d.opts.MarketId
+	var auth *string
+	if d.opts.Auth != "" {
+		auth = &d.opts.Auth
+	}
+
+	var cursor *string
+	for {
+		var resp *lighterapi.LiquidationInfos
+		err := d.pacer.do(ctx, func() (err error) {
+			resp, err = d.api.Liquidations(ctx, &lighterapi.LiquidationsParams{
+				Auth:         auth,
+				AccountIndex: *d.opts.AccountIndex,
+				MarketId:     &marketId,
+				Cursor:       cursor,
+				Limit:        liquidationPageSize,
+			})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("history: liquidations: %w", err)
+		}
+
+		reachedStart := len(resp.Liquidations) == 0
+		for _, liq := range resp.Liquidations {
+			ts := client.UnixMillis(liq.ExecutedAt)
+			if ts >= to {
+				continue
+			}
+			if ts < from {
+				reachedStart = true
+				break
+			}
+			if _, err := part.add(liq); err != nil {
+				return err
+			}
+		}
+		if reachedStart || resp.NextCursor == nil || *resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+
+	if err := part.flush(); err != nil {
+		return err
+	}
+	d.opts.Logf("[history] liquidations complete for market %d", d.opts.MarketId)
+	return d.commit(DatasetLiquidations, DatasetCheckpoint{Position: from, Done: true})
+}
+
+const dayMillis = int64(24 * time.Hour / time.Millisecond)
+
+func dayStart(ts int64) int64 {
+	return ts - ts%dayMillis
+}
+
+func resolutionMillis(resolution string) (int64, error) {
+	if len(resolution) < 2 {
+		return 0, fmt.Errorf("history: invalid resolution %q", resolution)
+	}
+	n, err := strconv.ParseInt(resolution[:len(resolution)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("history: invalid resolution %q", resolution)
+	}
+	switch resolution[len(resolution)-1] {
+	case 'm':
+		return n * time.Minute.Milliseconds(), nil
+	case 'h':
+		return n * time.Hour.Milliseconds(), nil
+	case 'd':
+		return n * dayMillis, nil
+	}
+	return 0, fmt.Errorf("history: invalid resolution %q", resolution)
+}
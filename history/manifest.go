@@ -0,0 +1,134 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	manifestFile   = "manifest.json"
+	checkpointFile = "checkpoint.json"
+)
+
+// ManifestEntry describes one partition file pair (CSV and JSONL share the same base path)
+type ManifestEntry struct {
+	Dataset        string `json:"dataset"`
+	Day            string `json:"day"`
+	Path           string `json:"path"`
+	Rows           int    `json:"rows"`
+	FirstTimestamp int64  `json:"first_timestamp"`
+	LastTimestamp  int64  `json:"last_timestamp"`
+}
+
+// Manifest lists every partition written to an output directory
+type Manifest struct {
+	mu        sync.Mutex
+	MarketId  uint8                    `json:"market_id"`
+	From      time.Time                `json:"from"`
+	To        time.Time                `json:"to"`
+	UpdatedAt time.Time                `json:"updated_at"`
+	Files     map[string]ManifestEntry `json:"files"`
+}
+
+func (m *Manifest) record(dataset, day, path string, rows int, first, last int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Files[path] = ManifestEntry{
+		Dataset:        dataset,
+		Day:            day,
+		Path:           path,
+		Rows:           rows,
+		FirstTimestamp: first,
+		LastTimestamp:  last,
+	}
+}
+
+// LoadManifest reads the manifest of an output directory, returning an empty one if none exists
+func LoadManifest(dir string) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]ManifestEntry)}
+	if err := readJSONFile(filepath.Join(dir, manifestFile), m); err != nil {
+		return nil, err
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]ManifestEntry)
+	}
+	return m, nil
+}
+
+func (m *Manifest) save(dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.UpdatedAt = time.Now().UTC()
+	return writeJSONFile(filepath.Join(dir, manifestFile), m)
+}
+
+// DatasetCheckpoint is the resume position of a dataset. For datasets walked backwards
+// (trades) Position is the oldest timestamp fully written; for the others it is the
+// next timestamp to fetch.
+type DatasetCheckpoint struct {
+	Position int64 `json:"position"`
+	Done     bool  `json:"done"`
+}
+
+// Checkpoint stores resume positions per dataset
+type Checkpoint struct {
+	mu       sync.Mutex
+	MarketId uint8                        `json:"market_id"`
+	From     int64                        `json:"from"`
+	To       int64                        `json:"to"`
+	Datasets map[string]DatasetCheckpoint `json:"datasets"`
+}
+
+func loadCheckpoint(dir string) (*Checkpoint, error) {
+	cp := &Checkpoint{Datasets: make(map[string]DatasetCheckpoint)}
+	if err := readJSONFile(filepath.Join(dir, checkpointFile), cp); err != nil {
+		return nil, err
+	}
+	if cp.Datasets == nil {
+		cp.Datasets = make(map[string]DatasetCheckpoint)
+	}
+	return cp, nil
+}
+
+func (c *Checkpoint) get(dataset string) (DatasetCheckpoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.Datasets[dataset]
+	return d, ok
+}
+
+func (c *Checkpoint) set(dataset string, d DatasetCheckpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Datasets[dataset] = d
+}
+
+func (c *Checkpoint) save(dir string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return writeJSONFile(filepath.Join(dir, checkpointFile), c)
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	return writeAtomic(path, func(w *bufio.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	})
+}
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/defi-maker/golighter/client"
+)
+
+// pacer spaces requests by a minimum interval and backs off when the API returns 429
+type pacer struct {
+	mu          sync.Mutex
+	minInterval time.Duration
+	last        time.Time
+}
+
+func newPacer(minInterval time.Duration) *pacer {
+	return &pacer{minInterval: minInterval}
+}
+
+func (p *pacer) wait(ctx context.Context) error {
+	p.mu.Lock()
+	delay := time.Until(p.last.Add(p.minInterval))
+	p.last = time.Now().Add(max(delay, 0))
+	p.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *pacer) do(ctx context.Context, call func() error) error {
+	backoff := p.minInterval
+	for attempt := 0; ; attempt++ {
+		if err := p.wait(ctx); err != nil {
+			return err
+		}
+		err := call()
+		if err == nil || !client.IsRateLimited(err) || attempt == maxRateLimitRetries {
+			return err
+		}
+
+		backoff *= 2
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
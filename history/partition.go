@@ -0,0 +1,188 @@
+package history
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/defi-maker/golighter/client"
+)
+
+// codec describes how a dataset is keyed, partitioned and rendered
+type codec[T any] struct {
+	name   string
+	header []string
+	key    func(T) int64
+	ts     func(T) int64
+	row    func(T) []string
+}
+
+// partitioner buffers the records of one UTC day and merges them into the day files on flush
+type partitioner[T any] struct {
+	codec    codec[T]
+	dir      string
+	marketId uint8
+	manifest *Manifest
+
+	day  string
+	rows map[int64]T
+}
+
+func newPartitioner[T any](c codec[T], dir string, marketId uint8, manifest *Manifest) *partitioner[T] {
+	return &partitioner[T]{codec: c, dir: dir, marketId: marketId, manifest: manifest, rows: make(map[int64]T)}
+}
+
+// add buffers a record, flushing the previous day when the record belongs to another one.
+// It reports whether a flush happened.
+func (p *partitioner[T]) add(record T) (bool, error) {
+	day := dayOf(p.codec.ts(record))
+	flushed := false
+	if p.day != "" && day != p.day {
+		if err := p.flush(); err != nil {
+			return false, err
+		}
+		flushed = true
+	}
+	p.day = day
+	p.rows[p.codec.key(record)] = record
+	return flushed, nil
+}
+
+func (p *partitioner[T]) basePath(day string) string {
+	return filepath.Join(p.dir, p.codec.name, fmt.Sprintf("market=%d", p.marketId), day)
+}
+
+// flush merges the buffered day with any existing files, deduplicating by key
+func (p *partitioner[T]) flush() error {
+	if p.day == "" || len(p.rows) == 0 {
+		p.day = ""
+		return nil
+	}
+	base := p.basePath(p.day)
+	if err := os.MkdirAll(filepath.Dir(base), 0o755); err != nil {
+		return err
+	}
+
+	existing, err := readJSONL[T](base + ".jsonl")
+	if err != nil {
+		return err
+	}
+	for _, r := range existing {
+		if _, ok := p.rows[p.codec.key(r)]; !ok {
+			p.rows[p.codec.key(r)] = r
+		}
+	}
+
+	records := make([]T, 0, len(p.rows))
+	for _, r := range p.rows {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		ti, tj := p.codec.ts(records[i]), p.codec.ts(records[j])
+		if ti != tj {
+			return ti < tj
+		}
+		return p.codec.key(records[i]) < p.codec.key(records[j])
+	})
+
+	if err := p.writeJSONL(base+".jsonl", records); err != nil {
+		return err
+	}
+	if err := p.writeCSV(base+".csv", records); err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(p.dir, base)
+	if err != nil {
+		return err
+	}
+	p.manifest.record(p.codec.name, p.day, rel, len(records), p.codec.ts(records[0]), p.codec.ts(records[len(records)-1]))
+
+	p.day = ""
+	p.rows = make(map[int64]T)
+	return nil
+}
+
+func (p *partitioner[T]) writeJSONL(path string, records []T) error {
+	return writeAtomic(path, func(w *bufio.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *partitioner[T]) writeCSV(path string, records []T) error {
+	return writeAtomic(path, func(w *bufio.Writer) error {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(p.codec.header); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := cw.Write(p.codec.row(r)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+}
+
+func readJSONL[T any](path string) ([]T, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []T
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var r T
+		if err := dec.Decode(&r); err != nil {
+			return nil, fmt.Errorf("history: read %s: %w", path, err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func writeAtomic(path string, fill func(*bufio.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := fill(w); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func dayOf(ts int64) string {
+	return time.UnixMilli(client.UnixMillis(ts)).UTC().Format("2006-01-02")
+}
@@ -0,0 +1,553 @@
+package lightertest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// firstOrderIndex keeps server-assigned order indexes clear of the client order index range
+const firstOrderIndex = txtypes.MinOrderIndex
+
+// Market describes a perpetual market served by the fake. Prices and sizes are the integer
+// units used by the signer; fees are fractions (0.0002 = 2 bps).
+type Market struct {
+	Id            uint8
+	Symbol        string
+	PriceDecimals uint8
+	SizeDecimals  uint8
+	MinBaseAmount int64
+	MakerFee      float64
+	TakerFee      float64
+	// MinInitialMarginFraction is in MarginFractionTick units; defaults to 500 (20x)
+	MinInitialMarginFraction int
+}
+
+func (m *Market) price(p uint32) float64 {
+	return float64(p) / math.Pow10(int(m.PriceDecimals))
+}
+
+func (m *Market) size(base int64) float64 {
+	return float64(base) / math.Pow10(int(m.SizeDecimals))
+}
+
+func (m *Market) formatPrice(p uint32) string {
+	return strconv.FormatFloat(m.price(p), 'f', int(m.PriceDecimals), 64)
+}
+
+func (m *Market) formatSize(base int64) string {
+	return strconv.FormatFloat(m.size(base), 'f', int(m.SizeDecimals), 64)
+}
+
+type position struct {
+	base       int64
+	entry      float64
+	realized   float64
+	imf        int
+	marginMode int32
+	allocated  float64
+}
+
+type account struct {
+	index      int64
+	l1Address  string
+	collateral float64
+	positions  map[uint8]*position
+	nonces     map[uint8]int64
+}
+
+func (a *account) position(m *Market) *position {
+	p, ok := a.positions[m.Id]
+	if !ok {
+		p = &position{imf: m.MinInitialMarginFraction}
+		a.positions[m.Id] = p
+	}
+	return p
+}
+
+type order struct {
+	index       int64
+	clientIndex int64
+	account     int64
+	market      uint8
+	isAsk       bool
+	price       uint32
+	initial     int64
+	remaining   int64
+	filledQuote float64
+	typ         uint8
+	tif         uint8
+	reduceOnly  bool
+	expiry      int64
+	nonce       int64
+	timestamp   int64
+	status      lighterapi.OrderStatus
+}
+
+type book struct {
+	bids []*order // best (highest) price first
+	asks []*order // best (lowest) price first
+}
+
+func (b *book) side(isAsk bool) *[]*order {
+	if isAsk {
+		return &b.asks
+	}
+	return &b.bids
+}
+
+// insert keeps price-time priority: an order goes behind every resting order at its price
+func (b *book) insert(o *order) {
+	side := b.side(o.isAsk)
+	i := sort.Search(len(*side), func(i int) bool {
+		if o.isAsk {
+			return (*side)[i].price > o.price
+		}
+		return (*side)[i].price < o.price
+	})
+	*side = append(*side, nil)
+	copy((*side)[i+1:], (*side)[i:])
+	(*side)[i] = o
+}
+
+func (b *book) remove(o *order) bool {
+	side := b.side(o.isAsk)
+	for i, resting := range *side {
+		if resting == o {
+			*side = append((*side)[:i], (*side)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// exchange is the state behind the fake: markets, books, accounts and trades. It is not
+// safe for concurrent use; Server serializes access.
+type exchange struct {
+	markets   map[uint8]*Market
+	books     map[uint8]*book
+	accounts  map[int64]*account
+	orders    map[int64]*order
+	inactive  []*order
+	trades    []lighterapi.Trade
+	transfers []lighterapi.TransferHistoryItem
+	// transferFee is charged, in USDC, on every transfer
+	transferFee float64
+	nextOrder   int64
+	nextTrade   int64
+}
+
+func newExchange() *exchange {
+	return &exchange{
+		markets:   make(map[uint8]*Market),
+		books:     make(map[uint8]*book),
+		accounts:  make(map[int64]*account),
+		orders:    make(map[int64]*order),
+		nextOrder: firstOrderIndex,
+		nextTrade: 1,
+	}
+}
+
+func (e *exchange) account(index int64) (*account, error) {
+	acc, ok := e.accounts[index]
+	if !ok {
+		return nil, fmt.Errorf("account %d not found", index)
+	}
+	return acc, nil
+}
+
+func (e *exchange) market(id uint8) (*Market, error) {
+	m, ok := e.markets[id]
+	if !ok {
+		return nil, fmt.Errorf("market %d not found", id)
+	}
+	return m, nil
+}
+
+// check reports why an order would be rejected, without changing any state
+func (e *exchange) check(o *order) error {
+	m, err := e.market(o.market)
+	if err != nil {
+		return err
+	}
+	if _, err := e.account(o.account); err != nil {
+		return err
+	}
+	if o.clientIndex != txtypes.NilClientOrderIndex {
+		for _, resting := range e.orders {
+			if resting.account == o.account && resting.clientIndex == o.clientIndex {
+				return fmt.Errorf("client order index %d already in use", o.clientIndex)
+			}
+		}
+	}
+	if o.initial < max(m.MinBaseAmount, txtypes.MinOrderBaseAmount) && !o.reduceOnly {
+		return fmt.Errorf("base amount %d below market minimum %d", o.initial, m.MinBaseAmount)
+	}
+	return nil
+}
+
+// place matches an order against the book and rests whatever GTT remainder is left
+func (e *exchange) place(o *order, txHash string, now int64) error {
+	if err := e.check(o); err != nil {
+		return err
+	}
+	m := e.markets[o.market]
+	acc := e.accounts[o.account]
+
+	pos := acc.position(m)
+	closing := (pos.base > 0 && o.isAsk) || (pos.base < 0 && !o.isAsk)
+	if o.reduceOnly && o.initial == txtypes.NilOrderBaseAmount && closing {
+		// A reduce-only order without a size closes the whole position
+		o.initial = abs(pos.base)
+	}
+
+	o.index = e.nextOrder
+	e.nextOrder++
+	o.timestamp = now
+	o.remaining = o.initial
+
+	if o.reduceOnly {
+		if !closing {
+			e.finish(o, lighterapi.OrderStatusCanceledReduceOnly)
+			return nil
+		}
+		o.remaining = min(o.remaining, abs(pos.base))
+	}
+
+	b := e.books[m.Id]
+	opposite := b.side(!o.isAsk)
+	if o.tif == txtypes.PostOnly && len(*opposite) > 0 && crosses(o, (*opposite)[0]) {
+		e.finish(o, lighterapi.OrderStatusCanceledPostOnly)
+		return nil
+	}
+
+	for o.remaining > 0 && len(*opposite) > 0 {
+		maker := (*opposite)[0]
+		if !crosses(o, maker) {
+			break
+		}
+		e.fill(m, o, maker, txHash, now)
+		if maker.remaining == 0 {
+			*opposite = (*opposite)[1:]
+			delete(e.orders, maker.index)
+			e.finish(maker, lighterapi.OrderStatusFilled)
+		}
+	}
+
+	switch {
+	case o.remaining == 0:
+		e.finish(o, lighterapi.OrderStatusFilled)
+	case o.typ == txtypes.MarketOrder || o.tif == txtypes.ImmediateOrCancel:
+		if o.filledQuote == 0 {
+			e.finish(o, lighterapi.OrderStatusCanceledNotEnoughLiquidity)
+		} else {
+			e.finish(o, lighterapi.OrderStatusCanceled)
+		}
+	default:
+		o.status = lighterapi.OrderStatusOpen
+		e.orders[o.index] = o
+		b.insert(o)
+	}
+	return nil
+}
+
+// crosses reports whether taker can trade with maker; a zero taker price accepts any price
+func crosses(taker, maker *order) bool {
+	if taker.price == txtypes.NilOrderPrice {
+		return true
+	}
+	if taker.isAsk {
+		return maker.price >= taker.price
+	}
+	return maker.price <= taker.price
+}
+
+func (e *exchange) fill(m *Market, taker, maker *order, txHash string, now int64) {
+	base := min(taker.remaining, maker.remaining)
+	price := m.price(maker.price)
+	size := m.size(base)
+	taker.remaining -= base
+	maker.remaining -= base
+	taker.filledQuote += size * price
+	maker.filledQuote += size * price
+
+	takerPos := e.accounts[taker.account].position(m)
+	makerPos := e.accounts[maker.account].position(m)
+	trade := lighterapi.Trade{
+		TradeId:                 e.nextTrade,
+		TxHash:                  txHash,
+		Type:                    lighterapi.TradeTypeTrade,
+		MarketId:                m.Id,
+		Size:                    m.formatSize(base),
+		Price:                   m.formatPrice(maker.price),
+		UsdAmount:               strconv.FormatFloat(size*price, 'f', 6, 64),
+		IsMakerAsk:              maker.isAsk,
+		MakerFee:                int32(math.Round(m.MakerFee * float64(txtypes.FeeTick))),
+		TakerFee:                int32(math.Round(m.TakerFee * float64(txtypes.FeeTick))),
+		MakerPositionSizeBefore: m.formatSize(makerPos.base),
+		TakerPositionSizeBefore: m.formatSize(takerPos.base),
+		Timestamp:               now,
+	}
+	if maker.isAsk {
+		trade.AskId, trade.AskAccountId = maker.index, maker.account
+		trade.BidId, trade.BidAccountId = taker.index, taker.account
+	} else {
+		trade.BidId, trade.BidAccountId = maker.index, maker.account
+		trade.AskId, trade.AskAccountId = taker.index, taker.account
+	}
+	e.nextTrade++
+
+	trade.MakerPositionSignChanged = e.settle(m, maker.account, makerPos, !maker.isAsk, base, price, m.MakerFee)
+	trade.TakerPositionSignChanged = e.settle(m, taker.account, takerPos, !taker.isAsk, base, price, m.TakerFee)
+	e.trades = append(e.trades, trade)
+}
+
+// settle applies a fill to a position, realizing PnL on the closed part and charging the fee
+// against collateral. It reports whether the position changed sign.
+func (e *exchange) settle(m *Market, accountIndex int64, pos *position, buy bool, base int64, price, fee float64) bool {
+	acc := e.accounts[accountIndex]
+	delta := base
+	if !buy {
+		delta = -base
+	}
+	before := pos.base
+	if before != 0 && (before > 0) != (delta > 0) {
+		closed := min(abs(delta), abs(before))
+		pnl := m.size(closed) * (price - pos.entry)
+		if before < 0 {
+			pnl = -pnl
+		}
+		pos.realized += pnl
+		acc.collateral += pnl
+	}
+	after := before + delta
+	switch {
+	case after == 0:
+		pos.entry = 0
+	case before == 0 || (before > 0) != (after > 0):
+		pos.entry = price
+	case abs(after) > abs(before):
+		pos.entry = (m.size(abs(before))*pos.entry + m.size(base)*price) / m.size(abs(after))
+	}
+	pos.base = after
+	acc.collateral -= m.size(base) * price * fee
+	return before != 0 && after != 0 && (before > 0) != (after > 0)
+}
+
+func (e *exchange) finish(o *order, status lighterapi.OrderStatus) {
+	o.status = status
+	e.inactive = append(e.inactive, o)
+}
+
+// lookup finds a resting order of an account by order index or client order index
+func (e *exchange) lookup(accountIndex int64, market uint8, index int64) (*order, error) {
+	if o, ok := e.orders[index]; ok && o.account == accountIndex && o.market == market {
+		return o, nil
+	}
+	for _, o := range e.orders {
+		if o.account == accountIndex && o.market == market && o.clientIndex == index {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("order %d not found", index)
+}
+
+func (e *exchange) cancel(o *order) {
+	e.books[o.market].remove(o)
+	delete(e.orders, o.index)
+	e.finish(o, lighterapi.OrderStatusCanceled)
+}
+
+func (e *exchange) cancelAll(accountIndex int64) {
+	for _, o := range e.sortedOrders() {
+		if o.account == accountIndex {
+			e.cancel(o)
+		}
+	}
+}
+
+// modify re-prices a resting order; it loses time priority and may match immediately
+func (e *exchange) modify(o *order, base int64, price uint32, txHash string, now int64) error {
+	filled := o.initial - o.remaining
+	if base <= filled {
+		return fmt.Errorf("base amount %d not above filled amount %d", base, filled)
+	}
+	e.books[o.market].remove(o)
+	delete(e.orders, o.index)
+
+	m := e.markets[o.market]
+	o.price = price
+	o.initial = base
+	o.remaining = base - filled
+	o.timestamp = now
+	opposite := e.books[o.market].side(!o.isAsk)
+	for o.remaining > 0 && len(*opposite) > 0 && crosses(o, (*opposite)[0]) {
+		maker := (*opposite)[0]
+		e.fill(m, o, maker, txHash, now)
+		if maker.remaining == 0 {
+			*opposite = (*opposite)[1:]
+			delete(e.orders, maker.index)
+			e.finish(maker, lighterapi.OrderStatusFilled)
+		}
+	}
+	if o.remaining == 0 {
+		e.finish(o, lighterapi.OrderStatusFilled)
+		return nil
+	}
+	e.orders[o.index] = o
+	e.books[o.market].insert(o)
+	return nil
+}
+
+// markPrice is the last trade price, falling back to the mid of the book
+func (e *exchange) markPrice(m *Market) float64 {
+	for i := len(e.trades) - 1; i >= 0; i-- {
+		if e.trades[i].MarketId == m.Id {
+			p, _ := strconv.ParseFloat(e.trades[i].Price, 64)
+			return p
+		}
+	}
+	b := e.books[m.Id]
+	switch {
+	case len(b.bids) > 0 && len(b.asks) > 0:
+		return (m.price(b.bids[0].price) + m.price(b.asks[0].price)) / 2
+	case len(b.bids) > 0:
+		return m.price(b.bids[0].price)
+	case len(b.asks) > 0:
+		return m.price(b.asks[0].price)
+	}
+	return 0
+}
+
+func (e *exchange) sortedOrders() []*order {
+	orders := make([]*order, 0, len(e.orders))
+	for _, o := range e.orders {
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].index < orders[j].index })
+	return orders
+}
+
+func (e *exchange) apiOrder(o *order) lighterapi.Order {
+	m := e.markets[o.market]
+	filled := o.initial - o.remaining
+	side := "buy"
+	if o.isAsk {
+		side = "sell"
+	}
+	return lighterapi.Order{
+		OrderIndex:          o.index,
+		OrderId:             strconv.FormatInt(o.index, 10),
+		ClientOrderIndex:    o.clientIndex,
+		ClientOrderId:       strconv.FormatInt(o.clientIndex, 10),
+		OwnerAccountIndex:   o.account,
+		MarketIndex:         o.market,
+		IsAsk:               o.isAsk,
+		Side:                side,
+		Price:               m.formatPrice(o.price),
+		BasePrice:           int32(o.price),
+		BaseSize:            o.initial,
+		InitialBaseAmount:   m.formatSize(o.initial),
+		RemainingBaseAmount: m.formatSize(o.remaining),
+		FilledBaseAmount:    m.formatSize(filled),
+		FilledQuoteAmount:   strconv.FormatFloat(o.filledQuote, 'f', 6, 64),
+		ReduceOnly:          o.reduceOnly,
+		OrderExpiry:         o.expiry,
+		Nonce:               o.nonce,
+		Timestamp:           o.timestamp,
+		Status:              o.status,
+		Type:                orderTypes[o.typ],
+		TimeInForce:         timeInForces[o.tif],
+	}
+}
+
+func (e *exchange) apiAccount(acc *account) lighterapi.DetailedAccount {
+	var (
+		unrealized float64
+		orders     int64
+		positions  []lighterapi.AccountPosition
+	)
+	counts := make(map[uint8]int64)
+	for _, o := range e.orders {
+		if o.account == acc.index {
+			counts[o.market]++
+			orders++
+		}
+	}
+	ids := make([]int, 0, len(acc.positions))
+	for id := range acc.positions {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		pos := acc.positions[uint8(id)]
+		m := e.markets[uint8(id)]
+		mark := e.markPrice(m)
+		size := m.size(abs(pos.base))
+		sign := int32(0)
+		upnl := 0.0
+		if pos.base > 0 {
+			sign, upnl = 1, size*(mark-pos.entry)
+		} else if pos.base < 0 {
+			sign, upnl = -1, size*(pos.entry-mark)
+		}
+		unrealized += upnl
+		positions = append(positions, lighterapi.AccountPosition{
+			MarketId:              m.Id,
+			Symbol:                m.Symbol,
+			Sign:                  sign,
+			Position:              m.formatSize(abs(pos.base)),
+			AvgEntryPrice:         strconv.FormatFloat(pos.entry, 'f', int(m.PriceDecimals), 64),
+			PositionValue:         formatUSD(size * mark),
+			UnrealizedPnl:         formatUSD(upnl),
+			RealizedPnl:           formatUSD(pos.realized),
+			InitialMarginFraction: strconv.FormatFloat(float64(pos.imf)*100/float64(txtypes.MarginFractionTick), 'f', 2, 64),
+			MarginMode:            pos.marginMode,
+			AllocatedMargin:       formatUSD(pos.allocated),
+			OpenOrderCount:        counts[m.Id],
+		})
+	}
+	return lighterapi.DetailedAccount{
+		AccountIndex:     acc.index,
+		Index:            acc.index,
+		L1Address:        acc.l1Address,
+		Status:           1,
+		Collateral:       formatUSD(acc.collateral),
+		AvailableBalance: formatUSD(acc.collateral),
+		CrossAssetValue:  formatUSD(acc.collateral + unrealized),
+		TotalAssetValue:  formatUSD(acc.collateral + unrealized),
+		TotalOrderCount:  orders,
+		Positions:        positions,
+	}
+}
+
+var orderTypes = map[uint8]lighterapi.OrderType{
+	txtypes.LimitOrder:           lighterapi.OrderTypeLimit,
+	txtypes.MarketOrder:          lighterapi.OrderTypeMarket,
+	txtypes.StopLossOrder:        lighterapi.OrderTypeStopLoss,
+	txtypes.StopLossLimitOrder:   lighterapi.OrderTypeStopLossLimit,
+	txtypes.TakeProfitOrder:      lighterapi.OrderTypeTakeProfit,
+	txtypes.TakeProfitLimitOrder: lighterapi.OrderTypeTakeProfitLimit,
+	txtypes.TWAPOrder:            lighterapi.OrderTypeTwap,
+}
+
+var timeInForces = map[uint8]lighterapi.OrderTimeInForce{
+	txtypes.ImmediateOrCancel: lighterapi.ImmediateOrCancel,
+	txtypes.GoodTillTime:      lighterapi.GoodTillTime,
+	txtypes.PostOnly:          lighterapi.PostOnly,
+}
+
+func formatUSD(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,125 @@
+package lightertest
+
+import (
+	"net/http"
+	"time"
+)
+
+// AnyRoute matches every request when used as a fault path
+const AnyRoute = "*"
+
+// Fault alters responses for a route. Latency delays the request; a non-zero Status replaces
+// the response with a ResultCode body carrying Code and Message.
+type Fault struct {
+	Status  int
+	Code    int32
+	Message string
+	Latency time.Duration
+	// Times limits how many requests the fault applies to; 0 applies it until cleared
+	Times int
+}
+
+// Inject queues a fault for path (for example "/api/v1/sendTx", or AnyRoute). Limited faults
+// on a path apply in the order they were injected. An unlimited fault ends the queue: limited
+// faults injected after it still apply first, it takes over once they are used up, and a
+// second unlimited fault replaces it.
+func (s *Server) Inject(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := fault
+	queue := s.faults[path]
+	if n := len(queue); n > 0 && queue[n-1].Times == 0 {
+		if f.Times == 0 {
+			queue[n-1] = &f
+			return
+		}
+		queue = append(queue[:n-1:n-1], &f, queue[n-1])
+		s.faults[path] = queue
+		return
+	}
+	s.faults[path] = append(queue, &f)
+}
+
+// FailNext makes the next n requests to path fail with status and message
+func (s *Server) FailNext(path string, n int, status int, message string) {
+	s.Inject(path, Fault{Status: status, Code: int32(status), Message: message, Times: n})
+}
+
+// RateLimit answers the next n requests to path with HTTP 429
+func (s *Server) RateLimit(path string, n int) {
+	s.FailNext(path, n, http.StatusTooManyRequests, "too many requests")
+}
+
+// SetLatency delays every request to path by d until faults are cleared
+func (s *Server) SetLatency(path string, d time.Duration) {
+	s.Inject(path, Fault{Latency: d})
+}
+
+// ClearFaults removes every injected fault
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = make(map[string][]*Fault)
+}
+
+// Requests returns how many requests path has received, faulted ones included
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if path == AnyRoute {
+		total := 0
+		for _, n := range s.requests {
+			total += n
+		}
+		return total
+	}
+	return s.requests[path]
+}
+
+// intercept counts requests and applies the first pending fault for the route before the
+// request reaches a handler set with Handle or the fake exchange
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		fault := s.nextFault(r.URL.Path)
+		route := s.routes[r.URL.Path]
+		s.mu.Unlock()
+
+		if fault != nil {
+			sleep(r.Context(), fault.Latency)
+			if fault.Status != 0 {
+				message := fault.Message
+				if message == "" {
+					message = http.StatusText(fault.Status)
+				}
+				writeError(w, fault.Status, fault.Code, message)
+				return
+			}
+		}
+		if route != nil {
+			route.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// nextFault pops one use of the first fault registered for path, then for AnyRoute
+func (s *Server) nextFault(path string) *Fault {
+	for _, key := range []string{path, AnyRoute} {
+		queue := s.faults[key]
+		if len(queue) == 0 {
+			continue
+		}
+		f := *queue[0]
+		if queue[0].Times > 0 {
+			queue[0].Times--
+			if queue[0].Times == 0 {
+				s.faults[key] = queue[1:]
+			}
+		}
+		return &f
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package lightertest
+
+import (
+	"testing"
+
+	"github.com/defi-maker/golighter/client"
+)
+
+// Accounts funded by NewExchange
+const (
+	TraderAccount int64 = 1
+	MakerAccount  int64 = 2
+)
+
+// ETH is the market listed by NewExchange: 2 price and 4 size decimals, a 0.001 ETH
+// minimum order, 2 bps maker and 5 bps taker fees, and 20x max leverage
+var ETH = Market{Id: 0, Symbol: "ETH", PriceDecimals: 2, SizeDecimals: 4, MinBaseAmount: 10, MakerFee: 0.0002, TakerFee: 0.0005}
+
+// NewExchange starts a fake server listing ETH with TraderAccount and MakerAccount each
+// holding 10000 USDC, and returns it with a REST client and a signer for TraderAccount.
+// The server is closed when the test ends.
+func NewExchange(t testing.TB, opts ...Option) (*Server, *client.Client, *client.TxClient) {
+	t.Helper()
+	srv := NewServer(opts...)
+	t.Cleanup(srv.Close)
+	srv.AddMarket(ETH)
+	srv.AddAccount(TraderAccount, "0x0000000000000000000000000000000000000001", 10000)
+	srv.AddAccount(MakerAccount, "0x0000000000000000000000000000000000000002", 10000)
+	api, err := srv.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := srv.TxClient(api, TraderAccount, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, api, tx
+}
@@ -0,0 +1,626 @@
+// Package lightertest provides an in-process fake of the Lighter REST API for tests. The fake
+// keeps order books, accounts and nonces in memory, matches orders with price-time priority,
+// and can inject errors, latency and rate limits per route.
+package lightertest
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// Result codes the fake returns for rejected transactions
+const (
+	CodeInvalidTx    int32 = 21100
+	CodeInvalidNonce int32 = 21104
+	CodeExpiredTx    int32 = 21105
+	CodeNotFound     int32 = 21400
+)
+
+// TestPrivateKey is a fixed API key private key for TxClients talking to the fake, which
+// does not verify signatures
+const TestPrivateKey = "0x11111111111111111111111111111111111111111111111111111111111111111111111111111111"
+
+// Server is a fake Lighter REST endpoint backed by an in-memory exchange
+type Server struct {
+	*httptest.Server
+
+	chainID uint32
+	now     func() time.Time
+
+	mu       sync.Mutex
+	ex       *exchange
+	faults   map[string][]*Fault
+	requests map[string]int
+	routes   map[string]http.Handler
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithChainID sets the chain id used to compute transaction hashes; it must match the
+// TxClient's chain id for hashes to agree with GetTxHash
+func WithChainID(chainID uint32) Option {
+	return func(s *Server) { s.chainID = chainID }
+}
+
+// WithClock overrides the time source used for timestamps and expiry checks
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// NewServer starts a fake server; call Close when done
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		now:      time.Now,
+		ex:       newExchange(),
+		faults:   make(map[string][]*Fault),
+		requests: make(map[string]int),
+		routes:   make(map[string]http.Handler),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleStatus)
+	mux.HandleFunc("/api/v1/sendTx", s.handleSendTx)
+	mux.HandleFunc("/api/v1/sendTxBatch", s.handleSendTxBatch)
+	mux.HandleFunc("/api/v1/nextNonce", s.handleNextNonce)
+	mux.HandleFunc("/api/v1/orderBooks", s.handleOrderBooks)
+	mux.HandleFunc("/api/v1/orderBookDetails", s.handleOrderBookDetails)
+	mux.HandleFunc("/api/v1/orderBookOrders", s.handleOrderBookOrders)
+	mux.HandleFunc("/api/v1/account", s.handleAccount)
+	mux.HandleFunc("/api/v1/accountsByL1Address", s.handleAccountsByL1Address)
+	mux.HandleFunc("/api/v1/accountActiveOrders", s.handleActiveOrders)
+	mux.HandleFunc("/api/v1/accountInactiveOrders", s.handleInactiveOrders)
+	mux.HandleFunc("/api/v1/recentTrades", s.handleRecentTrades)
+	mux.HandleFunc("/api/v1/trades", s.handleTrades)
+	mux.HandleFunc("/api/v1/transferFeeInfo", s.handleTransferFeeInfo)
+	mux.HandleFunc("/api/v1/transfer/history", s.handleTransferHistory)
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// Client returns a REST client pointed at the fake
+func (s *Server) Client(opts ...client.Option) (*client.Client, error) {
+	return client.New(s.URL, opts...)
+}
+
+// TxClient returns a signer for accountIndex using TestPrivateKey and the server chain id
+func (s *Server) TxClient(api *client.Client, accountIndex int64, apiKeyIndex uint8) (*client.TxClient, error) {
+	return client.NewTxClient(api, TestPrivateKey, accountIndex, apiKeyIndex, s.chainID)
+}
+
+// Handle serves path with h instead of the fake exchange, for endpoints the fake does not
+// model such as candlesticks, funding or pools. Faults and request counts still apply.
+func (s *Server) Handle(path string, h http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[path] = h
+}
+
+// HandleJSON answers every request to path with v encoded as JSON
+func (s *Server) HandleJSON(path string, v any) {
+	s.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, v)
+	}))
+}
+
+// AddMarket lists a market with an empty order book
+func (s *Server) AddMarket(m Market) {
+	if m.MinInitialMarginFraction == 0 {
+		m.MinInitialMarginFraction = 500
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ex.markets[m.Id] = &m
+	if _, ok := s.ex.books[m.Id]; !ok {
+		s.ex.books[m.Id] = &book{}
+	}
+}
+
+// AddAccount creates an account holding collateral USDC
+func (s *Server) AddAccount(index int64, l1Address string, collateral float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ex.accounts[index] = &account{
+		index:      index,
+		l1Address:  l1Address,
+		collateral: collateral,
+		positions:  make(map[uint8]*position),
+		nonces:     make(map[uint8]int64),
+	}
+}
+
+// SetTransferFee sets the fee, in USDC, charged on every transfer. Transfers paying less are
+// rejected.
+func (s *Server) SetTransferFee(usdc float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ex.transferFee = usdc
+}
+
+// SetNonce sets the next nonce expected from an account's API key
+func (s *Server) SetNonce(accountIndex int64, apiKeyIndex uint8, nonce int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.ex.account(accountIndex)
+	if err != nil {
+		return err
+	}
+	acc.nonces[apiKeyIndex] = nonce
+	return nil
+}
+
+// PlaceOrder adds a GTT limit order without a signed transaction, typically to seed
+// liquidity. It matches like any other order and returns the order index.
+func (s *Server) PlaceOrder(accountIndex int64, marketId uint8, isAsk bool, price uint32, baseAmount int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := &order{
+		account: accountIndex,
+		market:  marketId,
+		isAsk:   isAsk,
+		price:   price,
+		initial: baseAmount,
+		typ:     txtypes.LimitOrder,
+		tif:     txtypes.GoodTillTime,
+	}
+	if err := s.ex.place(o, "", s.now().UnixMilli()); err != nil {
+		return 0, err
+	}
+	return o.index, nil
+}
+
+// Orders returns the resting orders of an account
+func (s *Server) Orders(accountIndex int64) []lighterapi.Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var orders []lighterapi.Order
+	for _, o := range s.ex.sortedOrders() {
+		if o.account == accountIndex {
+			orders = append(orders, s.ex.apiOrder(o))
+		}
+	}
+	return orders
+}
+
+// Trades returns every trade in a market in execution order
+func (s *Server) Trades(marketId uint8) []lighterapi.Trade {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var trades []lighterapi.Trade
+	for _, t := range s.ex.trades {
+		if t.MarketId == marketId {
+			trades = append(trades, t)
+		}
+	}
+	return trades
+}
+
+// OrderStatus reports the status of an order by order index, or "" when it is unknown
+func (s *Server) OrderStatus(orderIndex int64) lighterapi.OrderStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o, ok := s.ex.orders[orderIndex]; ok {
+		return o.status
+	}
+	for _, o := range s.ex.inactive {
+		if o.index == orderIndex {
+			return o.status
+		}
+	}
+	return ""
+}
+
+// Account returns the current state of an account as the account endpoint reports it
+func (s *Server) Account(accountIndex int64) (lighterapi.DetailedAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.ex.account(accountIndex)
+	if err != nil {
+		return lighterapi.DetailedAccount{}, err
+	}
+	return s.ex.apiAccount(acc), nil
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(w, http.StatusNotFound, CodeNotFound, "route not found")
+		return
+	}
+	writeJSON(w, lighterapi.Status{Status: 200, NetworkId: 1, Timestamp: s.now().Unix()})
+}
+
+func (s *Server) handleSendTx(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidTx, err.Error())
+		return
+	}
+	txType, err := strconv.Atoi(r.PostForm.Get("tx_type"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidTx, "invalid tx_type")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hash, code, err := s.apply(uint8(txType), r.PostForm.Get("tx_info"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, code, err.Error())
+		return
+	}
+	writeJSON(w, lighterapi.RespSendTx{Code: 200, TxHash: hash})
+}
+
+// handleSendTxBatch applies the transactions in order and stops at the first rejection;
+// transactions before it stay applied
+func (s *Server) handleSendTxBatch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidTx, err.Error())
+		return
+	}
+	var (
+		txTypes []uint8
+		txInfos []string
+	)
+	if err := json.Unmarshal([]byte(r.PostForm.Get("tx_types")), &txTypes); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidTx, "invalid tx_types")
+		return
+	}
+	if err := json.Unmarshal([]byte(r.PostForm.Get("tx_infos")), &txInfos); err != nil || len(txInfos) != len(txTypes) {
+		writeError(w, http.StatusBadRequest, CodeInvalidTx, "invalid tx_infos")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hashes := make([]string, 0, len(txTypes))
+	for i, txType := range txTypes {
+		hash, code, err := s.apply(txType, txInfos[i])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, code, fmt.Sprintf("tx %d: %v", i, err))
+			return
+		}
+		hashes = append(hashes, hash)
+	}
+	writeJSON(w, lighterapi.RespSendTxBatch{Code: 200, TxHash: hashes})
+}
+
+func (s *Server) handleNextNonce(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	accountIndex, _ := strconv.ParseInt(q.Get("account_index"), 10, 64)
+	apiKeyIndex, _ := strconv.ParseUint(q.Get("api_key_index"), 10, 8)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, err := s.ex.account(accountIndex)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, lighterapi.NextNonce{Code: 200, Nonce: acc.nonces[uint8(apiKeyIndex)]})
+}
+
+func (s *Server) handleOrderBooks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := lighterapi.OrderBooks{Code: 200}
+	for _, m := range s.marketsFor(r) {
+		resp.OrderBooks = append(resp.OrderBooks, lighterapi.OrderBook{
+			MarketId:               m.Id,
+			Symbol:                 m.Symbol,
+			Status:                 lighterapi.OrderBookStatusActive,
+			MakerFee:               strconv.FormatFloat(m.MakerFee*100, 'f', 4, 64),
+			TakerFee:               strconv.FormatFloat(m.TakerFee*100, 'f', 4, 64),
+			MinBaseAmount:          m.formatSize(m.MinBaseAmount),
+			SupportedPriceDecimals: m.PriceDecimals,
+			SupportedSizeDecimals:  m.SizeDecimals,
+		})
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleOrderBookDetails(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := lighterapi.OrderBookDetails{Code: 200}
+	for _, m := range s.marketsFor(r) {
+		resp.OrderBookDetails = append(resp.OrderBookDetails, lighterapi.OrderBookDetail{
+			MarketId:                     m.Id,
+			Symbol:                       m.Symbol,
+			Status:                       lighterapi.OrderBookDetailStatusActive,
+			MakerFee:                     strconv.FormatFloat(m.MakerFee*100, 'f', 4, 64),
+			TakerFee:                     strconv.FormatFloat(m.TakerFee*100, 'f', 4, 64),
+			MinBaseAmount:                m.formatSize(m.MinBaseAmount),
+			PriceDecimals:                m.PriceDecimals,
+			SizeDecimals:                 m.SizeDecimals,
+			SupportedPriceDecimals:       m.PriceDecimals,
+			SupportedSizeDecimals:        m.SizeDecimals,
+			MinInitialMarginFraction:     m.MinInitialMarginFraction,
+			DefaultInitialMarginFraction: m.MinInitialMarginFraction,
+			LastTradePrice:               s.ex.markPrice(m),
+		})
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleOrderBookOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	marketId, _ := strconv.ParseUint(q.Get("market_id"), 10, 8)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, err := s.ex.market(uint8(marketId))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeNotFound, err.Error())
+		return
+	}
+	b := s.ex.books[m.Id]
+	resp := lighterapi.OrderBookOrders{Code: 200, TotalBids: int64(len(b.bids)), TotalAsks: int64(len(b.asks))}
+	resp.Bids = simpleOrders(m, b.bids, limit)
+	resp.Asks = simpleOrders(m, b.asks, limit)
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := lighterapi.DetailedAccounts{Code: 200}
+	switch lighterapi.AccountParamsBy(q.Get("by")) {
+	case lighterapi.AccountParamsByIndex:
+		index, _ := strconv.ParseInt(q.Get("value"), 10, 64)
+		acc, err := s.ex.account(index)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeNotFound, err.Error())
+			return
+		}
+		resp.Accounts = append(resp.Accounts, s.ex.apiAccount(acc))
+	case lighterapi.AccountParamsByL1Address:
+		for _, acc := range s.accountsOf(q.Get("value")) {
+			resp.Accounts = append(resp.Accounts, s.ex.apiAccount(acc))
+		}
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidTx, "invalid by")
+		return
+	}
+	resp.Total = int64(len(resp.Accounts))
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleAccountsByL1Address(w http.ResponseWriter, r *http.Request) {
+	l1Address := r.URL.Query().Get("l1_address")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := lighterapi.SubAccounts{Code: 200, L1Address: l1Address}
+	for _, acc := range s.accountsOf(l1Address) {
+		detail := s.ex.apiAccount(acc)
+		resp.SubAccounts = append(resp.SubAccounts, lighterapi.Account{
+			Index:            detail.Index,
+			L1Address:        detail.L1Address,
+			Collateral:       detail.Collateral,
+			AvailableBalance: detail.AvailableBalance,
+			Status:           detail.Status,
+			TotalOrderCount:  detail.TotalOrderCount,
+		})
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleActiveOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	accountIndex, _ := strconv.ParseInt(q.Get("account_index"), 10, 64)
+	marketId, _ := strconv.ParseUint(q.Get("market_id"), 10, 8)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := lighterapi.Orders{Code: 200, Orders: []lighterapi.Order{}}
+	for _, o := range s.ex.sortedOrders() {
+		if o.account == accountIndex && o.market == uint8(marketId) {
+			resp.Orders = append(resp.Orders, s.ex.apiOrder(o))
+		}
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleInactiveOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	accountIndex, _ := strconv.ParseInt(q.Get("account_index"), 10, 64)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := lighterapi.Orders{Code: 200, Orders: []lighterapi.Order{}}
+	for i := len(s.ex.inactive) - 1; i >= 0; i-- {
+		o := s.ex.inactive[i]
+		if o.account != accountIndex || (q.Has("market_id") && q.Get("market_id") != strconv.Itoa(int(o.market))) {
+			continue
+		}
+		resp.Orders = append(resp.Orders, s.ex.apiOrder(o))
+		if limit > 0 && len(resp.Orders) == limit {
+			break
+		}
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleRecentTrades(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	marketId, _ := strconv.ParseUint(q.Get("market_id"), 10, 8)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := lighterapi.Trades{Code: 200, Trades: []lighterapi.Trade{}}
+	for i := len(s.ex.trades) - 1; i >= 0; i-- {
+		if s.ex.trades[i].MarketId != uint8(marketId) {
+			continue
+		}
+		resp.Trades = append(resp.Trades, s.ex.trades[i])
+		if limit > 0 && len(resp.Trades) == limit {
+			break
+		}
+	}
+	writeJSON(w, resp)
+}
+
+// handleTrades filters by market and account in trade id order; cursors are not
+// supported, so every match up to limit is returned in one page
+func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var trades []lighterapi.Trade
+	for _, t := range s.ex.trades {
+		if q.Has("market_id") && q.Get("market_id") != strconv.Itoa(int(t.MarketId)) {
+			continue
+		}
+		if q.Has("account_index") {
+			account, _ := strconv.ParseInt(q.Get("account_index"), 10, 64)
+			if t.AskAccountId != account && t.BidAccountId != account {
+				continue
+			}
+		}
+		trades = append(trades, t)
+	}
+	if lighterapi.TradesParamsSortDir(q.Get("sort_dir")) == lighterapi.TradesParamsSortDirDesc {
+		sort.SliceStable(trades, func(i, j int) bool { return trades[i].TradeId > trades[j].TradeId })
+	}
+	if limit > 0 && len(trades) > limit {
+		trades = trades[:limit]
+	}
+	if trades == nil {
+		trades = []lighterapi.Trade{}
+	}
+	writeJSON(w, lighterapi.Trades{Code: 200, Trades: trades})
+}
+
+func (s *Server) handleTransferFeeInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, lighterapi.TransferFeeInfo{Code: 200, TransferFeeUsdc: int64(math.Round(s.ex.transferFee * txtypes.OneUSDC))})
+}
+
+// handleTransferHistory lists the transfers in and out of an account, newest first
+func (s *Server) handleTransferHistory(w http.ResponseWriter, r *http.Request) {
+	accountIndex, _ := strconv.ParseInt(r.URL.Query().Get("account_index"), 10, 64)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := lighterapi.TransferHistory{Code: 200}
+	for i := len(s.ex.transfers) - 1; i >= 0; i-- {
+		item := s.ex.transfers[i]
+		switch accountIndex {
+		case item.FromAccountIndex:
+			item.Type = lighterapi.L2TransferOutflow
+		case item.ToAccountIndex:
+			item.Type = lighterapi.L2TransferInflow
+		default:
+			continue
+		}
+		resp.Transfers = append(resp.Transfers, item)
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) marketsFor(r *http.Request) []*Market {
+	q := r.URL.Query()
+	var markets []*Market
+	for _, m := range s.ex.markets {
+		if q.Has("market_id") && q.Get("market_id") != strconv.Itoa(int(m.Id)) {
+			continue
+		}
+		markets = append(markets, m)
+	}
+	sort.Slice(markets, func(i, j int) bool { return markets[i].Id < markets[j].Id })
+	return markets
+}
+
+func (s *Server) accountsOf(l1Address string) []*account {
+	var accounts []*account
+	for _, acc := range s.ex.accounts {
+		if strings.EqualFold(acc.l1Address, l1Address) {
+			accounts = append(accounts, acc)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].index < accounts[j].index })
+	return accounts
+}
+
+func simpleOrders(m *Market, orders []*order, limit int) []lighterapi.SimpleOrder {
+	if limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+	out := make([]lighterapi.SimpleOrder, 0, len(orders))
+	for _, o := range orders {
+		out = append(out, lighterapi.SimpleOrder{
+			OrderIndex:          o.index,
+			OrderId:             strconv.FormatInt(o.index, 10),
+			OwnerAccountIndex:   o.account,
+			Price:               m.formatPrice(o.price),
+			InitialBaseAmount:   m.formatSize(o.initial),
+			RemainingBaseAmount: m.formatSize(o.remaining),
+			OrderExpiry:         o.expiry,
+		})
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code int32, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(lighterapi.ResultCode{Code: code, Message: &message})
+}
+
+// txHash mirrors the hash the SDK signs so RespSendTx.TxHash matches GetTxHash
+func (s *Server) txHash(info txtypes.TxInfo) string {
+	hash, err := info.Hash(s.chainID)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash)
+}
+
+// sleep waits for d or until the request is canceled
+func sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,287 @@
+package lightertest_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const (
+	trader = lightertest.TraderAccount
+	maker  = lightertest.MakerAccount
+)
+
+func sendOrder(t *testing.T, tx *client.TxClient, req types.CreateOrderTxReq) (*lighterapi.RespSendTx, error) {
+	t.Helper()
+	info, err := tx.GetCreateOrderTransaction(&req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx.Send(context.Background(), info, nil)
+}
+
+func mustPlace(t *testing.T, srv *lightertest.Server, isAsk bool, price uint32, base int64) int64 {
+	t.Helper()
+	index, err := srv.PlaceOrder(maker, 0, isAsk, price, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func collateral(t *testing.T, srv *lightertest.Server, account int64) float64 {
+	t.Helper()
+	acc, err := srv.Account(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := strconv.ParseFloat(acc.Collateral, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// expiry is the order expiry resting orders need to pass validation
+func expiry() int64 {
+	return time.Now().Add(time.Hour).UnixMilli()
+}
+
+func near(a, b float64) bool {
+	d := a - b
+	return d < 1e-6 && d > -1e-6
+}
+
+func TestMatchingPriceTimePriority(t *testing.T) {
+	srv, _, tx := lightertest.NewExchange(t)
+	first := mustPlace(t, srv, true, 300100, 100)
+	second := mustPlace(t, srv, true, 300100, 100)
+	mustPlace(t, srv, true, 300050, 100) // the better price trades first
+
+	resp, err := sendOrder(t, tx, types.CreateOrderTxReq{
+		ClientOrderIndex: 1, BaseAmount: 250, Price: 300100, Type: txtypes.LimitOrder, TimeInForce: txtypes.ImmediateOrCancel,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trades := srv.Trades(0)
+	if len(trades) != 3 {
+		t.Fatalf("%d trades, want 3", len(trades))
+	}
+	wantPrices := []string{"3000.50", "3001.00", "3001.00"}
+	wantSizes := []string{"0.0100", "0.0100", "0.0050"}
+	for i, tr := range trades {
+		if tr.Price != wantPrices[i] || tr.Size != wantSizes[i] || tr.TxHash != resp.TxHash || !tr.IsMakerAsk {
+			t.Fatalf("trade %d = %s @ %s (hash %q), want %s @ %s", i, tr.Size, tr.Price, tr.TxHash, wantSizes[i], wantPrices[i])
+		}
+	}
+	if trades[1].AskId != first || trades[2].AskId != second {
+		t.Fatalf("orders at 3001 traded as %d then %d, want %d then %d", trades[1].AskId, trades[2].AskId, first, second)
+	}
+	if got := srv.OrderStatus(first); got != lighterapi.OrderStatusFilled {
+		t.Fatalf("first order %s, want filled", got)
+	}
+	if got := srv.OrderStatus(second); got != lighterapi.OrderStatusOpen {
+		t.Fatalf("second order %s, want open with its remainder", got)
+	}
+
+	// 0.025 ETH bought for 75.0175 USDC pays the taker fee; the maker collects none but pays 2 bps
+	notional := 0.01*3000.50 + 0.015*3001
+	if got, want := collateral(t, srv, trader), 10000-notional*0.0005; !near(got, want) {
+		t.Fatalf("trader collateral %.6f, want %.6f", got, want)
+	}
+	if got, want := collateral(t, srv, maker), 10000-notional*0.0002; !near(got, want) {
+		t.Fatalf("maker collateral %.6f, want %.6f", got, want)
+	}
+	acc, err := srv.Account(trader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acc.Positions) != 1 || acc.Positions[0].Sign != 1 || acc.Positions[0].Position != "0.0250" {
+		t.Fatalf("trader positions %+v, want a 0.0250 long", acc.Positions)
+	}
+}
+
+func TestPostOnlyAndIOC(t *testing.T) {
+	srv, _, tx := lightertest.NewExchange(t)
+	mustPlace(t, srv, true, 300100, 100)
+
+	if _, err := sendOrder(t, tx, types.CreateOrderTxReq{
+		ClientOrderIndex: 1, BaseAmount: 100, Price: 300100, Type: txtypes.LimitOrder, TimeInForce: txtypes.PostOnly, OrderExpiry: expiry(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sendOrder(t, tx, types.CreateOrderTxReq{
+		ClientOrderIndex: 2, BaseAmount: 100, Price: 299000, Type: txtypes.LimitOrder, TimeInForce: txtypes.ImmediateOrCancel,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if trades := srv.Trades(0); len(trades) != 0 {
+		t.Fatalf("%d trades, want none", len(trades))
+	}
+	if orders := srv.Orders(trader); len(orders) != 0 {
+		t.Fatalf("trader rests %d orders, want none", len(orders))
+	}
+}
+
+func TestNoncesAdvanceOnlyOnAcceptedTransactions(t *testing.T) {
+	_, api, tx := lightertest.NewExchange(t)
+	ctx := context.Background()
+
+	nonce := int64(0)
+	ops := &types.TransactOpts{Nonce: &nonce}
+	reject := types.CreateOrderTxReq{ClientOrderIndex: 1, BaseAmount: 1, Price: 300000, Type: txtypes.LimitOrder, TimeInForce: txtypes.GoodTillTime, OrderExpiry: expiry()}
+	info, err := tx.GetCreateOrderTransaction(&reject, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var apiErr *client.APIError
+	if _, err := tx.Send(ctx, info, nil); !errors.As(err, &apiErr) || apiErr.Code != lightertest.CodeInvalidTx {
+		t.Fatalf("order below the market minimum returned %v, want code %d", err, lightertest.CodeInvalidTx)
+	}
+	if next, err := api.NextNonceValue(ctx, trader, 0); err != nil || next != 0 {
+		t.Fatalf("next nonce %d (%v) after a rejection, want 0", next, err)
+	}
+
+	stale := int64(5)
+	info, err = tx.GetCreateOrderTransaction(&types.CreateOrderTxReq{ClientOrderIndex: 2, BaseAmount: 10, Price: 290000, Type: txtypes.LimitOrder, TimeInForce: txtypes.GoodTillTime, OrderExpiry: expiry()}, &types.TransactOpts{Nonce: &stale})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Send(ctx, info, nil); !errors.As(err, &apiErr) || apiErr.Code != lightertest.CodeInvalidNonce {
+		t.Fatalf("out of order nonce returned %v, want code %d", err, lightertest.CodeInvalidNonce)
+	}
+
+	if _, err := sendOrder(t, tx, types.CreateOrderTxReq{ClientOrderIndex: 3, BaseAmount: 10, Price: 290000, Type: txtypes.LimitOrder, TimeInForce: txtypes.GoodTillTime, OrderExpiry: expiry()}); err != nil {
+		t.Fatal(err)
+	}
+	if next, err := api.NextNonceValue(ctx, trader, 0); err != nil || next != 1 {
+		t.Fatalf("next nonce %d (%v), want 1", next, err)
+	}
+}
+
+func TestGroupedOrdersAreAtomic(t *testing.T) {
+	srv, api, tx := lightertest.NewExchange(t)
+	ops, err := tx.FullFillDefaultOps(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The parent is valid; the stop-loss child is an order type the fake does not support
+	group, err := types.ConstructL2CreateGroupedOrdersTx(tx.GetKeyManager(), 0, &types.CreateGroupedOrdersTxReq{
+		GroupingType: txtypes.GroupingType_OneTriggersTheOther,
+		Orders: []*types.CreateOrderTxReq{
+			{BaseAmount: 10, Price: 290000, Type: txtypes.LimitOrder, TimeInForce: txtypes.GoodTillTime, OrderExpiry: expiry()},
+			{IsAsk: 1, Price: 280000, TriggerPrice: 280000, Type: txtypes.StopLossOrder, TimeInForce: txtypes.ImmediateOrCancel, ReduceOnly: 1, OrderExpiry: expiry()},
+		},
+	}, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Send(context.Background(), group, nil); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("group returned %v, want the child rejected", err)
+	}
+	if orders := srv.Orders(trader); len(orders) != 0 {
+		t.Fatalf("rejected group left %d orders resting", len(orders))
+	}
+	if next, err := api.NextNonceValue(context.Background(), trader, 0); err != nil || next != 0 {
+		t.Fatalf("next nonce %d (%v) after a rejected group, want 0", next, err)
+	}
+}
+
+func TestFaultsApplyInOrder(t *testing.T) {
+	srv, api, _ := lightertest.NewExchange(t)
+	ctx := context.Background()
+	const path = "/api/v1/nextNonce"
+
+	// An unlimited fault does not hide the limited faults queued after it
+	srv.Inject(path, lightertest.Fault{Status: 503, Message: "unlimited"})
+	srv.FailNext(path, 1, 500, "first")
+	srv.RateLimit(path, 1)
+
+	var apiErr *client.APIError
+	for _, want := range []int{500, 429, 503, 503} {
+		if _, err := api.NextNonceValue(ctx, trader, 0); !errors.As(err, &apiErr) || apiErr.Status != want {
+			t.Fatalf("request returned %v, want status %d", err, want)
+		}
+	}
+	srv.ClearFaults()
+	if _, err := api.NextNonceValue(ctx, trader, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests(path); n != 5 {
+		t.Fatalf("%d requests counted, want 5", n)
+	}
+}
+
+func TestWSOrdersChannel(t *testing.T) {
+	ws := lightertest.NewWSServer()
+	defer ws.Close()
+	ws.RequireAuth("secret")
+	ws.SetOrders(trader, []lighterapi.Order{{OrderIndex: 7, OrderId: "7", ClientOrderIndex: 1, Price: "3000.00", InitialBaseAmount: "0.0100", FilledBaseAmount: "0", Status: lighterapi.OrderStatusOpen}})
+
+	private := client.NewLighterWebsocketPrivateService(ws.Config(), func() string { return "secret" })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := private.Start(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer private.Close()
+
+	got := make(chan client.LighterOrdersResponse, 4)
+	if _, err := private.SubscribeOrders(client.LighterOrdersParamKey{AccountId: trader}, func(o client.LighterOrdersResponse) error {
+		got <- o
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.WaitForSubscription(ctx, "account_all_orders/1"); err != nil {
+		t.Fatal(err)
+	}
+	ws.PublishOrders(trader, []lighterapi.Order{{OrderIndex: 7, OrderId: "7", ClientOrderIndex: 1, Price: "3000.00", InitialBaseAmount: "0.0100", FilledBaseAmount: "0.0100", Status: lighterapi.OrderStatusFilled}})
+
+	for _, want := range []struct {
+		status   lighterapi.OrderStatus
+		snapshot bool
+	}{{lighterapi.OrderStatusOpen, true}, {lighterapi.OrderStatusFilled, false}} {
+		select {
+		case o := <-got:
+			if o.OrderId != "7" || o.Status != string(want.status) || o.IsSnapshot != want.snapshot {
+				t.Fatalf("order update %+v, want %s with snapshot %v", o, want.status, want.snapshot)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no %s order update", want.status)
+		}
+	}
+}
+
+func TestHandleServesUnmodeledRoutes(t *testing.T) {
+	srv, api, _ := lightertest.NewExchange(t)
+	ctx := context.Background()
+	const path = "/api/v1/funding-rates"
+	srv.HandleJSON(path, lighterapi.FundingRates{Code: 200, FundingRates: []lighterapi.FundingRate{{Exchange: "lighter", MarketId: 0, Rate: 0.0001, Symbol: "ETH"}}})
+
+	resp, err := api.FundingRates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.FundingRates) != 1 || resp.FundingRates[0].Rate != 0.0001 {
+		t.Fatalf("funding rates %+v, want the served rate", resp.FundingRates)
+	}
+	// Faults still apply ahead of the handler
+	srv.FailNext(path, 1, 503, "unavailable")
+	if _, err := api.FundingRates(ctx); err == nil {
+		t.Fatal("expected the injected fault")
+	}
+	if n := srv.Requests(path); n != 2 {
+		t.Fatalf("%d requests counted, want 2", n)
+	}
+}
@@ -0,0 +1,269 @@
+package lightertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// txHeader holds the fields every L2 transaction carries; transfers and withdrawals name
+// the sender FromAccountIndex
+type txHeader struct {
+	AccountIndex     *int64
+	FromAccountIndex *int64
+	ApiKeyIndex      uint8
+	ExpiredAt        int64
+	Nonce            int64
+}
+
+// apply validates and executes a signed transaction. Nonces must arrive in order per API key
+// and are consumed only by accepted transactions. Signatures are not verified. Order, cancel,
+// modify, sub-account, transfer, withdraw, leverage and margin transactions change state; other types are
+// accepted and only consume their nonce.
+func (s *Server) apply(txType uint8, payload string) (string, int32, error) {
+	var header txHeader
+	if err := json.Unmarshal([]byte(payload), &header); err != nil {
+		return "", CodeInvalidTx, fmt.Errorf("invalid tx_info: %w", err)
+	}
+	accountIndex := header.AccountIndex
+	if accountIndex == nil {
+		accountIndex = header.FromAccountIndex
+	}
+	if accountIndex == nil {
+		return "", CodeInvalidTx, fmt.Errorf("tx_info has no account index")
+	}
+	acc, err := s.ex.account(*accountIndex)
+	if err != nil {
+		return "", CodeNotFound, err
+	}
+	if expected := acc.nonces[header.ApiKeyIndex]; header.Nonce != expected {
+		return "", CodeInvalidNonce, fmt.Errorf("invalid nonce %d, expected %d", header.Nonce, expected)
+	}
+	now := s.now().UnixMilli()
+	if header.ExpiredAt != 0 && header.ExpiredAt < now {
+		return "", CodeExpiredTx, fmt.Errorf("transaction expired at %d", header.ExpiredAt)
+	}
+
+	info, err := decodeTx(txType, payload)
+	if err != nil {
+		return "", CodeInvalidTx, err
+	}
+	if err := info.Validate(); err != nil {
+		return "", CodeInvalidTx, err
+	}
+	hash := s.txHash(info)
+	if err := s.execute(info, header.Nonce, hash, now); err != nil {
+		return "", CodeInvalidTx, err
+	}
+	acc.nonces[header.ApiKeyIndex]++
+	return hash, 0, nil
+}
+
+func decodeTx(txType uint8, payload string) (txtypes.TxInfo, error) {
+	var info txtypes.TxInfo
+	switch txType {
+	case txtypes.TxTypeL2ChangePubKey:
+		info = &txtypes.L2ChangePubKeyTxInfo{}
+	case txtypes.TxTypeL2CreateSubAccount:
+		info = &txtypes.L2CreateSubAccountTxInfo{}
+	case txtypes.TxTypeL2CreatePublicPool:
+		info = &txtypes.L2CreatePublicPoolTxInfo{}
+	case txtypes.TxTypeL2UpdatePublicPool:
+		info = &txtypes.L2UpdatePublicPoolTxInfo{}
+	case txtypes.TxTypeL2Transfer:
+		info = &txtypes.L2TransferTxInfo{}
+	case txtypes.TxTypeL2Withdraw:
+		info = &txtypes.L2WithdrawTxInfo{}
+	case txtypes.TxTypeL2CreateOrder:
+		info = &txtypes.L2CreateOrderTxInfo{}
+	case txtypes.TxTypeL2CancelOrder:
+		info = &txtypes.L2CancelOrderTxInfo{}
+	case txtypes.TxTypeL2CancelAllOrders:
+		info = &txtypes.L2CancelAllOrdersTxInfo{}
+	case txtypes.TxTypeL2ModifyOrder:
+		info = &txtypes.L2ModifyOrderTxInfo{}
+	case txtypes.TxTypeL2MintShares:
+		info = &txtypes.L2MintSharesTxInfo{}
+	case txtypes.TxTypeL2BurnShares:
+		info = &txtypes.L2BurnSharesTxInfo{}
+	case txtypes.TxTypeL2UpdateLeverage:
+		info = &txtypes.L2UpdateLeverageTxInfo{}
+	case txtypes.TxTypeL2CreateGroupedOrders:
+		info = &txtypes.L2CreateGroupedOrdersTxInfo{}
+	case txtypes.TxTypeL2UpdateMargin:
+		info = &txtypes.L2UpdateMarginTxInfo{}
+	default:
+		return nil, fmt.Errorf("unsupported tx_type %d", txType)
+	}
+	if err := json.Unmarshal([]byte(payload), info); err != nil {
+		return nil, fmt.Errorf("invalid tx_info: %w", err)
+	}
+	return info, nil
+}
+
+func (s *Server) execute(info txtypes.TxInfo, nonce int64, hash string, now int64) error {
+	switch tx := info.(type) {
+	case *txtypes.L2CreateOrderTxInfo:
+		return s.createOrder(tx.AccountIndex, tx.OrderInfo, nonce, hash, now)
+	case *txtypes.L2CreateGroupedOrdersTxInfo:
+		// The group is accepted or rejected as a whole, so every order is checked first
+		orders := make([]*order, len(tx.Orders))
+		clientIndexes := make(map[int64]bool, len(tx.Orders))
+		for i, info := range tx.Orders {
+			o, err := newOrder(tx.AccountIndex, info, nonce)
+			if err != nil {
+				return err
+			}
+			if err := s.ex.check(o); err != nil {
+				return err
+			}
+			if o.clientIndex != txtypes.NilClientOrderIndex {
+				if clientIndexes[o.clientIndex] {
+					return fmt.Errorf("client order index %d used twice in group", o.clientIndex)
+				}
+				clientIndexes[o.clientIndex] = true
+			}
+			orders[i] = o
+		}
+		for _, o := range orders {
+			if err := s.ex.place(o, hash, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *txtypes.L2CancelOrderTxInfo:
+		o, err := s.ex.lookup(tx.AccountIndex, tx.MarketIndex, tx.Index)
+		if err != nil {
+			return err
+		}
+		s.ex.cancel(o)
+		return nil
+	case *txtypes.L2CancelAllOrdersTxInfo:
+		if tx.TimeInForce == txtypes.ImmediateCancelAll {
+			s.ex.cancelAll(tx.AccountIndex)
+		}
+		return nil
+	case *txtypes.L2ModifyOrderTxInfo:
+		o, err := s.ex.lookup(tx.AccountIndex, tx.MarketIndex, tx.Index)
+		if err != nil {
+			return err
+		}
+		return s.ex.modify(o, tx.BaseAmount, tx.Price, hash, now)
+	case *txtypes.L2CreateSubAccountTxInfo:
+		// Sub-accounts take the next free index and belong to the creator's L1 address
+		index := tx.AccountIndex
+		for i := range s.ex.accounts {
+			index = max(index, i)
+		}
+		s.ex.accounts[index+1] = &account{
+			index:     index + 1,
+			l1Address: s.ex.accounts[tx.AccountIndex].l1Address,
+			positions: make(map[uint8]*position),
+			nonces:    make(map[uint8]int64),
+		}
+		return nil
+	case *txtypes.L2TransferTxInfo:
+		from := s.ex.accounts[tx.FromAccountIndex]
+		to, err := s.ex.account(tx.ToAccountIndex)
+		if err != nil {
+			return err
+		}
+		amount := float64(tx.USDCAmount) / txtypes.OneUSDC
+		fee := float64(tx.Fee) / txtypes.OneUSDC
+		if fee < s.ex.transferFee {
+			return fmt.Errorf("transfer fee %v below %v", fee, s.ex.transferFee)
+		}
+		if from.collateral < amount+fee {
+			return fmt.Errorf("insufficient collateral for transfer")
+		}
+		from.collateral -= amount + fee
+		to.collateral += amount
+		s.ex.transfers = append(s.ex.transfers, lighterapi.TransferHistoryItem{
+			Id:               strconv.Itoa(len(s.ex.transfers) + 1),
+			Amount:           strconv.FormatFloat(amount, 'f', 6, 64),
+			FromAccountIndex: from.index,
+			FromL1Address:    from.l1Address,
+			ToAccountIndex:   to.index,
+			ToL1Address:      to.l1Address,
+			Timestamp:        now,
+			TxHash:           hash,
+		})
+		return nil
+	case *txtypes.L2WithdrawTxInfo:
+		from := s.ex.accounts[tx.FromAccountIndex]
+		amount := float64(tx.USDCAmount) / txtypes.OneUSDC
+		if from.collateral < amount {
+			return fmt.Errorf("insufficient collateral for withdrawal")
+		}
+		from.collateral -= amount
+		return nil
+	case *txtypes.L2UpdateLeverageTxInfo:
+		m, err := s.ex.market(tx.MarketIndex)
+		if err != nil {
+			return err
+		}
+		if int(tx.InitialMarginFraction) < m.MinInitialMarginFraction {
+			return fmt.Errorf("initial margin fraction %d below market minimum %d", tx.InitialMarginFraction, m.MinInitialMarginFraction)
+		}
+		pos := s.ex.accounts[tx.AccountIndex].position(m)
+		pos.imf = int(tx.InitialMarginFraction)
+		pos.marginMode = int32(tx.MarginMode)
+		return nil
+	case *txtypes.L2UpdateMarginTxInfo:
+		m, err := s.ex.market(tx.MarketIndex)
+		if err != nil {
+			return err
+		}
+		acc := s.ex.accounts[tx.AccountIndex]
+		pos := acc.position(m)
+		if pos.marginMode != txtypes.IsolatedMargin {
+			return fmt.Errorf("market %d is not in isolated margin mode", m.Id)
+		}
+		amount := float64(tx.USDCAmount) / txtypes.OneUSDC
+		if tx.Direction == txtypes.RemoveFromIsolatedMargin {
+			amount = -amount
+		}
+		if pos.allocated+amount < 0 || acc.collateral-amount < 0 {
+			return fmt.Errorf("insufficient margin")
+		}
+		pos.allocated += amount
+		acc.collateral -= amount
+		return nil
+	}
+	return nil
+}
+
+func (s *Server) createOrder(accountIndex int64, info *txtypes.OrderInfo, nonce int64, hash string, now int64) error {
+	o, err := newOrder(accountIndex, info, nonce)
+	if err != nil {
+		return err
+	}
+	return s.ex.place(o, hash, now)
+}
+
+func newOrder(accountIndex int64, info *txtypes.OrderInfo, nonce int64) (*order, error) {
+	if info == nil {
+		return nil, fmt.Errorf("order is missing")
+	}
+	switch info.Type {
+	case txtypes.LimitOrder, txtypes.MarketOrder:
+	default:
+		return nil, fmt.Errorf("order type %s is not supported by the fake", orderTypes[info.Type])
+	}
+	return &order{
+		clientIndex: info.ClientOrderIndex,
+		account:     accountIndex,
+		market:      info.MarketIndex,
+		isAsk:       info.IsAsk == 1,
+		price:       info.Price,
+		initial:     info.BaseAmount,
+		typ:         info.Type,
+		tif:         info.TimeInForce,
+		reduceOnly:  info.ReduceOnly == 1,
+		expiry:      info.OrderExpiry,
+		nonce:       nonce,
+	}, nil
+}
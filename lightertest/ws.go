@@ -0,0 +1,477 @@
+package lightertest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/gorilla/websocket"
+)
+
+// Error codes the fake stream sends in {"error": ...} messages
+const (
+	WSCodeInvalidChannel int = 30005
+	WSCodeUnauthorized   int = 30003
+)
+
+// WSServer is a fake of the Lighter stream endpoint. It answers subscribe and unsubscribe
+// requests, replies to pings, sends the scripted snapshot for each subscription and
+// broadcasts published updates to subscribers.
+type WSServer struct {
+	*httptest.Server
+
+	upgrader websocket.Upgrader
+
+	mu        sync.Mutex
+	conns     map[*wsConn]bool
+	books     map[uint8]*wsBook
+	accounts  map[int64]client.WSAccountUpdate
+	orders    map[int64][]lighterapi.Order
+	trades    map[uint8][]client.WSTrade
+	authToken string
+	reject    int
+	pongs     int
+	changed   chan struct{}
+}
+
+type wsConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	auth    string
+	subs    map[string]bool
+}
+
+func (c *wsConn) send(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// wsBook is the scripted state of one order book; offset advances with every update
+type wsBook struct {
+	bids   map[string]string
+	asks   map[string]string
+	offset int64
+}
+
+// NewWSServer starts a fake stream server; call Close when done
+func NewWSServer() *WSServer {
+	s := &WSServer{
+		conns:    make(map[*wsConn]bool),
+		books:    make(map[uint8]*wsBook),
+		accounts: make(map[int64]client.WSAccountUpdate),
+		orders:   make(map[int64][]lighterapi.Order),
+		trades:   make(map[uint8][]client.WSTrade),
+		changed:  make(chan struct{}),
+	}
+	s.upgrader.CheckOrigin = func(*http.Request) bool { return true }
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// WSURL returns the ws:// address of the stream
+func (s *WSServer) WSURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/stream"
+}
+
+// Config returns a WSConfig pointed at the fake with short timeouts suited to tests
+func (s *WSServer) Config() *client.WSConfig {
+	cfg := client.DefaultWSConfig()
+	cfg.URL = s.WSURL()
+	cfg.ReconnectDelay = 50 * time.Millisecond
+	cfg.ReadTimeout = 5 * time.Second
+	cfg.WriteTimeout = time.Second
+	return cfg
+}
+
+// Close drops every connection and shuts the server down
+func (s *WSServer) Close() {
+	s.DropConnections()
+	s.Server.Close()
+}
+
+// RequireAuth makes account_all and account_all_orders subscriptions require "Bearer token" on the connection;
+// an empty token disables the check
+func (s *WSServer) RequireAuth(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authToken = token
+}
+
+// SetOrderBook replaces the snapshot sent to new order_book subscribers. Offsets keep
+// counting from the previous state.
+func (s *WSServer) SetOrderBook(marketId uint8, bids, asks []client.WSPriceLevel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.book(marketId)
+	b.bids = make(map[string]string)
+	b.asks = make(map[string]string)
+	applyWSLevels(b.bids, bids)
+	applyWSLevels(b.asks, asks)
+}
+
+// PublishOrderBook applies level changes to the book (size "0" removes a level) and sends
+// them to subscribers as update/order_book with the next offset
+func (s *WSServer) PublishOrderBook(marketId uint8, bids, asks []client.WSPriceLevel) {
+	s.mu.Lock()
+	b := s.book(marketId)
+	applyWSLevels(b.bids, bids)
+	applyWSLevels(b.asks, asks)
+	b.offset++
+	msg := orderBookMessage(client.MessageTypeOrderBookUpdate, marketId, bids, asks, b.offset)
+	s.mu.Unlock()
+	s.broadcast(fmt.Sprintf("%s/%d", client.ChannelOrderBook, marketId), msg)
+}
+
+// SkipOffsets advances a book's offset by n without sending anything, so the next update
+// shows a gap
+func (s *WSServer) SkipOffsets(marketId uint8, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.book(marketId).offset += n
+}
+
+// SetAccount sets the snapshot sent to new account_all subscribers
+func (s *WSServer) SetAccount(accountId int64, snapshot client.WSAccountUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[accountId] = snapshot
+}
+
+// PublishAccount sends update/account_all to the account's subscribers
+func (s *WSServer) PublishAccount(accountId int64, update client.WSAccountUpdate) {
+	channel := fmt.Sprintf("%s/%d", client.ChannelAccount, accountId)
+	update.Type = client.MessageTypeAccountUpdate
+	update.Channel = wireChannel(channel)
+	update.Account = accountId
+	s.broadcast(channel, update)
+}
+
+// SetOrders sets the open orders sent to new account_all_orders subscribers
+func (s *WSServer) SetOrders(accountId int64, orders []lighterapi.Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[accountId] = orders
+}
+
+// PublishOrders sends changed orders of an account as update/account_all_orders
+func (s *WSServer) PublishOrders(accountId int64, orders []lighterapi.Order) {
+	channel := fmt.Sprintf("%s/%d", client.ChannelAccountOrders, accountId)
+	s.broadcast(channel, ordersMessage(client.MessageTypeAccountOrdersUpdate, channel, accountId, orders))
+}
+
+// PublishTrades records trades for later trade snapshots and sends them as update/trade
+func (s *WSServer) PublishTrades(marketId uint8, trades []client.WSTrade) {
+	s.mu.Lock()
+	s.trades[marketId] = append(s.trades[marketId], trades...)
+	s.mu.Unlock()
+	channel := fmt.Sprintf("%s/%d", client.ChannelTrade, marketId)
+	s.broadcast(channel, tradesMessage(client.MessageTypeTradeUpdate, channel, trades))
+}
+
+// Broadcast sends a raw message to every connection
+func (s *WSServer) Broadcast(msg any) {
+	for _, c := range s.connections() {
+		_ = c.send(msg)
+	}
+}
+
+// SendError sends a server error message to every connection
+func (s *WSServer) SendError(code int, message string) {
+	s.Broadcast(wsError(code, message))
+}
+
+// Ping sends a server ping to every connection; replies are counted by Pongs
+func (s *WSServer) Ping() {
+	s.Broadcast(client.WSMessage{Type: client.MessageTypePing})
+}
+
+// Pongs returns how many pong replies the server has received
+func (s *WSServer) Pongs() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pongs
+}
+
+// DropConnections closes every connection without a close handshake, as a network failure would
+func (s *WSServer) DropConnections() {
+	for _, c := range s.connections() {
+		_ = c.conn.UnderlyingConn().Close()
+	}
+}
+
+// RejectConnections refuses the next n connection attempts with HTTP 503
+func (s *WSServer) RejectConnections(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = n
+}
+
+// Connections returns the number of open connections
+func (s *WSServer) Connections() int {
+	return len(s.connections())
+}
+
+// Subscribed reports whether any connection is subscribed to channel ("order_book/0")
+func (s *WSServer) Subscribed(channel string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		if c.subs[channel] {
+			return true
+		}
+	}
+	return false
+}
+
+// WaitForSubscription blocks until a connection subscribes to channel or ctx is done
+func (s *WSServer) WaitForSubscription(ctx context.Context, channel string) error {
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+		if s.Subscribed(channel) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("lightertest: wait for %s: %w", channel, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+func (s *WSServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.reject > 0 {
+		s.reject--
+		s.mu.Unlock()
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	s.mu.Unlock()
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &wsConn{
+		conn: conn,
+		auth: strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		subs: make(map[string]bool),
+	}
+	s.mu.Lock()
+	s.conns[c] = true
+	s.notify()
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.notify()
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	if err := c.send(map[string]string{"type": client.MessageTypeConnected, "session_id": strconv.FormatInt(time.Now().UnixNano(), 36)}); err != nil {
+		return
+	}
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg client.WSSubscribeMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			_ = c.send(wsError(WSCodeInvalidChannel, "invalid message"))
+			continue
+		}
+		switch msg.Type {
+		case client.MessageTypePing:
+			_ = c.send(client.WSMessage{Type: client.MessageTypePong})
+		case client.MessageTypePong:
+			s.mu.Lock()
+			s.pongs++
+			s.mu.Unlock()
+		case client.MessageTypeSubscribe:
+			s.subscribe(c, msg.Channel)
+		case client.MessageTypeUnsubscribe:
+			s.mu.Lock()
+			delete(c.subs, msg.Channel)
+			s.notify()
+			s.mu.Unlock()
+			_ = c.send(map[string]string{"type": client.MessageTypeUnsubscribed, "channel": wireChannel(msg.Channel)})
+		default:
+			_ = c.send(wsError(WSCodeInvalidChannel, "unknown message type "+msg.Type))
+		}
+	}
+}
+
+// subscribe registers the channel and sends its snapshot
+func (s *WSServer) subscribe(c *wsConn, channel string) {
+	name, idText, _ := strings.Cut(channel, "/")
+	id, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil {
+		_ = c.send(wsError(WSCodeInvalidChannel, "invalid channel "+channel))
+		return
+	}
+
+	s.mu.Lock()
+	var snapshot any
+	switch name {
+	case client.ChannelOrderBook:
+		b := s.book(uint8(id))
+		snapshot = orderBookMessage(client.MessageTypeOrderBookSubscribed, uint8(id), wsLevels(b.bids, true), wsLevels(b.asks, false), b.offset)
+	case client.ChannelTrade:
+		snapshot = tradesMessage(client.MessageTypeTradeSubscribed, channel, s.trades[uint8(id)])
+	case client.ChannelAccount, client.ChannelAccountOrders:
+		if s.authToken != "" && c.auth != s.authToken {
+			s.mu.Unlock()
+			_ = c.send(wsError(WSCodeUnauthorized, "unauthorized"))
+			return
+		}
+		if name == client.ChannelAccountOrders {
+			snapshot = ordersMessage(client.MessageTypeAccountOrdersSubscribed, channel, id, s.orders[id])
+			break
+		}
+		account := s.accounts[id]
+		account.Type = client.MessageTypeAccountSubscribed
+		account.Channel = wireChannel(channel)
+		account.Account = id
+		snapshot = account
+	default:
+		s.mu.Unlock()
+		_ = c.send(wsError(WSCodeInvalidChannel, "invalid channel "+channel))
+		return
+	}
+	c.subs[channel] = true
+	s.notify()
+	// Send under the lock so no update published meanwhile can overtake the snapshot
+	_ = c.send(snapshot)
+	s.mu.Unlock()
+}
+
+func (s *WSServer) broadcast(channel string, msg any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		if c.subs[channel] {
+			_ = c.send(msg)
+		}
+	}
+}
+
+func (s *WSServer) connections() []*wsConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conns := make([]*wsConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+func (s *WSServer) book(marketId uint8) *wsBook {
+	b, ok := s.books[marketId]
+	if !ok {
+		b = &wsBook{bids: make(map[string]string), asks: make(map[string]string)}
+		s.books[marketId] = b
+	}
+	return b
+}
+
+// notify wakes WaitForSubscription callers; s.mu must be held
+func (s *WSServer) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// wireChannel converts a subscription channel ("order_book/0") into the form the server
+// echoes in messages ("order_book:0")
+func wireChannel(channel string) string {
+	return strings.Replace(channel, "/", ":", 1)
+}
+
+func orderBookMessage(msgType string, marketId uint8, bids, asks []client.WSPriceLevel, offset int64) map[string]any {
+	if bids == nil {
+		bids = []client.WSPriceLevel{}
+	}
+	if asks == nil {
+		asks = []client.WSPriceLevel{}
+	}
+	return map[string]any{
+		"type":    msgType,
+		"channel": fmt.Sprintf("%s:%d", client.ChannelOrderBook, marketId),
+		"order_book": map[string]any{
+			"code":   0,
+			"bids":   bids,
+			"asks":   asks,
+			"offset": offset,
+		},
+		"timestamp": time.Now().UnixMilli(),
+	}
+}
+
+func tradesMessage(msgType, channel string, trades []client.WSTrade) map[string]any {
+	if trades == nil {
+		trades = []client.WSTrade{}
+	}
+	return map[string]any{
+		"type":    msgType,
+		"channel": wireChannel(channel),
+		"trades":  trades,
+	}
+}
+
+// ordersMessage groups orders by market as the account_all_orders channel does
+func ordersMessage(msgType, channel string, accountId int64, orders []lighterapi.Order) client.WSAccountOrdersUpdate {
+	byMarket := make(map[string][]lighterapi.Order)
+	for _, o := range orders {
+		key := strconv.Itoa(int(o.MarketIndex))
+		byMarket[key] = append(byMarket[key], o)
+	}
+	return client.WSAccountOrdersUpdate{Account: accountId, Channel: wireChannel(channel), Type: msgType, Orders: byMarket}
+}
+
+func wsError(code int, message string) map[string]any {
+	return map[string]any{"error": map[string]any{"code": code, "message": message}}
+}
+
+func applyWSLevels(side map[string]string, levels []client.WSPriceLevel) {
+	for _, l := range levels {
+		if size, err := strconv.ParseFloat(l.Size, 64); err == nil && size == 0 {
+			delete(side, l.Price)
+			continue
+		}
+		side[l.Price] = l.Size
+	}
+}
+
+func wsLevels(side map[string]string, desc bool) []client.WSPriceLevel {
+	levels := make([]client.WSPriceLevel, 0, len(side))
+	for price, size := range side {
+		levels = append(levels, client.WSPriceLevel{Price: price, Size: size})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		pi, _ := strconv.ParseFloat(levels[i].Price, 64)
+		pj, _ := strconv.ParseFloat(levels[j].Price, 64)
+		if desc {
+			return pi > pj
+		}
+		return pi < pj
+	})
+	return levels
+}
@@ -0,0 +1,287 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const (
+	daysPerYear  = 365
+	poolPageSize = 100
+)
+
+// Holding is an investment in a public pool valued at the current share price
+type Holding struct {
+	PoolIndex  int64
+	Name       string
+	Shares     int64
+	EntryUsdc  float64
+	SharePrice float64
+	Value      float64
+	Pnl        float64
+	PnlPercent float64
+	APY        float64
+}
+
+// PoolRank scores a public pool from its daily returns
+type PoolRank struct {
+	PoolIndex   int64
+	Name        string
+	Status      uint8
+	TVL         float64
+	OperatorFee float64
+	APY         float64
+	ReportedAPY float64
+	Volatility  float64
+	Sharpe      float64
+}
+
+// RankBy selects the metric pools are ordered by
+type RankBy string
+
+const (
+	RankByAPY    RankBy = "apy"
+	RankBySharpe RankBy = "sharpe"
+	RankByTVL    RankBy = "tvl"
+)
+
+// RankOptions filters and orders RankPools results
+type RankOptions struct {
+	By RankBy
+	// Window limits APY and volatility to the most recent daily returns; 0 uses all
+	Window        int
+	MinTVL        float64
+	IncludeFrozen bool
+	Limit         int
+}
+
+// APYFromDailyReturns compounds the mean of the last window daily returns (fractions) over a
+// year. A window of 0 uses every return.
+func APYFromDailyReturns(returns []lighterapi.DailyReturn, window int) float64 {
+	mean, _ := dailyStats(returns, window)
+	return math.Pow(1+mean, daysPerYear) - 1
+}
+
+// dailyStats returns the mean and standard deviation of the most recent daily returns
+func dailyStats(returns []lighterapi.DailyReturn, window int) (mean, std float64) {
+	sorted := append([]lighterapi.DailyReturn(nil), returns...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+	if window > 0 && len(sorted) > window {
+		sorted = sorted[len(sorted)-window:]
+	}
+	if len(sorted) == 0 {
+		return 0, 0
+	}
+	for _, r := range sorted {
+		mean += r.DailyReturn
+	}
+	mean /= float64(len(sorted))
+	for _, r := range sorted {
+		std += (r.DailyReturn - mean) * (r.DailyReturn - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(sorted)))
+}
+
+// Investor values an account's pool shares and mints or burns them in USDC terms
+type Investor struct {
+	api *client.Client
+	tx  *client.TxClient
+}
+
+// NewInvestor creates an investor for the account tx signs for
+func NewInvestor(api *client.Client, tx *client.TxClient) (*Investor, error) {
+	if api == nil || tx == nil {
+		return nil, errors.New("pool: REST client and TxClient are required")
+	}
+	return &Investor{api: api, tx: tx}, nil
+}
+
+// Holdings values every pool share entry of the account (DetailedAccount.Shares)
+func (inv *Investor) Holdings(ctx context.Context) ([]Holding, error) {
+	account, err := inv.api.AccountByIndex(ctx, inv.tx.GetAccountIndex())
+	if err != nil {
+		return nil, err
+	}
+	holdings := make([]Holding, 0, len(account.Shares))
+	for _, share := range account.Shares {
+		pool, err := inv.api.AccountByIndex(ctx, share.PublicPoolIndex)
+		if err != nil {
+			return nil, fmt.Errorf("pool: fetch pool %d: %w", share.PublicPoolIndex, err)
+		}
+		holdings = append(holdings, HoldingOf(share, pool))
+	}
+	return holdings, nil
+}
+
+// HoldingOf values a share entry against its pool account
+func HoldingOf(share lighterapi.PublicPoolShare, pool *lighterapi.DetailedAccount) Holding {
+	h := Holding{
+		PoolIndex:  share.PublicPoolIndex,
+		Name:       pool.Name,
+		Shares:     share.SharesAmount,
+		EntryUsdc:  parseFloat(share.EntryUsdc),
+		SharePrice: snapshotOf(pool, time.Now().UTC()).SharePrice,
+		APY:        APYFromDailyReturns(pool.PoolInfo.DailyReturns, 0),
+	}
+	h.Value = float64(h.Shares) * h.SharePrice
+	h.Pnl = h.Value - h.EntryUsdc
+	if h.EntryUsdc > 0 {
+		h.PnlPercent = h.Pnl / h.EntryUsdc
+	}
+	return h
+}
+
+// RankPools lists public pools and orders them by the chosen metric
+func (inv *Investor) RankPools(ctx context.Context, opts RankOptions) ([]PoolRank, error) {
+	if opts.By == "" {
+		opts.By = RankByAPY
+	}
+	filter := lighterapi.PublicPoolsParamsFilterAll
+
+	var ranks []PoolRank
+	for index := int64(0); ; {
+		resp, err := inv.api.PublicPools(ctx, &lighterapi.PublicPoolsParams{
+			Filter: &filter,
+			Index:  index,
+			Limit:  poolPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range resp.PublicPools {
+			index = max(index, p.Index+1)
+			if !opts.IncludeFrozen && p.PoolInfo.Status == StatusFrozen {
+				continue
+			}
+			tvl := parseFloat(p.TotalAssetValue)
+			if tvl < opts.MinTVL {
+				continue
+			}
+			mean, std := dailyStats(p.PoolInfo.DailyReturns, opts.Window)
+			rank := PoolRank{
+				PoolIndex:   p.Index,
+				Name:        p.Name,
+				Status:      p.PoolInfo.Status,
+				TVL:         tvl,
+				OperatorFee: parsePercent(p.PoolInfo.OperatorFee),
+				APY:         math.Pow(1+mean, daysPerYear) - 1,
+				ReportedAPY: p.PoolInfo.AnnualPercentageYield,
+				Volatility:  std * math.Sqrt(daysPerYear),
+			}
+			if std > 0 {
+				rank.Sharpe = mean / std * math.Sqrt(daysPerYear)
+			}
+			ranks = append(ranks, rank)
+		}
+		if len(resp.PublicPools) < poolPageSize {
+			break
+		}
+	}
+
+	metric := func(r PoolRank) float64 {
+		switch opts.By {
+		case RankBySharpe:
+			return r.Sharpe
+		case RankByTVL:
+			return r.TVL
+		default:
+			return r.APY
+		}
+	}
+	sort.SliceStable(ranks, func(i, j int) bool { return metric(ranks[i]) > metric(ranks[j]) })
+	if opts.Limit > 0 && len(ranks) > opts.Limit {
+		ranks = ranks[:opts.Limit]
+	}
+	return ranks, nil
+}
+
+// Mint buys usdc worth of shares at the current share price and returns the transaction hash
+// and share amount
+func (inv *Investor) Mint(ctx context.Context, poolIndex int64, usdc float64) (string, int64, error) {
+	shares, err := inv.sharesFor(ctx, poolIndex, usdc)
+	if err != nil {
+		return "", 0, err
+	}
+	info, err := inv.tx.GetMintSharesTransaction(&types.MintSharesTxReq{PublicPoolIndex: poolIndex, ShareAmount: shares}, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	txHash, err := inv.tx.SendRawTx(ctx, info, nil)
+	return txHash, shares, err
+}
+
+// Burn redeems usdc worth of shares at the current share price, capped at the shares held
+func (inv *Investor) Burn(ctx context.Context, poolIndex int64, usdc float64) (string, int64, error) {
+	shares, err := inv.sharesFor(ctx, poolIndex, usdc)
+	if err != nil {
+		return "", 0, err
+	}
+	held, err := inv.sharesHeld(ctx, poolIndex)
+	if err != nil {
+		return "", 0, err
+	}
+	return inv.burn(ctx, poolIndex, min(shares, held))
+}
+
+// BurnAll redeems every share the account holds in a pool
+func (inv *Investor) BurnAll(ctx context.Context, poolIndex int64) (string, int64, error) {
+	held, err := inv.sharesHeld(ctx, poolIndex)
+	if err != nil {
+		return "", 0, err
+	}
+	return inv.burn(ctx, poolIndex, held)
+}
+
+func (inv *Investor) burn(ctx context.Context, poolIndex, shares int64) (string, int64, error) {
+	if shares < txtypes.MinPoolSharesToMintOrBurn {
+		return "", 0, fmt.Errorf("pool: no shares to burn in pool %d", poolIndex)
+	}
+	info, err := inv.tx.GetBurnSharesTransaction(&types.BurnSharesTxReq{PublicPoolIndex: poolIndex, ShareAmount: shares}, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	txHash, err := inv.tx.SendRawTx(ctx, info, nil)
+	return txHash, shares, err
+}
+
+func (inv *Investor) sharesFor(ctx context.Context, poolIndex int64, usdc float64) (int64, error) {
+	if usdc <= 0 {
+		return 0, errors.New("pool: amount must be positive")
+	}
+	pool, err := inv.api.AccountByIndex(ctx, poolIndex)
+	if err != nil {
+		return 0, err
+	}
+	price := snapshotOf(pool, time.Now().UTC()).SharePrice
+	if price <= 0 {
+		return 0, fmt.Errorf("pool: no share price for pool %d", poolIndex)
+	}
+	shares := int64(math.Floor(usdc / price))
+	if shares < txtypes.MinPoolSharesToMintOrBurn || shares > txtypes.MaxPoolSharesToMintOrBurn {
+		return 0, fmt.Errorf("pool: %s USDC is outside the mintable share range at price %g", strconv.FormatFloat(usdc, 'f', -1, 64), price)
+	}
+	return shares, nil
+}
+
+func (inv *Investor) sharesHeld(ctx context.Context, poolIndex int64) (int64, error) {
+	account, err := inv.api.AccountByIndex(ctx, inv.tx.GetAccountIndex())
+	if err != nil {
+		return 0, err
+	}
+	for _, share := range account.Shares {
+		if share.PublicPoolIndex == poolIndex {
+			return share.SharesAmount, nil
+		}
+	}
+	return 0, nil
+}
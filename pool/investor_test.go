@@ -0,0 +1,167 @@
+package pool_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/lightertest"
+	"github.com/defi-maker/golighter/pool"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func returns(daily ...float64) []lighterapi.DailyReturn {
+	out := make([]lighterapi.DailyReturn, len(daily))
+	for i, r := range daily {
+		out[i] = lighterapi.DailyReturn{Timestamp: int64(i) * 86400, DailyReturn: r}
+	}
+	return out
+}
+
+// newInvestor serves a pool worth 2400 USDC over 2,000,000 shares, a share price of 0.0012,
+// and a trader holding 1000 of them bought for 1 USDC
+func newInvestor(t *testing.T) (*pool.Investor, *[]sentTx) {
+	t.Helper()
+	srv, api, tx := lightertest.NewExchange(t)
+	serveAccounts(srv,
+		lighterapi.DetailedAccount{Index: lightertest.TraderAccount, Shares: []lighterapi.PublicPoolShare{
+			{PublicPoolIndex: poolIndex, SharesAmount: 1000, EntryUsdc: "1.00"},
+		}},
+		lighterapi.DetailedAccount{Index: poolIndex, Name: "basis", TotalAssetValue: "2400", PoolInfo: lighterapi.PublicPoolInfo{
+			TotalShares:    2_000_000,
+			OperatorShares: 400_000,
+			DailyReturns:   returns(0.001, 0.001),
+		}},
+	)
+	inv, err := pool.NewInvestor(api, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inv, captureTxs(t, srv)
+}
+
+func TestInvestorHoldings(t *testing.T) {
+	inv, _ := newInvestor(t)
+	holdings, err := inv.Holdings(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holdings) != 1 {
+		t.Fatalf("%d holdings, want 1", len(holdings))
+	}
+	h := holdings[0]
+	if h.PoolIndex != poolIndex || h.Name != "basis" || !near(h.SharePrice, 0.0012) || !near(h.Value, 1.2) || !near(h.Pnl, 0.2) || !near(h.PnlPercent, 0.2) {
+		t.Fatalf("holding %+v, want 1000 shares worth 1.2 for a 0.2 gain", h)
+	}
+	if want := math.Pow(1.001, 365) - 1; !near(h.APY, want) {
+		t.Fatalf("APY %v, want %v", h.APY, want)
+	}
+}
+
+func TestInvestorMintsAndBurnsShares(t *testing.T) {
+	inv, txs := newInvestor(t)
+	ctx := context.Background()
+
+	if _, shares, err := inv.Mint(ctx, poolIndex, 12); err != nil || shares != 10_000 {
+		t.Fatalf("minted %d shares (%v), want 10000 for 12 USDC", shares, err)
+	}
+	// Burning more than is held burns the holding
+	if _, shares, err := inv.Burn(ctx, poolIndex, 120); err != nil || shares != 1000 {
+		t.Fatalf("burned %d shares (%v), want the 1000 held", shares, err)
+	}
+	if _, shares, err := inv.BurnAll(ctx, poolIndex); err != nil || shares != 1000 {
+		t.Fatalf("burned %d shares (%v), want the 1000 held", shares, err)
+	}
+
+	want := []struct {
+		typ    int
+		shares int64
+	}{{txtypes.TxTypeL2MintShares, 10_000}, {txtypes.TxTypeL2BurnShares, 1000}, {txtypes.TxTypeL2BurnShares, 1000}}
+	if len(*txs) != len(want) {
+		t.Fatalf("%d transactions sent, want %d", len(*txs), len(want))
+	}
+	for i, w := range want {
+		var info struct {
+			PublicPoolIndex int64
+			ShareAmount     int64
+		}
+		if err := json.Unmarshal([]byte((*txs)[i].Info), &info); err != nil {
+			t.Fatal(err)
+		}
+		if (*txs)[i].Type != w.typ || info.PublicPoolIndex != poolIndex || info.ShareAmount != w.shares {
+			t.Fatalf("transaction %d is type %d for %+v, want type %d for %d shares", i, (*txs)[i].Type, info, w.typ, w.shares)
+		}
+	}
+
+	if _, _, err := inv.Mint(ctx, poolIndex, 0); err == nil {
+		t.Fatal("expected a zero amount to be refused")
+	}
+	if _, _, err := inv.Mint(ctx, poolIndex, 0.0001); err == nil {
+		t.Fatal("expected an amount below one share to be refused")
+	}
+	if _, _, err := inv.BurnAll(ctx, 99); err == nil {
+		t.Fatal("expected burning in a pool without shares to be refused")
+	}
+	if len(*txs) != len(want) {
+		t.Fatal("a refused request was sent")
+	}
+}
+
+func TestInvestorRanksPools(t *testing.T) {
+	srv, api, tx := lightertest.NewExchange(t)
+	srv.HandleJSON("/api/v1/publicPools", lighterapi.PublicPools{Code: 200, PublicPools: []lighterapi.PublicPool{
+		{Index: 10, Name: "steady", TotalAssetValue: "5000", PoolInfo: lighterapi.PublicPoolInfo{DailyReturns: returns(0.001, 0.001, 0.001)}},
+		{Index: 11, Name: "volatile", TotalAssetValue: "1000", PoolInfo: lighterapi.PublicPoolInfo{DailyReturns: returns(0.004, 0.003, -0.001)}},
+		{Index: 12, Name: "frozen", TotalAssetValue: "9000", PoolInfo: lighterapi.PublicPoolInfo{Status: pool.StatusFrozen, DailyReturns: returns(0.01)}},
+	}})
+	inv, err := pool.NewInvestor(api, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		opts pool.RankOptions
+		want []int64
+	}{
+		{pool.RankOptions{}, []int64{11, 10}},
+		{pool.RankOptions{By: pool.RankByTVL, IncludeFrozen: true}, []int64{12, 10, 11}},
+		{pool.RankOptions{By: pool.RankBySharpe, Limit: 1}, []int64{11}},
+		{pool.RankOptions{MinTVL: 2000}, []int64{10}},
+		// Over the last day the steady pool beats the volatile one
+		{pool.RankOptions{Window: 1}, []int64{10, 11}},
+	} {
+		ranks, err := inv.RankPools(context.Background(), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int64
+		for _, r := range ranks {
+			got = append(got, r.PoolIndex)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%+v ranked %v, want %v", tt.opts, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%+v ranked %v, want %v", tt.opts, got, tt.want)
+			}
+		}
+	}
+
+	ranks, err := inv.RankPools(context.Background(), pool.RankOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := ranks[1]; r.Volatility != 0 || r.Sharpe != 0 || !near(r.APY, math.Pow(1.001, 365)-1) {
+		t.Fatalf("steady pool %+v, want no volatility and a 1 bp daily APY", r)
+	}
+	if r := ranks[0]; r.Volatility <= 0 || r.Sharpe <= 0 {
+		t.Fatalf("volatile pool %+v, want positive volatility and Sharpe", r)
+	}
+}
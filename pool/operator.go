@@ -0,0 +1,348 @@
+// Package pool provides operator and investor tooling for Lighter public pools.
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// Pool statuses accepted by UpdatePublicPool
+const (
+	StatusActive uint8 = 0
+	StatusFrozen uint8 = 1
+)
+
+const (
+	defaultWarnMargin    = 0.1
+	createConfirmTimeout = 30 * time.Second
+	createConfirmPoll    = time.Second
+)
+
+// SharesForUSDC converts a USDC amount into shares at the initial share value of 0.001 USDC
+func SharesForUSDC(usdc float64) int64 {
+	return int64(math.Round(usdc * txtypes.OneUSDC / float64(txtypes.InitialPoolShareValue)))
+}
+
+// FeeToTicks converts a fee fraction (0.1 = 10%) into FeeTick units
+func FeeToTicks(fee float64) int64 {
+	return int64(math.Round(fee * float64(txtypes.FeeTick)))
+}
+
+// ShareRateToTicks converts a share rate fraction (0.05 = 5%) into ShareTick units
+func ShareRateToTicks(rate float64) int64 {
+	return int64(math.Round(rate * float64(txtypes.ShareTick)))
+}
+
+// CreateParams describes a new public pool in human units
+type CreateParams struct {
+	// OperatorFee is the fraction of profits kept by the operator (0.1 = 10%)
+	OperatorFee float64
+	// InitialDeposit is the USDC the operator seeds the pool with
+	InitialDeposit float64
+	// MinOperatorShareRate is the smallest fraction of shares the operator must hold
+	MinOperatorShareRate float64
+}
+
+// UpdateParams changes pool parameters. The transaction carries every parameter, so the
+// operator fee and minimum share rate are always set explicitly rather than rebuilt from the
+// values the API reports; a nil Status keeps the current one.
+type UpdateParams struct {
+	OperatorFee          *float64
+	MinOperatorShareRate *float64
+	Status               *uint8
+}
+
+// Snapshot is the state of a pool at one point in time
+type Snapshot struct {
+	Time                 time.Time
+	Status               uint8
+	TotalShares          int64
+	OperatorShares       int64
+	OperatorShareRatio   float64
+	MinOperatorShareRate float64
+	OperatorFee          float64
+	SharePrice           float64
+	NAV                  float64
+}
+
+// ShareRatioWarning is emitted when the operator's share ratio comes within the warning
+// margin of the pool minimum
+type ShareRatioWarning struct {
+	PoolIndex int64
+	Ratio     float64
+	Minimum   float64
+	Time      time.Time
+}
+
+func (w ShareRatioWarning) String() string {
+	return fmt.Sprintf("pool %d operator share ratio %.4f is near the minimum %.4f", w.PoolIndex, w.Ratio, w.Minimum)
+}
+
+// PricePoint is a share price observation from SharePrices
+type PricePoint struct {
+	Time  time.Time
+	Price float64
+}
+
+// Operator runs a public pool: it updates parameters, tracks shares and NAV, and warns when
+// the operator share ratio approaches its minimum
+type Operator struct {
+	api       *client.Client
+	tx        *client.TxClient
+	poolIndex int64
+
+	mu         sync.Mutex
+	warnMargin float64
+	onWarning  func(ShareRatioWarning)
+	warning    bool
+	history    []Snapshot
+}
+
+// CreatePool submits a CreatePublicPool transaction from the operator account and waits until
+// the new pool account is listed for the operator's L1 address
+func CreatePool(ctx context.Context, api *client.Client, tx *client.TxClient, params CreateParams) (*Operator, error) {
+	if api == nil || tx == nil {
+		return nil, errors.New("pool: REST client and operator TxClient are required")
+	}
+	req := &types.CreatePublicPoolTxReq{
+		OperatorFee:          FeeToTicks(params.OperatorFee),
+		InitialTotalShares:   SharesForUSDC(params.InitialDeposit),
+		MinOperatorShareRate: ShareRateToTicks(params.MinOperatorShareRate),
+	}
+	if req.InitialTotalShares < txtypes.MinInitialTotalShares || req.InitialTotalShares > txtypes.MaxInitialTotalShares {
+		return nil, fmt.Errorf("pool: initial deposit %s USDC outside the allowed range", strconv.FormatFloat(params.InitialDeposit, 'f', -1, 64))
+	}
+
+	operator, err := api.AccountByIndex(ctx, tx.GetAccountIndex())
+	if err != nil {
+		return nil, err
+	}
+	existing, err := api.AccountsByL1Address(ctx, &lighterapi.AccountsByL1AddressParams{L1Address: operator.L1Address})
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]bool, len(existing.SubAccounts))
+	for _, acc := range existing.SubAccounts {
+		known[acc.Index] = true
+	}
+
+	info, err := tx.GetCreatePublicPoolTransaction(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := tx.SendRawTx(ctx, info, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, createConfirmTimeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(createConfirmPoll)
+	defer ticker.Stop()
+	for {
+		accounts, err := api.AccountsByL1Address(ctx, &lighterapi.AccountsByL1AddressParams{L1Address: operator.L1Address})
+		if err == nil {
+			for _, acc := range accounts.SubAccounts {
+				if !known[acc.Index] {
+					return NewOperator(api, tx, acc.Index)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("pool: confirm pool creation %s: %w", txHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// NewOperator manages an existing pool; tx must sign for the pool's operator account
+func NewOperator(api *client.Client, tx *client.TxClient, poolIndex int64) (*Operator, error) {
+	if api == nil || tx == nil {
+		return nil, errors.New("pool: REST client and operator TxClient are required")
+	}
+	return &Operator{api: api, tx: tx, poolIndex: poolIndex, warnMargin: defaultWarnMargin}, nil
+}
+
+// PoolIndex returns the account index of the pool
+func (o *Operator) PoolIndex() int64 { return o.poolIndex }
+
+// SetWarnMargin sets how close to the minimum share rate the ratio may get before warning.
+// A margin of 0.1 warns below 110% of the minimum.
+func (o *Operator) SetWarnMargin(margin float64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.warnMargin = margin
+}
+
+// OnWarning registers the share ratio warning callback
+func (o *Operator) OnWarning(fn func(ShareRatioWarning)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onWarning = fn
+}
+
+// Info fetches the pool account
+func (o *Operator) Info(ctx context.Context) (*lighterapi.DetailedAccount, error) {
+	return o.api.AccountByIndex(ctx, o.poolIndex)
+}
+
+// Update sets the operator fee and minimum operator share rate of the pool, and its status
+// when params.Status is set
+func (o *Operator) Update(ctx context.Context, params UpdateParams) (string, error) {
+	if params.OperatorFee == nil || params.MinOperatorShareRate == nil {
+		return "", errors.New("pool: update needs both the operator fee and the minimum operator share rate")
+	}
+	req := &types.UpdatePublicPoolTxReq{
+		PublicPoolIndex:      o.poolIndex,
+		OperatorFee:          FeeToTicks(*params.OperatorFee),
+		MinOperatorShareRate: ShareRateToTicks(*params.MinOperatorShareRate),
+	}
+	if params.Status != nil {
+		req.Status = *params.Status
+	} else {
+		pool, err := o.Info(ctx)
+		if err != nil {
+			return "", err
+		}
+		req.Status = pool.PoolInfo.Status
+	}
+
+	info, err := o.tx.GetUpdatePublicPoolTransaction(req, nil)
+	if err != nil {
+		return "", err
+	}
+	return o.tx.SendRawTx(ctx, info, nil)
+}
+
+// Snapshot fetches the pool, records a snapshot and emits a warning when the operator share
+// ratio enters the warning margin
+func (o *Operator) Snapshot(ctx context.Context) (Snapshot, error) {
+	pool, err := o.Info(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snap := snapshotOf(pool, time.Now().UTC())
+
+	o.mu.Lock()
+	o.history = append(o.history, snap)
+	var warning *ShareRatioWarning
+	near := snap.MinOperatorShareRate > 0 && snap.OperatorShareRatio < snap.MinOperatorShareRate*(1+o.warnMargin)
+	if near && !o.warning {
+		warning = &ShareRatioWarning{
+			PoolIndex: o.poolIndex,
+			Ratio:     snap.OperatorShareRatio,
+			Minimum:   snap.MinOperatorShareRate,
+			Time:      snap.Time,
+		}
+	}
+	o.warning = near
+	onWarning := o.onWarning
+	o.mu.Unlock()
+
+	if warning != nil && onWarning != nil {
+		onWarning(*warning)
+	}
+	return snap, nil
+}
+
+// History returns the snapshots recorded so far
+func (o *Operator) History() []Snapshot {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Snapshot(nil), o.history...)
+}
+
+// SharePrices returns the pool's share price series in time order
+func (o *Operator) SharePrices(ctx context.Context) ([]PricePoint, error) {
+	pool, err := o.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sharePriceSeries(pool.PoolInfo.SharePrices), nil
+}
+
+// NAVSeries values the current share count at every share price, approximating NAV over time
+// for periods where the share count did not change
+func (o *Operator) NAVSeries(ctx context.Context) ([]PricePoint, error) {
+	pool, err := o.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	series := sharePriceSeries(pool.PoolInfo.SharePrices)
+	for i := range series {
+		series[i].Price *= float64(pool.PoolInfo.TotalShares)
+	}
+	return series, nil
+}
+
+// Run records a snapshot every interval until ctx is done. Errors are passed to onError when set.
+func (o *Operator) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := o.Snapshot(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func snapshotOf(pool *lighterapi.DetailedAccount, at time.Time) Snapshot {
+	info := pool.PoolInfo
+	snap := Snapshot{
+		Time:                 at,
+		Status:               info.Status,
+		TotalShares:          info.TotalShares,
+		OperatorShares:       info.OperatorShares,
+		MinOperatorShareRate: parsePercent(info.MinOperatorShareRate),
+		OperatorFee:          parsePercent(info.OperatorFee),
+		NAV:                  parseFloat(pool.TotalAssetValue),
+	}
+	if info.TotalShares > 0 {
+		snap.OperatorShareRatio = float64(info.OperatorShares) / float64(info.TotalShares)
+		snap.SharePrice = snap.NAV / float64(info.TotalShares)
+	}
+	if prices := sharePriceSeries(info.SharePrices); snap.SharePrice == 0 && len(prices) > 0 {
+		snap.SharePrice = prices[len(prices)-1].Price
+	}
+	return snap
+}
+
+func sharePriceSeries(prices []lighterapi.SharePrice) []PricePoint {
+	series := make([]PricePoint, 0, len(prices))
+	for _, p := range prices {
+		series = append(series, PricePoint{Time: time.UnixMilli(client.UnixMillis(p.Timestamp)).UTC(), Price: p.SharePrice})
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Time.Before(series[j].Time) })
+	return series
+}
+
+// parsePercent converts the percent strings reported for pool parameters ("10.00") to fractions
+func parsePercent(value string) float64 {
+	return parseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%")) / 100
+}
+
+func parseFloat(value string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return f
+}
@@ -0,0 +1,98 @@
+package pool_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/lightertest"
+	"github.com/defi-maker/golighter/pool"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const poolIndex int64 = 50
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// serveAccounts answers account lookups by index from accounts, in place of the fake,
+// which does not model pools
+func serveAccounts(srv *lightertest.Server, accounts ...lighterapi.DetailedAccount) {
+	srv.Handle("/api/v1/account", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		index, _ := strconv.ParseInt(r.URL.Query().Get("value"), 10, 64)
+		for _, acc := range accounts {
+			if acc.Index == index {
+				writeJSON(w, lighterapi.DetailedAccounts{Code: 200, Total: 1, Accounts: []lighterapi.DetailedAccount{acc}})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, lighterapi.ResultCode{Code: lightertest.CodeNotFound})
+	}))
+}
+
+// sentTx is a transaction received by captureTxs
+type sentTx struct {
+	Type int
+	Info string
+}
+
+// captureTxs answers sendTx in place of the fake, which accepts pool transactions without
+// effect, and returns the transactions it received
+func captureTxs(t *testing.T, srv *lightertest.Server) *[]sentTx {
+	var txs []sentTx
+	srv.Handle("/api/v1/sendTx", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		txType, err := strconv.Atoi(r.FormValue("tx_type"))
+		if err != nil {
+			t.Error(err)
+		}
+		txs = append(txs, sentTx{Type: txType, Info: r.FormValue("tx_info")})
+		writeJSON(w, lighterapi.RespSendTx{Code: 200, TxHash: "0xpool"})
+	}))
+	return &txs
+}
+
+func TestOperatorUpdateSendsExplicitParameters(t *testing.T) {
+	srv, api, tx := lightertest.NewExchange(t)
+	// The reported fee and rate are in units Update must not rebuild from
+	serveAccounts(srv, lighterapi.DetailedAccount{Index: poolIndex, PoolInfo: lighterapi.PublicPoolInfo{Status: pool.StatusFrozen, OperatorFee: "100", MinOperatorShareRate: "200"}})
+	txs := captureTxs(t, srv)
+	op, err := pool.NewOperator(api, tx, poolIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	fee, rate := 0.1, 0.05
+
+	if _, err := op.Update(ctx, pool.UpdateParams{OperatorFee: &fee}); err == nil {
+		t.Fatal("expected an update without the minimum share rate to be refused")
+	}
+	if len(*txs) != 0 {
+		t.Fatal("a refused update was sent")
+	}
+
+	if _, err := op.Update(ctx, pool.UpdateParams{OperatorFee: &fee, MinOperatorShareRate: &rate}); err != nil {
+		t.Fatal(err)
+	}
+	active := pool.StatusActive
+	if _, err := op.Update(ctx, pool.UpdateParams{OperatorFee: &fee, MinOperatorShareRate: &rate, Status: &active}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*txs) != 2 {
+		t.Fatalf("%d updates sent, want 2", len(*txs))
+	}
+	for i, want := range []uint8{pool.StatusFrozen, pool.StatusActive} {
+		var u txtypes.L2UpdatePublicPoolTxInfo
+		if err := json.Unmarshal([]byte((*txs)[i].Info), &u); err != nil || (*txs)[i].Type != txtypes.TxTypeL2UpdatePublicPool {
+			t.Fatalf("update %d is %+v (%v)", i, (*txs)[i], err)
+		}
+		if u.PublicPoolIndex != poolIndex || u.OperatorFee != 100_000 || u.MinOperatorShareRate != 500 || u.Status != want {
+			t.Fatalf("update %d %+v, want a 10%% fee, a 5%% share rate and status %d", i, u, want)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/defi-maker/golighter/client"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const (
+	defaultQueueSize       = 4096
+	defaultShutdownTimeout = 10 * time.Second
+	// wsAuthTTL stays under the 7 hour limit of auth tokens
+	wsAuthTTL = 6 * time.Hour
+)
+
+// Config describes the connections of a Runtime and how it drives its strategies
+type Config struct {
+	BaseURL       string
+	ClientOptions []client.Option
+	// WS defaults to client.DefaultWSConfig
+	WS           *client.WSConfig
+	PrivateKey   string
+	AccountIndex int64
+	ApiKeyIndex  uint8
+	ChainID      uint32
+	// Markets are subscribed for order book and trade events
+	Markets []uint8
+	// Paper trades through a client.PaperTxClient against the live book instead of sending
+	// orders; PrivateKey is not needed
+	Paper           bool
+	PaperCollateral float64
+	// TimerInterval is the period of OnTimer; 0 disables timers
+	TimerInterval time.Duration
+	// QueueSize is the backlog of events waiting for one strategy above which it is reported
+	// to OnError as falling behind; defaults to 4096. Events are never dropped.
+	QueueSize int
+	// ShutdownTimeout bounds the cancel-all sent on shutdown; defaults to 10s
+	ShutdownTimeout time.Duration
+	// OnError receives connection errors and errors returned by strategy hooks
+	OnError func(error)
+}
+
+// Services are the connections a Runtime drives. NewRuntime builds them from Config;
+// NewRuntimeWith accepts prepared ones, for example fakes from lightertest.
+type Services struct {
+	Orders  client.OrderClient
+	Public  client.LighterWebsocketPublicServiceI
+	Private client.LighterWebsocketPrivateServiceI
+	// Paper, when set, receives market data before strategies so resting paper orders fill
+	Paper *client.PaperTxClient
+}
+
+// Runtime connects strategies to the exchange. Each strategy gets its own goroutine and
+// Context, so its hooks run one at a time in the order events arrived.
+type Runtime struct {
+	cfg      Config
+	services Services
+	book     *client.OrderBookCache
+
+	mu      sync.Mutex
+	workers []*worker
+	unsubs  []func() error
+	running bool
+}
+
+// NewRuntime creates the REST client, the live or paper order client and the WS services
+func NewRuntime(cfg Config) (*Runtime, error) {
+	api, err := client.New(cfg.BaseURL, cfg.ClientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	wsConfig := cfg.WS
+	if wsConfig == nil {
+		wsConfig = client.DefaultWSConfig()
+	}
+	book := client.NewOrderBookCache()
+	services := Services{Public: client.NewLighterWebsocketPublicService(wsConfig)}
+
+	if cfg.Paper {
+		paper, err := client.NewPaperTxClient(api, book, cfg.AccountIndex, client.WithPaperCollateral(cfg.PaperCollateral))
+		if err != nil {
+			return nil, err
+		}
+		services.Orders, services.Private, services.Paper = paper, paper, paper
+	} else {
+		tx, err := client.NewTxClient(api, cfg.PrivateKey, cfg.AccountIndex, cfg.ApiKeyIndex, cfg.ChainID)
+		if err != nil {
+			return nil, err
+		}
+		services.Orders = tx
+		services.Private = client.NewLighterWebsocketPrivateService(wsConfig, func() string {
+			token, err := tx.GetAuthToken(time.Now().Add(wsAuthTTL))
+			if err != nil {
+				return ""
+			}
+			return token
+		})
+	}
+	return newRuntime(cfg, services, book), nil
+}
+
+// NewRuntimeWith drives prepared services. A Paper client must share book with the runtime.
+func NewRuntimeWith(cfg Config, services Services, book *client.OrderBookCache) (*Runtime, error) {
+	if services.Orders == nil || services.Public == nil || services.Private == nil {
+		return nil, errors.New("strategy: orders, public and private services are required")
+	}
+	if book == nil {
+		book = client.NewOrderBookCache()
+	}
+	return newRuntime(cfg, services, book), nil
+}
+
+func newRuntime(cfg Config, services Services, book *client.OrderBookCache) *Runtime {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+	return &Runtime{cfg: cfg, services: services, book: book}
+}
+
+// Orders returns the order client strategies trade through
+func (r *Runtime) Orders() client.OrderClient { return r.services.Orders }
+
+// Book returns the order books maintained from the subscribed markets
+func (r *Runtime) Book() *client.OrderBookCache { return r.book }
+
+// Add registers a strategy; it must be called before Run
+func (r *Runtime) Add(s Strategy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return errors.New("strategy: runtime is already running")
+	}
+	r.workers = append(r.workers, &worker{strategy: s, wake: make(chan struct{}, 1)})
+	return nil
+}
+
+// Run connects, subscribes and delivers events until ctx is canceled. It then unsubscribes,
+// stops the strategies, cancels every open order of the account and closes the connections.
+func (r *Runtime) Run(ctx context.Context) error {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return errors.New("strategy: runtime is already running")
+	}
+	if len(r.workers) == 0 {
+		r.mu.Unlock()
+		return errors.New("strategy: no strategies added")
+	}
+	r.running = true
+	workers := r.workers
+	r.mu.Unlock()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		w.ctx = NewContext(runCtx, r.services.Orders, r.book, nil)
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			w.run(runCtx, r.cfg.TimerInterval, r.report)
+		}(w)
+	}
+
+	err := r.connect(runCtx)
+	if err == nil {
+		<-runCtx.Done()
+	}
+	cancel()
+	wg.Wait()
+	return errors.Join(err, r.shutdown())
+}
+
+func (r *Runtime) connect(ctx context.Context) error {
+	if err := r.services.Public.Start(ctx, r.report); err != nil {
+		return fmt.Errorf("strategy: start public stream: %w", err)
+	}
+	if err := r.services.Private.Start(ctx, r.report); err != nil {
+		return fmt.Errorf("strategy: start private stream: %w", err)
+	}
+
+	for _, marketId := range r.cfg.Markets {
+		unsub, err := r.services.Public.SubscribeOrderBook(client.LighterOrderBookParamKey{MarketId: marketId}, func(resp client.LighterOrderBookResponse) error {
+			if err := r.applyBook(resp); err != nil {
+				return err
+			}
+			r.broadcast(func(c *Context, s Strategy) error { return s.OnBook(c, resp) })
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("strategy: subscribe order book %d: %w", marketId, err)
+		}
+		r.track(unsub)
+
+		unsub, err = r.services.Public.SubscribeTrades(client.LighterTradesParamKey{MarketId: marketId}, func(resp client.LighterTradesResponse) error {
+			if r.services.Paper != nil {
+				if err := r.services.Paper.HandleTrades(resp); err != nil {
+					return err
+				}
+			}
+			r.broadcast(func(c *Context, s Strategy) error { return s.OnTrade(c, resp) })
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("strategy: subscribe trades %d: %w", marketId, err)
+		}
+		r.track(unsub)
+	}
+
+	account := r.services.Orders.GetAccountIndex()
+	unsub, err := r.services.Private.SubscribeAccount(client.LighterAccountParamKey{AccountId: account}, func(resp client.LighterAccountResponse) error {
+		r.broadcast(func(c *Context, s Strategy) error {
+			c.TrackAccount(resp)
+			return s.OnAccount(c, resp)
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("strategy: subscribe account %d: %w", account, err)
+	}
+	r.track(unsub)
+
+	unsub, err = r.services.Private.SubscribeOrders(client.LighterOrdersParamKey{AccountId: account}, func(resp client.LighterOrdersResponse) error {
+		r.broadcast(func(c *Context, s Strategy) error {
+			c.TrackOrder(resp)
+			return s.OnOrderUpdate(c, resp)
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("strategy: subscribe orders %d: %w", account, err)
+	}
+	r.track(unsub)
+	return nil
+}
+
+func (r *Runtime) applyBook(resp client.LighterOrderBookResponse) error {
+	if r.services.Paper != nil {
+		return r.services.Paper.HandleOrderBook(resp)
+	}
+	return r.book.Handle(resp)
+}
+
+func (r *Runtime) track(unsub func() error) {
+	if unsub == nil {
+		return
+	}
+	r.mu.Lock()
+	r.unsubs = append(r.unsubs, unsub)
+	r.mu.Unlock()
+}
+
+// broadcast queues an event for every strategy. It never blocks: paper order updates are
+// raised inside the hook that placed the order, on the worker that must consume them.
+func (r *Runtime) broadcast(fn func(*Context, Strategy) error) {
+	r.mu.Lock()
+	workers := r.workers
+	r.mu.Unlock()
+	for _, w := range workers {
+		s := w.strategy
+		if n := w.push(func(c *Context) { r.report(fn(c, s)) }); n == r.cfg.QueueSize+1 {
+			r.report(fmt.Errorf("strategy: %T is falling behind with %d queued events", s, n))
+		}
+	}
+}
+
+// shutdown unsubscribes, cancels every open order of the account and closes the streams
+func (r *Runtime) shutdown() error {
+	r.mu.Lock()
+	unsubs := r.unsubs
+	r.unsubs = nil
+	r.running = false
+	r.mu.Unlock()
+	for _, unsub := range unsubs {
+		unsub()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.ShutdownTimeout)
+	defer cancel()
+	var errs []error
+	tx, err := r.services.Orders.GetCancelAllOrdersTransaction(&types.CancelAllOrdersTxReq{TimeInForce: txtypes.ImmediateCancelAll}, nil)
+	if err == nil {
+		_, err = r.services.Orders.Send(ctx, tx, nil)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("strategy: cancel all on shutdown: %w", err))
+	}
+	if err := r.services.Private.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := r.services.Public.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Runtime) report(err error) {
+	if err != nil && r.cfg.OnError != nil {
+		r.cfg.OnError(err)
+	}
+}
+
+// worker runs the hooks of one strategy on a single goroutine
+type worker struct {
+	strategy Strategy
+	ctx      *Context
+	wake     chan struct{}
+
+	mu    sync.Mutex
+	queue []func(*Context)
+}
+
+// push queues an event and returns the backlog
+func (w *worker) push(fn func(*Context)) int {
+	w.mu.Lock()
+	w.queue = append(w.queue, fn)
+	n := len(w.queue)
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return n
+}
+
+func (w *worker) run(ctx context.Context, timerInterval time.Duration, report func(error)) {
+	var tick <-chan time.Time
+	if timerInterval > 0 {
+		ticker := time.NewTicker(timerInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+			// Events queued while this batch runs wake the loop again, so timers still get
+			// their turn between batches
+			w.mu.Lock()
+			batch := w.queue
+			w.queue = nil
+			w.mu.Unlock()
+			for _, fn := range batch {
+				if ctx.Err() != nil {
+					return
+				}
+				fn(w.ctx)
+			}
+		case now := <-tick:
+			report(w.strategy.OnTimer(w.ctx, now))
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package strategy_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/defi-maker/golighter/lightertest"
+	"github.com/defi-maker/golighter/strategy"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+const testAccount = lightertest.TraderAccount
+
+var testLevels = struct{ bids, asks []client.WSPriceLevel }{
+	bids: []client.WSPriceLevel{{Price: "3000.00", Size: "1.0000"}},
+	asks: []client.WSPriceLevel{{Price: "3001.00", Size: "1.0000"}},
+}
+
+// recorder places one resting buy on the first book and records order updates together
+// with the open orders the context saw at that time
+type recorder struct {
+	strategy.Base
+
+	orders int
+
+	mu      sync.Mutex
+	placed  bool
+	updates []client.LighterOrdersResponse
+	open    []int
+	err     error
+	seen    chan struct{}
+}
+
+func newRecorder(orders int) *recorder {
+	return &recorder{orders: orders, seen: make(chan struct{}, 1024)}
+}
+
+func (r *recorder) OnBook(c *strategy.Context, _ client.LighterOrderBookResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.placed {
+		return nil
+	}
+	r.placed = true
+	for i := 0; i < r.orders; i++ {
+		_, err := c.Place(types.CreateOrderTxReq{
+			MarketIndex:      0,
+			ClientOrderIndex: int64(100 + i),
+			BaseAmount:       100,
+			Price:            uint32(290000 - i),
+			Type:             txtypes.LimitOrder,
+			TimeInForce:      txtypes.GoodTillTime,
+		})
+		if err != nil {
+			r.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *recorder) OnOrderUpdate(c *strategy.Context, o client.LighterOrdersResponse) error {
+	r.mu.Lock()
+	r.updates = append(r.updates, o)
+	r.open = append(r.open, len(c.OpenOrders(0)))
+	r.mu.Unlock()
+	r.seen <- struct{}{}
+	return nil
+}
+
+func (r *recorder) wait(t *testing.T, n int) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.seen:
+		case <-timeout:
+			t.Fatalf("saw %d of %d order updates", i, n)
+		}
+	}
+}
+
+// newLiveFakes returns a fake exchange with a signer for testAccount and a stream server
+// publishing the test book
+func newLiveFakes(t *testing.T) (*lightertest.Server, *client.TxClient, *lightertest.WSServer) {
+	t.Helper()
+	srv, _, tx := lightertest.NewExchange(t)
+	ws := lightertest.NewWSServer()
+	t.Cleanup(ws.Close)
+	ws.SetOrderBook(0, testLevels.bids, testLevels.asks)
+	return srv, tx, ws
+}
+
+func runInBackground(t *testing.T, rt *strategy.Runtime) (context.CancelFunc, <-chan error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- rt.Run(ctx) }()
+	t.Cleanup(cancel)
+	return cancel, done
+}
+
+func TestRuntimeLiveOrderUpdates(t *testing.T) {
+	srv, tx, ws := newLiveFakes(t)
+	rt, err := strategy.NewRuntimeWith(strategy.Config{Markets: []uint8{0}}, strategy.Services{
+		Orders:  tx,
+		Public:  client.NewLighterWebsocketPublicService(ws.Config()),
+		Private: client.NewLighterWebsocketPrivateService(ws.Config(), nil),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := newRecorder(1)
+	if err := rt.Add(rec); err != nil {
+		t.Fatal(err)
+	}
+	cancel, done := runInBackground(t, rt)
+
+	ctx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if err := ws.WaitForSubscription(ctx, "account_all_orders/1"); err != nil {
+		t.Fatal(err)
+	}
+	var orders []lighterapi.Order
+	for len(orders) == 0 {
+		if ctx.Err() != nil {
+			t.Fatal("order was never placed")
+		}
+		time.Sleep(10 * time.Millisecond)
+		orders = srv.Orders(testAccount)
+	}
+	ws.PublishOrders(testAccount, orders)
+	rec.wait(t, 1)
+
+	rec.mu.Lock()
+	got, open := rec.updates[0], rec.open[0]
+	rec.mu.Unlock()
+	if got.ClientOrderIndex != 100 || got.Status != string(lighterapi.OrderStatusOpen) || got.OrderId != orders[0].OrderId {
+		t.Fatalf("unexpected order update %+v", got)
+	}
+	if open != 1 {
+		t.Fatalf("context saw %d open orders, want 1", open)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if left := srv.Orders(testAccount); len(left) != 0 {
+		t.Fatalf("%d orders left after shutdown", len(left))
+	}
+}
+
+// refusingOrders is a private service whose order subscription fails
+type refusingOrders struct {
+	client.LighterWebsocketPrivateServiceI
+}
+
+func (refusingOrders) SubscribeOrders(client.LighterOrdersParamKey, func(client.LighterOrdersResponse) error) (func() error, error) {
+	return nil, errors.New("refused")
+}
+
+func TestRuntimeFailsWithoutOrderUpdates(t *testing.T) {
+	_, tx, ws := newLiveFakes(t)
+	rt, err := strategy.NewRuntimeWith(strategy.Config{Markets: []uint8{0}}, strategy.Services{
+		Orders:  tx,
+		Public:  client.NewLighterWebsocketPublicService(ws.Config()),
+		Private: refusingOrders{client.NewLighterWebsocketPrivateService(ws.Config(), nil)},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Add(newRecorder(0)); err != nil {
+		t.Fatal(err)
+	}
+	_, done := runInBackground(t, rt)
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "subscribe orders") {
+			t.Fatalf("run returned %v, want an order subscription error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not fail")
+	}
+}
+
+func TestRuntimePaperOrdersFromHookDoNotBlock(t *testing.T) {
+	_, _, ws := newLiveFakes(t)
+	book := client.NewOrderBookCache()
+	paper, err := client.NewPaperTxClient(nil, book, testAccount,
+		client.WithPaperMarket(&lighterapi.OrderBookDetail{MarketId: 0, Symbol: "ETH", PriceDecimals: 2, SizeDecimals: 4, MinBaseAmount: "0.001"}),
+		client.WithPaperCollateral(1e6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu     sync.Mutex
+		errs   []error
+		orders = 20
+	)
+	rt, err := strategy.NewRuntimeWith(strategy.Config{
+		Markets:   []uint8{0},
+		QueueSize: 1,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	}, strategy.Services{
+		Orders:  paper,
+		Public:  client.NewLighterWebsocketPublicService(ws.Config()),
+		Private: paper,
+		Paper:   paper,
+	}, book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := newRecorder(orders)
+	if err := rt.Add(rec); err != nil {
+		t.Fatal(err)
+	}
+	cancel, done := runInBackground(t, rt)
+
+	// Every order raises its update inside the hook; a blocking queue of one would stall
+	rec.wait(t, orders)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if rec.err != nil {
+		t.Fatal(rec.err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	lagging := false
+	for _, err := range errs {
+		lagging = lagging || strings.Contains(err.Error(), "falling behind")
+	}
+	if !lagging {
+		t.Fatalf("backlog above QueueSize was not reported: %v", errs)
+	}
+	if n := len(paper.OpenOrders(0)); n != 0 {
+		t.Fatalf("%d paper orders left after shutdown", n)
+	}
+}
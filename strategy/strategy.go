@@ -0,0 +1,189 @@
+// Package strategy defines the event interface trading bots implement, shared by the live
+// runtime and the backtester, and the Context they use to read state and place orders.
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	lighterapi "github.com/defi-maker/golighter/api"
+	"github.com/defi-maker/golighter/client"
+	"github.com/elliottech/lighter-go/types"
+	"github.com/elliottech/lighter-go/types/txtypes"
+)
+
+// defaultOrderExpiry is used for resting orders placed without an expiry
+const defaultOrderExpiry = 28 * 24 * time.Hour
+
+// Strategy receives market and account events. Hooks of one strategy are never called
+// concurrently, so implementations need no locking. A returned error is reported to the
+// runtime's error handler and does not stop the strategy.
+type Strategy interface {
+	OnBook(c *Context, book client.LighterOrderBookResponse) error
+	OnTrade(c *Context, trade client.LighterTradesResponse) error
+	OnOrderUpdate(c *Context, order client.LighterOrdersResponse) error
+	OnAccount(c *Context, account client.LighterAccountResponse) error
+	OnTimer(c *Context, now time.Time) error
+}
+
+// Base implements every hook as a no-op. Embed it to override only the hooks a strategy needs.
+type Base struct{}
+
+func (Base) OnBook(*Context, client.LighterOrderBookResponse) error     { return nil }
+func (Base) OnTrade(*Context, client.LighterTradesResponse) error       { return nil }
+func (Base) OnOrderUpdate(*Context, client.LighterOrdersResponse) error { return nil }
+func (Base) OnAccount(*Context, client.LighterAccountResponse) error    { return nil }
+func (Base) OnTimer(*Context, time.Time) error                          { return nil }
+
+// Context gives a strategy its order client, the local book and the positions and open orders
+// seen on the private stream. Runtimes feed it through TrackOrder and TrackAccount before
+// calling the matching hooks.
+type Context struct {
+	ctx       context.Context
+	orders    client.OrderClient
+	book      *client.OrderBookCache
+	positions *client.AccountPositionCache
+	now       func() time.Time
+
+	mu   sync.Mutex
+	open map[string]client.LighterOrdersResponse
+}
+
+// NewContext creates a strategy context. A nil now uses time.Now; backtests pass their
+// simulated clock.
+func NewContext(ctx context.Context, orders client.OrderClient, book *client.OrderBookCache, now func() time.Time) *Context {
+	if now == nil {
+		now = time.Now
+	}
+	return &Context{
+		ctx:       ctx,
+		orders:    orders,
+		book:      book,
+		positions: client.NewAccountPositionCache(),
+		now:       now,
+		open:      make(map[string]client.LighterOrdersResponse),
+	}
+}
+
+// Context is canceled when the runtime shuts down
+func (c *Context) Context() context.Context { return c.ctx }
+
+// Now returns the runtime clock, simulated in backtests
+func (c *Context) Now() time.Time { return c.now() }
+
+func (c *Context) AccountIndex() int64 { return c.orders.GetAccountIndex() }
+
+// Orders returns the live or paper order client
+func (c *Context) Orders() client.OrderClient { return c.orders }
+
+// Book returns the local order books of the subscribed markets
+func (c *Context) Book() *client.OrderBookCache { return c.book }
+
+// Position returns the latest position received for a market
+func (c *Context) Position(marketId uint8) (client.WSPosition, bool) {
+	return c.positions.Get(c.AccountIndex(), marketId)
+}
+
+// OpenOrders returns the orders of a market that are open according to order updates,
+// oldest first
+func (c *Context) OpenOrders(marketId uint8) []client.LighterOrdersResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []client.LighterOrdersResponse
+	for _, o := range c.open {
+		if o.MarketId == marketId {
+			out = append(out, o)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Timestamp < out[j].Timestamp })
+	return out
+}
+
+// TrackOrder records an order update; runtimes call it before OnOrderUpdate
+func (c *Context) TrackOrder(o client.LighterOrdersResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch lighterapi.OrderStatus(o.Status) {
+	case lighterapi.OrderStatusOpen, lighterapi.OrderStatusPending, lighterapi.OrderStatusInProgress:
+		c.open[o.OrderId] = o
+	default:
+		delete(c.open, o.OrderId)
+	}
+}
+
+// TrackAccount records positions from an account update; runtimes call it before OnAccount
+func (c *Context) TrackAccount(a client.LighterAccountResponse) {
+	c.positions.Handle(a)
+}
+
+// Place signs and sends an order. Resting limit orders without an expiry get one 28 days out.
+func (c *Context) Place(req types.CreateOrderTxReq) (string, error) {
+	if req.OrderExpiry == txtypes.NilOrderExpiry && req.Type == txtypes.LimitOrder && req.TimeInForce != txtypes.ImmediateOrCancel {
+		req.OrderExpiry = c.now().Add(defaultOrderExpiry).UnixMilli()
+	}
+	tx, err := c.orders.GetCreateOrderTransaction(&req, nil)
+	if err != nil {
+		return "", fmt.Errorf("strategy: build order: %w", err)
+	}
+	return c.orders.SendRawTx(c.ctx, tx, nil)
+}
+
+// PlaceBatch signs orders with consecutive nonces and sends them in one SendBatch call,
+// returning their transaction hashes. Expiries default as in Place.
+func (c *Context) PlaceBatch(reqs []types.CreateOrderTxReq) ([]string, error) {
+	infos := make([]txtypes.TxInfo, 0, len(reqs))
+	var nonce int64
+	for i, req := range reqs {
+		if req.OrderExpiry == txtypes.NilOrderExpiry && req.Type == txtypes.LimitOrder && req.TimeInForce != txtypes.ImmediateOrCancel {
+			req.OrderExpiry = c.now().Add(defaultOrderExpiry).UnixMilli()
+		}
+		var ops *types.TransactOpts
+		if i > 0 {
+			next := nonce + int64(i)
+			ops = &types.TransactOpts{Nonce: &next}
+		}
+		tx, err := c.orders.GetCreateOrderTransaction(&req, ops)
+		if err != nil {
+			return nil, fmt.Errorf("strategy: build order %d of batch: %w", i, err)
+		}
+		if i == 0 {
+			nonce = tx.Nonce
+		}
+		infos = append(infos, tx)
+	}
+	resp, err := c.orders.SendBatch(c.ctx, infos)
+	if err != nil {
+		return nil, err
+	}
+	return resp.TxHash, nil
+}
+
+// Modify changes the price or size of an open order
+func (c *Context) Modify(req types.ModifyOrderTxReq) (string, error) {
+	tx, err := c.orders.GetModifyOrderTransaction(&req, nil)
+	if err != nil {
+		return "", fmt.Errorf("strategy: build modify: %w", err)
+	}
+	return c.orders.SendRawTx(c.ctx, tx, nil)
+}
+
+// Cancel cancels an order by order index or client order index
+func (c *Context) Cancel(marketId uint8, index int64) (string, error) {
+	tx, err := c.orders.GetCancelOrderTransaction(&types.CancelOrderTxReq{MarketIndex: marketId, Index: index}, nil)
+	if err != nil {
+		return "", fmt.Errorf("strategy: build cancel: %w", err)
+	}
+	return c.orders.SendRawTx(c.ctx, tx, nil)
+}
+
+// CancelAll cancels every open order of the account immediately
+func (c *Context) CancelAll() (string, error) {
+	tx, err := c.orders.GetCancelAllOrdersTransaction(&types.CancelAllOrdersTxReq{TimeInForce: txtypes.ImmediateCancelAll}, nil)
+	if err != nil {
+		return "", fmt.Errorf("strategy: build cancel all: %w", err)
+	}
+	return c.orders.SendRawTx(c.ctx, tx, nil)
+}